@@ -73,6 +73,11 @@ You can find more information in https://goployer.dev`,
 	rootCmd.AddCommand(NewAddCommand())
 	rootCmd.AddCommand(NewUpdateCommand())
 	rootCmd.AddCommand(NewRefreshCommand())
+	rootCmd.AddCommand(NewRollbackCommand())
+	rootCmd.AddCommand(NewUnlockCommand())
+	rootCmd.AddCommand(NewResumeCommand())
+	rootCmd.AddCommand(NewApproveCommand())
+	rootCmd.AddCommand(NewCanaryCommand())
 
 	rootCmd.PersistentFlags().StringVarP(&v, "log-level", "v", constants.DefaultLogLevel.String(), "Log level (debug, info, warn, error, fatal, panic)")
 
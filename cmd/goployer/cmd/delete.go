@@ -43,7 +43,7 @@ func funcDelete(ctx context.Context, _ io.Writer, mode string) error {
 		}
 
 		// Start runner
-		if err := runner.Start(builderSt, mode); err != nil {
+		if err := runner.Start(ctx, builderSt, mode); err != nil {
 			return err
 		}
 
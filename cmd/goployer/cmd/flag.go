@@ -50,13 +50,18 @@ var zeroTimeout = 0 * time.Minute
 var zeroPollingInterval = 0 * time.Second
 
 var flagKey = map[string]string{
-	"deploy":  "deploySet",
-	"delete":  "fullSet",
-	"init":    "initSet",
-	"status":  "statusSet",
-	"update":  "updateSet",
-	"add":     "addSet",
-	"refresh": "refreshSet",
+	"deploy":   "deploySet",
+	"delete":   "fullSet",
+	"init":     "initSet",
+	"status":   "statusSet",
+	"update":   "updateSet",
+	"add":      "addSet",
+	"refresh":  "refreshSet",
+	"rollback": "rollbackSet",
+	"unlock":   "unlockSet",
+	"resume":   "resumeSet",
+	"approve":  "approveSet",
+	"canary":   "canarySet",
 }
 
 var CommonFlagRegistry = []Flag{
@@ -214,6 +219,41 @@ var FlagRegistry = map[string][]Flag{
 			DefValue:      false,
 			FlagAddMethod: "BoolVar",
 		},
+		{
+			Name:          "dry-run",
+			Usage:         "Print the plan of changes without changing any resources",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "plan-file",
+			Usage:         "File path to save the plan in JSON format with --dry-run",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "max-parallel",
+			Usage:         "Maximum number of stack and region pairs deployed at the same time. If 0, all of them run at the same time",
+			Value:         aws.Int(0),
+			DefValue:      0,
+			FlagAddMethod: "IntVar",
+		},
+		{
+			Name:          "lock-backend",
+			Usage:         "Backend of deployment lock: dynamodb, file or none. If undefined, dynamodb is used with lock table or metrics, otherwise file",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-table",
+			Usage:         "DynamoDB table name for deployment lock. If undefined, the metric table is used",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
 	},
 	"deploySet": {
 		{
@@ -351,6 +391,34 @@ var FlagRegistry = map[string][]Flag{
 			DefValue:      pollingInterval,
 			FlagAddMethod: "DurationVar",
 		},
+		{
+			Name:          "launch-failure-threshold",
+			Usage:         "Number of consecutive failed launches of instances to stop health checking. If 0, health checking waits until timeout",
+			Value:         aws.Int(constants.DefaultLaunchFailureThreshold),
+			DefValue:      constants.DefaultLaunchFailureThreshold,
+			FlagAddMethod: "IntVar",
+		},
+		{
+			Name:          "diagnostics-dir",
+			Usage:         "Directory to save diagnostics bundle of unhealthy instances when health checking times out",
+			Value:         aws.String(constants.DefaultDiagnosticsDir),
+			DefValue:      constants.DefaultDiagnosticsDir,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "disable-diagnostics",
+			Usage:         "Disable collecting diagnostics bundle of unhealthy instances when health checking times out",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "diagnostics-cloud-init",
+			Usage:         "Fetch cloud-init output log of unhealthy instances via SSM for diagnostics bundle",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
 		{
 			Name:          "auto-apply",
 			Usage:         "Apply command without confirmation from local terminal",
@@ -365,6 +433,83 @@ var FlagRegistry = map[string][]Flag{
 			DefValue:      false,
 			FlagAddMethod: "BoolVar",
 		},
+		{
+			Name:          "auto-rollback",
+			Usage:         "Roll back to the previous version if the new autoscaling group does not become healthy",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "dry-run",
+			Usage:         "Print the plan of changes without changing any resources",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "plan-file",
+			Usage:         "File path to save the plan in JSON format with --dry-run",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "max-parallel",
+			Usage:         "Maximum number of stack and region pairs deployed at the same time. If 0, all of them run at the same time",
+			Value:         aws.Int(0),
+			DefValue:      0,
+			FlagAddMethod: "IntVar",
+		},
+		{
+			Name:          "strict",
+			Usage:         "Fail the deployment on any step failure with the exit code of the failure class",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "result-file",
+			Usage:         "File path to save the result of each stack and region in JSON format",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-backend",
+			Usage:         "Backend of deployment lock: dynamodb, file or none. If undefined, dynamodb is used with lock table or metrics, otherwise file",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-table",
+			Usage:         "DynamoDB table name for deployment lock. If undefined, the metric table is used",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "state-backend",
+			Usage:         "Backend of deployment state: file, s3, dynamodb or none (default file)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "state-location",
+			Usage:         "Location of deployment state: directory for file, bucket/prefix for s3 and table name for dynamodb",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "on-interrupt",
+			Usage:         "Action for the new autoscaling groups when the deployment is interrupted: rollback or detach. If undefined, goployer asks it",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
 	},
 	"initSet": {
 		{
@@ -438,6 +583,34 @@ var FlagRegistry = map[string][]Flag{
 			DefValue:      pollingInterval,
 			FlagAddMethod: "DurationVar",
 		},
+		{
+			Name:          "launch-failure-threshold",
+			Usage:         "Number of consecutive failed launches of instances to stop health checking. If 0, health checking waits until timeout",
+			Value:         aws.Int(constants.DefaultLaunchFailureThreshold),
+			DefValue:      constants.DefaultLaunchFailureThreshold,
+			FlagAddMethod: "IntVar",
+		},
+		{
+			Name:          "diagnostics-dir",
+			Usage:         "Directory to save diagnostics bundle of unhealthy instances when health checking times out",
+			Value:         aws.String(constants.DefaultDiagnosticsDir),
+			DefValue:      constants.DefaultDiagnosticsDir,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "disable-diagnostics",
+			Usage:         "Disable collecting diagnostics bundle of unhealthy instances when health checking times out",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "diagnostics-cloud-init",
+			Usage:         "Fetch cloud-init output log of unhealthy instances via SSM for diagnostics bundle",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
 		{
 			Name:          "timeout",
 			Usage:         "Time to wait for deploy to finish before timing out (default 60m)",
@@ -445,6 +618,20 @@ var FlagRegistry = map[string][]Flag{
 			DefValue:      timeout,
 			FlagAddMethod: "DurationVar",
 		},
+		{
+			Name:          "lock-backend",
+			Usage:         "Backend of deployment lock: dynamodb, file or none. If undefined, dynamodb is used with lock table or metrics, otherwise file",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-table",
+			Usage:         "DynamoDB table name for deployment lock. If undefined, the metric table is used",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
 	},
 	"refreshSet": {
 		{
@@ -490,6 +677,373 @@ var FlagRegistry = map[string][]Flag{
 			FlagAddMethod: "DurationVar",
 		},
 	},
+	"rollbackSet": {
+		{
+			Name:          "manifest",
+			Shorthand:     "m",
+			Usage:         "The manifest configuration file to use. (required)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "manifest-s3-region",
+			Usage:         "Region of bucket containing the manifest configuration file to use. (required if –manifest starts with s3://)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "stack",
+			Usage:         "stack that should be rolled back",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "region",
+			Usage:         "The region to roll back, if undefined, then the rollback will run against all regions for the given environment.",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "assume-role",
+			Usage:         "The Role ARN to assume into.",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "to",
+			Usage:         "Version to roll back to, like v012. If undefined, you can choose one from the list",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "timeout",
+			Usage:         "Time to wait for rollback to finish before timing out (default 60m)",
+			Value:         &zeroTimeout,
+			DefValue:      timeout,
+			FlagAddMethod: "DurationVar",
+		},
+		{
+			Name:          "polling-interval",
+			Usage:         "Time to interval for polling health check (default 60s)",
+			Value:         &zeroPollingInterval,
+			DefValue:      pollingInterval,
+			FlagAddMethod: "DurationVar",
+		},
+		{
+			Name:          "slack-off",
+			Usage:         "Turn off slack alarm",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "disable-metrics",
+			Usage:         "Disable gathering metrics.",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "auto-apply",
+			Usage:         "Apply command without confirmation from local terminal",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "lock-backend",
+			Usage:         "Backend of deployment lock: dynamodb, file or none. If undefined, dynamodb is used with lock table or metrics, otherwise file",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-table",
+			Usage:         "DynamoDB table name for deployment lock. If undefined, the metric table is used",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "log-level",
+			Shorthand:     "v",
+			Usage:         "Level of logging",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      "warning",
+			FlagAddMethod: "StringVar",
+		},
+	},
+	"unlockSet": {
+		{
+			Name:          "force",
+			Usage:         "Remove the lock regardless of the holder",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "region",
+			Usage:         "Region of the lock table",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-backend",
+			Usage:         "Backend of deployment lock: dynamodb, file or none. If undefined, dynamodb is used with lock table or metrics, otherwise file",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-table",
+			Usage:         "DynamoDB table name for deployment lock. If undefined, the metric table is used",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "auto-apply",
+			Usage:         "Apply command without confirmation from local terminal",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "log-level",
+			Shorthand:     "v",
+			Usage:         "Level of logging",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      "warning",
+			FlagAddMethod: "StringVar",
+		},
+	},
+	"resumeSet": {
+		{
+			Name:          "region",
+			Usage:         "Region of the state storage",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "max-parallel",
+			Usage:         "Maximum number of stack and region pairs deployed at the same time. If 0, all of them run at the same time",
+			Value:         aws.Int(0),
+			DefValue:      0,
+			FlagAddMethod: "IntVar",
+		},
+		{
+			Name:          "strict",
+			Usage:         "Fail the deployment on any step failure with the exit code of the failure class",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "result-file",
+			Usage:         "File path to save the result of each stack and region in JSON format",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "state-backend",
+			Usage:         "Backend of deployment state: file, s3, dynamodb or none (default file)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "state-location",
+			Usage:         "Location of deployment state: directory for file, bucket/prefix for s3 and table name for dynamodb",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "on-interrupt",
+			Usage:         "Action for the new autoscaling groups when the deployment is interrupted: rollback or detach. If undefined, goployer asks it",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "auto-apply",
+			Usage:         "Apply command without confirmation from local terminal",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "log-level",
+			Shorthand:     "v",
+			Usage:         "Level of logging",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      "warning",
+			FlagAddMethod: "StringVar",
+		},
+	},
+	"approveSet": {
+		{
+			Name:          "stack",
+			Usage:         "Stack of the approval gate. If undefined, gates of all stacks are decided",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "region",
+			Usage:         "Region of the approval gate. If undefined, gates of all regions are decided",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "approver",
+			Usage:         "Identity of the approver which is recorded with the decision (default user@host)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "comment",
+			Usage:         "Comment which is recorded with the decision",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "reject",
+			Usage:         "Reject the approval gate so that the new version is rolled back",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "state-backend",
+			Usage:         "Backend of deployment state: file, s3, dynamodb or none (default file)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "state-location",
+			Usage:         "Location of deployment state: directory for file, bucket/prefix for s3 and table name for dynamodb",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "log-level",
+			Shorthand:     "v",
+			Usage:         "Level of logging",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      "warning",
+			FlagAddMethod: "StringVar",
+		},
+	},
+	"canarySet": {
+		{
+			Name:          "manifest",
+			Shorthand:     "m",
+			Usage:         "The manifest configuration file to use. (required)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "manifest-s3-region",
+			Usage:         "Region of bucket containing the manifest configuration file to use. (required if –manifest starts with s3://)",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "stack",
+			Usage:         "Stack of canaries. If undefined, canaries of all canary stacks are selected",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "region",
+			Usage:         "Region of canaries. If undefined, canaries of all regions are selected",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "assume-role",
+			Usage:         "The Role ARN to assume into.",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "timeout",
+			Usage:         "Time to wait for promotion or abort to finish before timing out (default 60m)",
+			Value:         &zeroTimeout,
+			DefValue:      timeout,
+			FlagAddMethod: "DurationVar",
+		},
+		{
+			Name:          "polling-interval",
+			Usage:         "Time to interval for polling health check (default 60s)",
+			Value:         &zeroPollingInterval,
+			DefValue:      pollingInterval,
+			FlagAddMethod: "DurationVar",
+		},
+		{
+			Name:          "slack-off",
+			Usage:         "Turn off slack alarm",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "disable-metrics",
+			Usage:         "Disable gathering metrics.",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "auto-apply",
+			Usage:         "Apply command without confirmation from local terminal",
+			Value:         aws.Bool(false),
+			DefValue:      false,
+			FlagAddMethod: "BoolVar",
+		},
+		{
+			Name:          "lock-backend",
+			Usage:         "Backend of deployment lock: dynamodb, file or none. If undefined, dynamodb is used with lock table or metrics, otherwise file",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "lock-table",
+			Usage:         "DynamoDB table name for deployment lock. If undefined, the metric table is used",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      constants.EmptyString,
+			FlagAddMethod: "StringVar",
+		},
+		{
+			Name:          "log-level",
+			Shorthand:     "v",
+			Usage:         "Level of logging",
+			Value:         aws.String(constants.EmptyString),
+			DefValue:      "warning",
+			FlagAddMethod: "StringVar",
+		},
+	},
 }
 
 func (fl *Flag) flag() *pflag.Flag {
@@ -50,7 +50,7 @@ func funcRefresh(ctx context.Context, _ io.Writer, args []string, mode string) e
 		builderSt.Config.Application = args[0]
 
 		// Start runner
-		if err := runner.Start(builderSt, mode); err != nil {
+		if err := runner.Start(ctx, builderSt, mode); err != nil {
 			return err
 		}
 
@@ -50,7 +50,7 @@ func funcStatus(ctx context.Context, _ io.Writer, args []string, mode string) er
 		builderSt.Config.Application = args[0]
 
 		// Start runner
-		if err := runner.Start(builderSt, mode); err != nil {
+		if err := runner.Start(ctx, builderSt, mode); err != nil {
 			return err
 		}
 
@@ -24,6 +24,7 @@ import (
 	Logger "github.com/sirupsen/logrus"
 
 	"github.com/DevopsArtFactory/goployer/cmd/goployer/app"
+	"github.com/DevopsArtFactory/goployer/pkg/runner"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 )
 
@@ -33,7 +34,7 @@ func main() {
 			Logger.Debugln("ignore error since context is cancelled:", err)
 		} else {
 			tool.Red.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			os.Exit(runner.ExitCode(err))
 		}
 	}
 }
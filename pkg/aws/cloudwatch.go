@@ -17,7 +17,9 @@ limitations under the license.
 package aws
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -344,11 +346,154 @@ func (c CloudWatchClient) GetOneDayStatisticsOfLoadBalancer(lb string, startTime
 	return ret, sum, nil
 }
 
+// GetAlarmStates returns states of CloudWatch alarms by name
+func (c CloudWatchClient) GetAlarmStates(ctx context.Context, names []string) (map[string]string, error) {
+	input := &cloudwatch.DescribeAlarmsInput{
+		AlarmNames: aws.StringSlice(names),
+	}
+
+	ret := map[string]string{}
+	err := c.Client.DescribeAlarmsPagesWithContext(ctx, input, func(page *cloudwatch.DescribeAlarmsOutput, lastPage bool) bool {
+		for _, alarm := range page.MetricAlarms {
+			ret[*alarm.AlarmName] = aws.StringValue(alarm.StateValue)
+		}
+		for _, alarm := range page.CompositeAlarms {
+			ret[*alarm.AlarmName] = aws.StringValue(alarm.StateValue)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetTargetGroup5xxRates returns percentages of 5xx responses of targets over requests in every period
+func (c CloudWatchClient) GetTargetGroup5xxRates(ctx context.Context, loadBalancerArn, targetGroupArn string, startTime, endTime time.Time, period int64) ([]float64, error) {
+	queries := []*cloudwatch.MetricDataQuery{
+		targetGroupMetricQuery("errors", "HTTPCode_Target_5XX_Count", "Sum", loadBalancerArn, targetGroupArn, period),
+		targetGroupMetricQuery("requests", "RequestCount", "Sum", loadBalancerArn, targetGroupArn, period),
+		{
+			Expression: aws.String("100 * FILL(errors, 0) / requests"),
+			Id:         aws.String("rate"),
+			Label:      aws.String("5xxRate"),
+		},
+	}
+
+	return c.getMetricValues(ctx, queries, startTime, endTime)
+}
+
+// GetTargetGroupResponseTimes returns response times of targets with the statistic in every period
+func (c CloudWatchClient) GetTargetGroupResponseTimes(ctx context.Context, loadBalancerArn, targetGroupArn, stat string, startTime, endTime time.Time, period int64) ([]float64, error) {
+	query := targetGroupMetricQuery("latency", "TargetResponseTime", stat, loadBalancerArn, targetGroupArn, period)
+	query.ReturnData = aws.Bool(true)
+
+	return c.getMetricValues(ctx, []*cloudwatch.MetricDataQuery{query}, startTime, endTime)
+}
+
+// GetTargetGroupRequestCountPerTarget returns the number of requests per target of the target group in every period
+func (c CloudWatchClient) GetTargetGroupRequestCountPerTarget(ctx context.Context, loadBalancerArn, targetGroupArn string, startTime, endTime time.Time, period int64) ([]float64, error) {
+	query := targetGroupMetricQuery("requests", "RequestCountPerTarget", "Sum", loadBalancerArn, targetGroupArn, period)
+	query.ReturnData = aws.Bool(true)
+
+	return c.getMetricValues(ctx, []*cloudwatch.MetricDataQuery{query}, startTime, endTime)
+}
+
+// GetMetricValues returns values of the metric with dimensions in every period
+func (c CloudWatchClient) GetMetricValues(ctx context.Context, namespace, metricName, stat string, dimensions map[string]string, startTime, endTime time.Time, period int64) ([]float64, error) {
+	var keys []string
+	for k := range dimensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var dims []*cloudwatch.Dimension
+	for _, k := range keys {
+		dims = append(dims, &cloudwatch.Dimension{Name: aws.String(k), Value: aws.String(dimensions[k])})
+	}
+
+	query := &cloudwatch.MetricDataQuery{
+		Id:         aws.String("custom"),
+		ReturnData: aws.Bool(true),
+		MetricStat: &cloudwatch.MetricStat{
+			Metric: &cloudwatch.Metric{
+				Dimensions: dims,
+				MetricName: aws.String(metricName),
+				Namespace:  aws.String(namespace),
+			},
+			Period: aws.Int64(period),
+			Stat:   aws.String(stat),
+		},
+	}
+
+	return c.getMetricValues(ctx, []*cloudwatch.MetricDataQuery{query}, startTime, endTime)
+}
+
+// getMetricValues returns values of the query which returns data
+func (c CloudWatchClient) getMetricValues(ctx context.Context, queries []*cloudwatch.MetricDataQuery, startTime, endTime time.Time) ([]float64, error) {
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime:         aws.Time(startTime),
+		EndTime:           aws.Time(endTime),
+		MetricDataQueries: queries,
+	}
+
+	var ret []float64
+	err := c.Client.GetMetricDataPagesWithContext(ctx, input, func(page *cloudwatch.GetMetricDataOutput, lastPage bool) bool {
+		for _, result := range page.MetricDataResults {
+			ret = append(ret, aws.Float64ValueSlice(result.Values)...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// targetGroupMetricQuery creates query of application load balancer metric for the target group
+// Dimensions of metrics are the suffixes of ARNs, like `app/name/id` and `targetgroup/name/id`.
+func targetGroupMetricQuery(id, metric, stat, loadBalancerArn, targetGroupArn string, period int64) *cloudwatch.MetricDataQuery {
+	return &cloudwatch.MetricDataQuery{
+		Id:         aws.String(id),
+		ReturnData: aws.Bool(false),
+		MetricStat: &cloudwatch.MetricStat{
+			Metric: &cloudwatch.Metric{
+				Dimensions: []*cloudwatch.Dimension{
+					{
+						Name:  aws.String("LoadBalancer"),
+						Value: aws.String(loadBalancerArn[strings.Index(loadBalancerArn, "/")+1:]),
+					},
+					{
+						Name:  aws.String("TargetGroup"),
+						Value: aws.String(targetGroupArn[strings.LastIndex(targetGroupArn, ":")+1:]),
+					},
+				},
+				MetricName: aws.String(metric),
+				Namespace:  aws.String("AWS/ApplicationELB"),
+			},
+			Period: aws.Int64(period),
+			Stat:   aws.String(stat),
+		},
+	}
+}
+
 // CheckMetricTimeValidation validates metric time
 func CheckMetricTimeValidation(startTime time.Time, endTime time.Time) bool {
 	return endTime.Sub(startTime) > 0
 }
 
+// GenerateAlarmNames returns names of alarms which will be created for autoscaling group
+func GenerateAlarmNames(asgName string, alarms []schemas.AlarmConfigs) []string {
+	var ret []string
+	for _, alarm := range alarms {
+		ret = append(ret, createAlarmName(asgName, alarm.Name))
+	}
+
+	return ret
+}
+
 // createAlarmName creates name of alarm
 func createAlarmName(asgName, suffix string) string {
 	return fmt.Sprintf("%s_%s", asgName, suffix)
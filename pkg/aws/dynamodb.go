@@ -241,6 +241,13 @@ func (d DynamoDBClient) UpdateRecord(updateKey, asg string, tableName string, st
 	return nil
 }
 
+// WaitTableExists waits until the table becomes active
+func (d DynamoDBClient) WaitTableExists(tableName string) error {
+	return d.Client.WaitUntilTableExists(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+}
+
 // GetSingleItem retrieves single item for single autoscaling group
 func (d DynamoDBClient) GetSingleItem(asg, tableName string) (map[string]*dynamodb.AttributeValue, error) {
 	input := &dynamodb.GetItemInput{
@@ -260,6 +267,33 @@ func (d DynamoDBClient) GetSingleItem(asg, tableName string) (map[string]*dynamo
 	return result.Item, err
 }
 
+// GetItemsWithPrefix retrieves all items whose identifier starts with prefix
+func (d DynamoDBClient) GetItemsWithPrefix(prefix, tableName string) ([]map[string]*dynamodb.AttributeValue, error) {
+	input := &dynamodb.ScanInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#I": aws.String(constants.HashKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {
+				S: aws.String(prefix),
+			},
+		},
+		FilterExpression: aws.String("begins_with(#I, :prefix)"),
+		TableName:        aws.String(tableName),
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	err := d.Client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
 // UpdateStatistics updates the status value on metric table
 func (d DynamoDBClient) UpdateStatistics(asg string, tableName, timezone string, updateFields map[string]interface{}) error {
 	baseEx := "SET #T = :statisticsRecordTime"
@@ -321,3 +355,160 @@ func (d DynamoDBClient) UpdateStatistics(asg string, tableName, timezone string,
 
 	return nil
 }
+
+// PutLockItem creates lock item only when no lock exists or the existing lock is expired
+func (d DynamoDBClient) PutLockItem(key, tableName string, fields map[string]string, expiresAt, now int64) error {
+	item := map[string]*dynamodb.AttributeValue{
+		constants.HashKey: {
+			S: aws.String(key),
+		},
+		"expires_at": {
+			N: aws.String(fmt.Sprintf("%d", expiresAt)),
+		},
+	}
+
+	for k, v := range fields {
+		item[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+
+	input := &dynamodb.PutItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(#I) OR #E < :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#I": aws.String(constants.HashKey),
+			"#E": aws.String("expires_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {
+				N: aws.String(fmt.Sprintf("%d", now)),
+			},
+		},
+		Item:      item,
+		TableName: aws.String(tableName),
+	}
+
+	_, err := d.Client.PutItem(input)
+	return err
+}
+
+// RenewLockItem extends the lease of lock item which is held by the owner
+func (d DynamoDBClient) RenewLockItem(key, tableName, owner string, expiresAt int64) error {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#O = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#O": aws.String("owner"),
+			"#E": aws.String("expires_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {
+				S: aws.String(owner),
+			},
+			":expires": {
+				N: aws.String(fmt.Sprintf("%d", expiresAt)),
+			},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			constants.HashKey: {
+				S: aws.String(key),
+			},
+		},
+		TableName:        aws.String(tableName),
+		UpdateExpression: aws.String("SET #E = :expires"),
+	}
+
+	_, err := d.Client.UpdateItem(input)
+	return err
+}
+
+// DeleteLockItem deletes lock item
+// If owner is empty, lock item is deleted regardless of the owner.
+func (d DynamoDBClient) DeleteLockItem(key, tableName, owner string) error {
+	input := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			constants.HashKey: {
+				S: aws.String(key),
+			},
+		},
+		TableName: aws.String(tableName),
+	}
+
+	if len(owner) > 0 {
+		input.ConditionExpression = aws.String("#O = :owner")
+		input.ExpressionAttributeNames = map[string]*string{
+			"#O": aws.String("owner"),
+		}
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":owner": {
+				S: aws.String(owner),
+			},
+		}
+	}
+
+	_, err := d.Client.DeleteItem(input)
+	return err
+}
+
+// PutStringItem creates or replaces item with string fields
+func (d DynamoDBClient) PutStringItem(key, tableName string, fields map[string]string) error {
+	item := map[string]*dynamodb.AttributeValue{
+		constants.HashKey: {
+			S: aws.String(key),
+		},
+	}
+
+	for k, v := range fields {
+		item[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(tableName),
+	}
+
+	_, err := d.Client.PutItem(input)
+	return err
+}
+
+// AppendStringItem appends value to the string list field of the item in a single update
+// The item and the field are created if they do not exist.
+func (d DynamoDBClient) AppendStringItem(key, tableName, field, value string) error {
+	input := &dynamodb.UpdateItemInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#F": aws.String(field),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":empty": {
+				L: []*dynamodb.AttributeValue{},
+			},
+			":value": {
+				L: []*dynamodb.AttributeValue{
+					{S: aws.String(value)},
+				},
+			},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			constants.HashKey: {
+				S: aws.String(key),
+			},
+		},
+		TableName:        aws.String(tableName),
+		UpdateExpression: aws.String("SET #F = list_append(if_not_exists(#F, :empty), :value)"),
+	}
+
+	_, err := d.Client.UpdateItem(input)
+	return err
+}
+
+// DeleteSingleItem deletes single item
+func (d DynamoDBClient) DeleteSingleItem(key, tableName string) error {
+	input := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			constants.HashKey: {
+				S: aws.String(key),
+			},
+		},
+		TableName: aws.String(tableName),
+	}
+
+	_, err := d.Client.DeleteItem(input)
+	return err
+}
@@ -17,6 +17,8 @@ limitations under the license.
 package aws
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"regexp"
@@ -76,8 +78,8 @@ func getKMSClientFn(session client.ConfigProvider, region string, creds *credent
 }
 
 // GetMatchingAutoscalingGroup returns only one matching autoscaling group information
-func (e EC2Client) GetMatchingAutoscalingGroup(name string) (*autoscaling.Group, error) {
-	asgGroup, err := getSingleAutoScalingGroup(e.AsClient, name)
+func (e EC2Client) GetMatchingAutoscalingGroup(ctx context.Context, name string) (*autoscaling.Group, error) {
+	asgGroup, err := getSingleAutoScalingGroup(ctx, e.AsClient, name)
 	if err != nil {
 		return nil, err
 	}
@@ -891,6 +893,45 @@ func (e EC2Client) EnableMetrics(asgName string) error {
 	return nil
 }
 
+// GetScalingPolicyNames returns names of scaling policies attached to autoscaling group
+func (e EC2Client) GetScalingPolicyNames(asgName string) ([]string, error) {
+	input := &autoscaling.DescribePoliciesInput{
+		AutoScalingGroupName: aws.String(asgName),
+	}
+
+	var ret []string
+	err := e.AsClient.DescribePoliciesPages(input, func(page *autoscaling.DescribePoliciesOutput, lastPage bool) bool {
+		for _, p := range page.ScalingPolicies {
+			ret = append(ret, *p.PolicyName)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetLifecycleHookNames returns names of lifecycle hooks of autoscaling group
+func (e EC2Client) GetLifecycleHookNames(asgName string) ([]string, error) {
+	input := &autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: aws.String(asgName),
+	}
+
+	result, err := e.AsClient.DescribeLifecycleHooks(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, h := range result.LifecycleHooks {
+		ret = append(ret, *h.LifecycleHookName)
+	}
+
+	return ret, nil
+}
+
 // GenerateLifecycleHooks generate lifecycle hooks
 func (e EC2Client) GenerateLifecycleHooks(hooks schemas.LifecycleHooks) []*autoscaling.LifecycleHookSpecification {
 	var ret []*autoscaling.LifecycleHookSpecification
@@ -964,11 +1005,11 @@ func (e EC2Client) GetTargetGroups(asgName string) ([]*string, error) {
 }
 
 // getSingleAutoScalingGroup return detailed information of autoscaling group
-func getSingleAutoScalingGroup(client *autoscaling.AutoScaling, asgName string) (*autoscaling.Group, error) {
+func getSingleAutoScalingGroup(ctx context.Context, client *autoscaling.AutoScaling, asgName string) (*autoscaling.Group, error) {
 	input := &autoscaling.DescribeAutoScalingGroupsInput{
 		AutoScalingGroupNames: aws.StringSlice([]string{asgName}),
 	}
-	ret, err := client.DescribeAutoScalingGroups(input)
+	ret, err := client.DescribeAutoScalingGroupsWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -980,6 +1021,20 @@ func getSingleAutoScalingGroup(client *autoscaling.AutoScaling, asgName string)
 	return ret.AutoScalingGroups[0], nil
 }
 
+// DescribeScalingActivities returns recent scaling activities of autoscaling group from the latest one
+func (e EC2Client) DescribeScalingActivities(ctx context.Context, asg string) ([]*autoscaling.Activity, error) {
+	input := &autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(asg),
+	}
+
+	result, err := e.AsClient.DescribeScalingActivitiesWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Activities, nil
+}
+
 // UpdateAutoScalingGroup  updates auto scaling group information
 func (e EC2Client) UpdateAutoScalingGroup(asg string, capacity schemas.Capacity) error {
 	input := &autoscaling.UpdateAutoScalingGroupInput{
@@ -1222,6 +1277,21 @@ func (e EC2Client) RevokeInboundRulesWithGroup(sgID, protocol string, fromSg *st
 	return nil
 }
 
+// RevokeInboundPermissions revokes inbound rules of security group
+func (e EC2Client) RevokeInboundPermissions(sgID string, permissions []*ec2.IpPermission) error {
+	input := &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       aws.String(sgID),
+		IpPermissions: permissions,
+	}
+
+	_, err := e.Client.RevokeSecurityGroupIngress(input)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // DeleteCanaryTag deletes canary tag from auto scaling group
 func (e EC2Client) DeleteCanaryTag(asg string) error {
 	input := &autoscaling.DeleteTagsInput{
@@ -1261,6 +1331,124 @@ func (e EC2Client) DescribeInstances(instanceIds []*string) ([]*ec2.Instance, er
 	return result.Reservations[0].Instances, nil
 }
 
+// GetPrivateIPAddresses returns private IP addresses of instances by instance ID
+func (e EC2Client) GetPrivateIPAddresses(ctx context.Context, instanceIds []*string) (map[string]string, error) {
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIds,
+	}
+
+	ret := map[string]string{}
+	err := e.Client.DescribeInstancesPagesWithContext(ctx, input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.PrivateIpAddress != nil {
+					ret[*instance.InstanceId] = *instance.PrivateIpAddress
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// TerminateInstanceInAutoScalingGroup terminates the instance in autoscaling group
+// If decrement is false, desired capacity is not decreased, so autoscaling group launches a new one.
+func (e EC2Client) TerminateInstanceInAutoScalingGroup(ctx context.Context, instanceID string, decrement bool) error {
+	input := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(instanceID),
+		ShouldDecrementDesiredCapacity: aws.Bool(decrement),
+	}
+
+	_, err := e.AsClient.TerminateInstanceInAutoScalingGroupWithContext(ctx, input)
+	return err
+}
+
+// GetInstanceStateReasons returns codes of state reason of instances by instance ID
+func (e EC2Client) GetInstanceStateReasons(ctx context.Context, instanceIds []*string) (map[string]string, error) {
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIds,
+	}
+
+	ret := map[string]string{}
+	err := e.Client.DescribeInstancesPagesWithContext(ctx, input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.StateReason != nil {
+					ret[*instance.InstanceId] = aws.StringValue(instance.StateReason.Code)
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// SetOnDemandPercentage changes the percentage of on-demand instances of autoscaling group with mixed instances policy
+func (e EC2Client) SetOnDemandPercentage(ctx context.Context, asg string, percentage int64) error {
+	input := &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(asg),
+		MixedInstancesPolicy: &autoscaling.MixedInstancesPolicy{
+			InstancesDistribution: &autoscaling.InstancesDistribution{
+				OnDemandPercentageAboveBaseCapacity: aws.Int64(percentage),
+			},
+		},
+	}
+
+	_, err := e.AsClient.UpdateAutoScalingGroupWithContext(ctx, input)
+	return err
+}
+
+// GetConsoleOutput returns the latest console output of the instance
+func (e EC2Client) GetConsoleOutput(ctx context.Context, instanceID string) (string, error) {
+	input := &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+		Latest:     aws.Bool(true),
+	}
+
+	result, err := e.Client.GetConsoleOutputWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	if result.Output == nil {
+		return "", nil
+	}
+
+	output, err := base64.StdEncoding.DecodeString(*result.Output)
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), nil
+}
+
+// DescribeInstanceStatuses returns status checks of instances including instances which are not running
+func (e EC2Client) DescribeInstanceStatuses(ctx context.Context, instanceIds []*string) ([]*ec2.InstanceStatus, error) {
+	input := &ec2.DescribeInstanceStatusInput{
+		InstanceIds:         instanceIds,
+		IncludeAllInstances: aws.Bool(true),
+	}
+
+	var ret []*ec2.InstanceStatus
+	err := e.Client.DescribeInstanceStatusPagesWithContext(ctx, input, func(page *ec2.DescribeInstanceStatusOutput, lastPage bool) bool {
+		ret = append(ret, page.InstanceStatuses...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
 // ModifyNetworkInterfaces modifies network interface attributes
 func (e EC2Client) ModifyNetworkInterfaces(eni *string, groups []*string) error {
 	input := &ec2.ModifyNetworkInterfaceAttributeInput{
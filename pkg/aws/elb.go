@@ -17,6 +17,9 @@ limitations under the license.
 package aws
 
 import (
+	"context"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -45,12 +48,12 @@ func getELBClientFn(session client.ConfigProvider, region string, creds *credent
 }
 
 // GetHostInELB returns instances in ELB
-func (e ELBClient) GetHealthyHostInELB(group *autoscaling.Group, elbName string) ([]HealthcheckHost, error) {
+func (e ELBClient) GetHealthyHostInELB(ctx context.Context, group *autoscaling.Group, elbName string) ([]HealthcheckHost, error) {
 	input := &elb.DescribeInstanceHealthInput{
 		LoadBalancerName: aws.String(elbName),
 	}
 
-	result, err := e.Client.DescribeInstanceHealth(input)
+	result, err := e.Client.DescribeInstanceHealthWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -74,3 +77,60 @@ func (e ELBClient) GetHealthyHostInELB(group *autoscaling.Group, elbName string)
 
 	return ret, nil
 }
+
+// DeregisterInstances deregisters instances from the classic load balancer
+func (e ELBClient) DeregisterInstances(ctx context.Context, elbName string, instanceIDs []string) error {
+	var instances []*elb.Instance
+	for _, id := range instanceIDs {
+		instances = append(instances, &elb.Instance{InstanceId: aws.String(id)})
+	}
+
+	input := &elb.DeregisterInstancesFromLoadBalancerInput{
+		LoadBalancerName: aws.String(elbName),
+		Instances:        instances,
+	}
+
+	_, err := e.Client.DeregisterInstancesFromLoadBalancerWithContext(ctx, input)
+	return err
+}
+
+// GetDrainingInstances returns instances which are still registered in the classic load balancer while connections are drained
+func (e ELBClient) GetDrainingInstances(ctx context.Context, elbName string, instanceIDs []string) ([]string, error) {
+	input := &elb.DescribeInstanceHealthInput{
+		LoadBalancerName: aws.String(elbName),
+	}
+
+	result, err := e.Client.DescribeInstanceHealthWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, instance := range result.InstanceStates {
+		if tool.IsStringInArray(aws.StringValue(instance.InstanceId), instanceIDs) {
+			ret = append(ret, aws.StringValue(instance.InstanceId))
+		}
+	}
+
+	return ret, nil
+}
+
+// GetConnectionDrainingTimeout returns the connection draining timeout of the classic load balancer
+// It returns 0 if connection draining is disabled.
+func (e ELBClient) GetConnectionDrainingTimeout(ctx context.Context, elbName string) (time.Duration, error) {
+	input := &elb.DescribeLoadBalancerAttributesInput{
+		LoadBalancerName: aws.String(elbName),
+	}
+
+	result, err := e.Client.DescribeLoadBalancerAttributesWithContext(ctx, input)
+	if err != nil {
+		return 0, err
+	}
+
+	draining := result.LoadBalancerAttributes.ConnectionDraining
+	if draining == nil || !aws.BoolValue(draining.Enabled) {
+		return 0, nil
+	}
+
+	return time.Duration(aws.Int64Value(draining.Timeout)) * time.Second, nil
+}
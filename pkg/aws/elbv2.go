@@ -17,6 +17,11 @@ limitations under the license.
 package aws
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
@@ -37,6 +42,7 @@ type HealthcheckHost struct {
 	LifecycleState string
 	TargetStatus   string
 	HealthStatus   string
+	Reason         string
 	Valid          bool
 }
 
@@ -78,12 +84,12 @@ func (e ELBV2Client) GetTargetGroupARNs(targetGroups []string) ([]*string, error
 }
 
 // GetHostInTarget gets host instance
-func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, targetGroupArn *string, isUpdate, downSizingUpdate bool) ([]HealthcheckHost, error) {
+func (e ELBV2Client) GetHostInTarget(ctx context.Context, group *autoscaling.Group, targetGroupArn *string, isUpdate, downSizingUpdate bool) ([]HealthcheckHost, error) {
 	input := &elbv2.DescribeTargetHealthInput{
 		TargetGroupArn: aws.String(*targetGroupArn),
 	}
 
-	result, err := e.Client.DescribeTargetHealth(input)
+	result, err := e.Client.DescribeTargetHealthWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -91,9 +97,13 @@ func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, targetGroupArn *s
 	ret := []HealthcheckHost{}
 	for _, instance := range group.Instances {
 		targetState := constants.InitialStatus
+		reason := ""
 		for _, hd := range result.TargetHealthDescriptions {
 			if *hd.Target.Id == *instance.InstanceId {
 				targetState = *hd.TargetHealth.State
+				if hd.TargetHealth.Reason != nil {
+					reason = fmt.Sprintf("%s: %s", *hd.TargetHealth.Reason, aws.StringValue(hd.TargetHealth.Description))
+				}
 				break
 			}
 		}
@@ -110,6 +120,7 @@ func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, targetGroupArn *s
 			LifecycleState: *instance.LifecycleState,
 			TargetStatus:   targetState,
 			HealthStatus:   *instance.HealthStatus,
+			Reason:         reason,
 			Valid:          valid,
 		})
 	}
@@ -236,7 +247,7 @@ func (e ELBV2Client) GetMatchingLoadBalancer(lb string) (*elbv2.LoadBalancer, er
 }
 
 // CreateLoadBalancer retrieves all load balancers
-func (e ELBV2Client) CreateLoadBalancer(app string, subnets []string, groupID *string) (*elbv2.LoadBalancer, error) {
+func (e ELBV2Client) CreateLoadBalancer(app string, subnets []string, groupID *string, scheme string) (*elbv2.LoadBalancer, error) {
 	input := &elbv2.CreateLoadBalancerInput{
 		Name: aws.String(app),
 		Tags: []*elbv2.Tag{
@@ -248,6 +259,10 @@ func (e ELBV2Client) CreateLoadBalancer(app string, subnets []string, groupID *s
 		Subnets: aws.StringSlice(subnets),
 	}
 
+	if len(scheme) > 0 {
+		input.Scheme = aws.String(scheme)
+	}
+
 	if groupID != nil {
 		input.SecurityGroups = []*string{groupID}
 	}
@@ -260,8 +275,34 @@ func (e ELBV2Client) CreateLoadBalancer(app string, subnets []string, groupID *s
 	return result.LoadBalancers[0], nil
 }
 
+// ListenerConfig is the configuration of a listener which forwards requests to a target group
+type ListenerConfig struct {
+	Port           int64
+	Protocol       string
+	CertificateArn string
+	SslPolicy      string
+}
+
+// certificates returns certificates of the listener
+func (l ListenerConfig) certificates() []*elbv2.Certificate {
+	if len(l.CertificateArn) == 0 {
+		return nil
+	}
+
+	return []*elbv2.Certificate{{CertificateArn: aws.String(l.CertificateArn)}}
+}
+
+// sslPolicy returns the security policy of the listener
+func (l ListenerConfig) sslPolicy() *string {
+	if len(l.SslPolicy) == 0 {
+		return nil
+	}
+
+	return aws.String(l.SslPolicy)
+}
+
 // CreateNewListener creates a new listener and attach target group to load balancer
-func (e ELBV2Client) CreateNewListener(loadBalancerArn string, targetGroupArn string) error {
+func (e ELBV2Client) CreateNewListener(loadBalancerArn string, targetGroupArn string, listener ListenerConfig) error {
 	input := &elbv2.CreateListenerInput{
 		DefaultActions: []*elbv2.Action{
 			{
@@ -270,8 +311,10 @@ func (e ELBV2Client) CreateNewListener(loadBalancerArn string, targetGroupArn st
 			},
 		},
 		LoadBalancerArn: aws.String(loadBalancerArn),
-		Port:            aws.Int64(80),
-		Protocol:        aws.String("HTTP"),
+		Port:            aws.Int64(listener.Port),
+		Protocol:        aws.String(listener.Protocol),
+		Certificates:    listener.certificates(),
+		SslPolicy:       listener.sslPolicy(),
 	}
 
 	_, err := e.Client.CreateListener(input)
@@ -297,7 +340,8 @@ func (e ELBV2Client) DescribeListeners(loadBalancerArn string) ([]*elbv2.Listene
 }
 
 // ModifyListener modifies the existing listener and change target to newly created target group
-func (e ELBV2Client) ModifyListener(listenerArn *string, targetGroupArn string) error {
+// Port, protocol and certificate of the listener are also changed to the configuration.
+func (e ELBV2Client) ModifyListener(listenerArn *string, targetGroupArn string, listener ListenerConfig) error {
 	input := &elbv2.ModifyListenerInput{
 		DefaultActions: []*elbv2.Action{
 			{
@@ -305,7 +349,11 @@ func (e ELBV2Client) ModifyListener(listenerArn *string, targetGroupArn string)
 				Type:           aws.String("forward"),
 			},
 		},
-		ListenerArn: listenerArn,
+		ListenerArn:  listenerArn,
+		Port:         aws.Int64(listener.Port),
+		Protocol:     aws.String(listener.Protocol),
+		Certificates: listener.certificates(),
+		SslPolicy:    listener.sslPolicy(),
 	}
 
 	_, err := e.Client.ModifyListener(input)
@@ -315,3 +363,190 @@ func (e ELBV2Client) ModifyListener(listenerArn *string, targetGroupArn string)
 
 	return nil
 }
+
+// DeregisterTargets deregisters instances from the target group
+func (e ELBV2Client) DeregisterTargets(ctx context.Context, targetGroupArn string, instanceIDs []string) error {
+	var targets []*elbv2.TargetDescription
+	for _, id := range instanceIDs {
+		targets = append(targets, &elbv2.TargetDescription{Id: aws.String(id)})
+	}
+
+	input := &elbv2.DeregisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets:        targets,
+	}
+
+	_, err := e.Client.DeregisterTargetsWithContext(ctx, input)
+	return err
+}
+
+// GetDrainingTargets returns instances which are still in draining state in the target group
+func (e ELBV2Client) GetDrainingTargets(ctx context.Context, targetGroupArn string, instanceIDs []string) ([]string, error) {
+	var targets []*elbv2.TargetDescription
+	for _, id := range instanceIDs {
+		targets = append(targets, &elbv2.TargetDescription{Id: aws.String(id)})
+	}
+
+	input := &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets:        targets,
+	}
+
+	result, err := e.Client.DescribeTargetHealthWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, hd := range result.TargetHealthDescriptions {
+		if hd.TargetHealth != nil && aws.StringValue(hd.TargetHealth.State) == elbv2.TargetHealthStateEnumDraining {
+			ret = append(ret, aws.StringValue(hd.Target.Id))
+		}
+	}
+
+	return ret, nil
+}
+
+// GetDeregistrationDelay returns the deregistration delay of the target group
+func (e ELBV2Client) GetDeregistrationDelay(ctx context.Context, targetGroupArn string) (time.Duration, error) {
+	input := &elbv2.DescribeTargetGroupAttributesInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	}
+
+	result, err := e.Client.DescribeTargetGroupAttributesWithContext(ctx, input)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, attr := range result.Attributes {
+		if aws.StringValue(attr.Key) != "deregistration_delay.timeout_seconds" {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(aws.StringValue(attr.Value))
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, nil
+}
+
+// ForwardRule is a listener or a listener rule whose actions forward requests to a target group
+type ForwardRule struct {
+	ListenerArn string
+	RuleArn     string
+	Actions     []*elbv2.Action
+}
+
+// Name returns the listener rule ARN, or the listener ARN for the default actions
+func (f ForwardRule) Name() string {
+	if len(f.RuleArn) > 0 {
+		return f.RuleArn
+	}
+
+	return f.ListenerArn
+}
+
+// GetForwardRules returns listeners and listener rules of load balancers which forward requests to the target group
+func (e ELBV2Client) GetForwardRules(ctx context.Context, targetGroupArn string) ([]ForwardRule, error) {
+	tgs, err := e.Client.DescribeTargetGroupsWithContext(ctx, &elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: aws.StringSlice([]string{targetGroupArn}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lbArns []*string
+	for _, tg := range tgs.TargetGroups {
+		lbArns = append(lbArns, tg.LoadBalancerArns...)
+	}
+
+	var ret []ForwardRule
+	for _, lbArn := range lbArns {
+		var listeners []*elbv2.Listener
+		err := e.Client.DescribeListenersPagesWithContext(ctx, &elbv2.DescribeListenersInput{LoadBalancerArn: lbArn}, func(page *elbv2.DescribeListenersOutput, lastPage bool) bool {
+			listeners = append(listeners, page.Listeners...)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, listener := range listeners {
+			if forwardsTo(listener.DefaultActions, targetGroupArn) {
+				ret = append(ret, ForwardRule{ListenerArn: *listener.ListenerArn, Actions: listener.DefaultActions})
+			}
+
+			input := &elbv2.DescribeRulesInput{ListenerArn: listener.ListenerArn}
+			for {
+				result, err := e.Client.DescribeRulesWithContext(ctx, input)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, rule := range result.Rules {
+					if !aws.BoolValue(rule.IsDefault) && forwardsTo(rule.Actions, targetGroupArn) {
+						ret = append(ret, ForwardRule{ListenerArn: *listener.ListenerArn, RuleArn: *rule.RuleArn, Actions: rule.Actions})
+					}
+				}
+
+				if result.NextMarker == nil {
+					break
+				}
+				input.Marker = result.NextMarker
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// ModifyForwardRule replaces actions of the listener or the listener rule
+func (e ELBV2Client) ModifyForwardRule(ctx context.Context, rule ForwardRule, actions []*elbv2.Action) error {
+	if len(rule.RuleArn) > 0 {
+		_, err := e.Client.ModifyRuleWithContext(ctx, &elbv2.ModifyRuleInput{
+			RuleArn: aws.String(rule.RuleArn),
+			Actions: actions,
+		})
+		return err
+	}
+
+	_, err := e.Client.ModifyListenerWithContext(ctx, &elbv2.ModifyListenerInput{
+		ListenerArn:    aws.String(rule.ListenerArn),
+		DefaultActions: actions,
+	})
+	return err
+}
+
+// ForwardTargetGroups returns target groups of the forward action with their weights
+func ForwardTargetGroups(action *elbv2.Action) []*elbv2.TargetGroupTuple {
+	if action == nil || aws.StringValue(action.Type) != elbv2.ActionTypeEnumForward {
+		return nil
+	}
+
+	if action.ForwardConfig != nil && len(action.ForwardConfig.TargetGroups) > 0 {
+		return action.ForwardConfig.TargetGroups
+	}
+
+	if action.TargetGroupArn != nil {
+		return []*elbv2.TargetGroupTuple{{TargetGroupArn: action.TargetGroupArn, Weight: aws.Int64(1)}}
+	}
+
+	return nil
+}
+
+// forwardsTo returns true if any of actions forwards requests to the target group
+func forwardsTo(actions []*elbv2.Action, targetGroupArn string) bool {
+	for _, action := range actions {
+		for _, tg := range ForwardTargetGroups(action) {
+			if aws.StringValue(tg.TargetGroupArn) == targetGroupArn {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -17,6 +17,7 @@ limitations under the license.
 package aws
 
 import (
+	"bytes"
 	"io"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -60,3 +61,47 @@ func (s S3Client) GetManifest(bucket, key string) ([]byte, error) {
 
 	return body, nil
 }
+
+// PutObject uploads object to the bucket
+func (s S3Client) PutObject(bucket, key string, body []byte) error {
+	input := &s3.PutObjectInput{
+		Body:   bytes.NewReader(body),
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	_, err := s.Client.PutObject(input)
+	return err
+}
+
+// DeleteObject deletes object in the bucket
+func (s S3Client) DeleteObject(bucket, key string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	_, err := s.Client.DeleteObject(input)
+	return err
+}
+
+// ListObjectKeys returns keys of every object which starts with the prefix
+func (s S3Client) ListObjectKeys(bucket, prefix string) ([]string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	var keys []string
+	err := s.Client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, *obj.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -17,7 +17,11 @@ limitations under the license.
 package aws
 
 import (
+	"context"
+	"strconv"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -58,3 +62,70 @@ func (s SSMClient) SendCommand(target []*string, commands []*string) bool {
 
 	return true
 }
+
+// SendHealthCheckCommand runs shell commands on the instance for health check and returns the command ID
+func (s SSMClient) SendHealthCheckCommand(ctx context.Context, instanceID string, commands []string, executionTimeout int64) (string, error) {
+	return s.sendShellCommand(ctx, instanceID, "goployer health check", commands, executionTimeout)
+}
+
+// SendDiagnosticsCommand runs shell commands on the instance to collect diagnostics and returns the command ID
+func (s SSMClient) SendDiagnosticsCommand(ctx context.Context, instanceID string, commands []string, executionTimeout int64) (string, error) {
+	return s.sendShellCommand(ctx, instanceID, "goployer diagnostics", commands, executionTimeout)
+}
+
+// sendShellCommand runs shell commands on the instance with AWS-RunShellScript document
+func (s SSMClient) sendShellCommand(ctx context.Context, instanceID, comment string, commands []string, executionTimeout int64) (string, error) {
+	input := &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  aws.StringSlice([]string{instanceID}),
+		Comment:      aws.String(comment),
+		Parameters: map[string][]*string{
+			"commands":         aws.StringSlice(commands),
+			"executionTimeout": aws.StringSlice([]string{strconv.FormatInt(executionTimeout, 10)}),
+		},
+	}
+
+	result, err := s.Client.SendCommandWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	return *result.Command.CommandId, nil
+}
+
+// GetCommandInvocation returns status and response code of the command on the instance
+// The invocation can be missing for a moment right after the command is sent, so it is regarded as pending.
+func (s SSMClient) GetCommandInvocation(ctx context.Context, commandID, instanceID string) (string, int64, error) {
+	input := &ssm.GetCommandInvocationInput{
+		CommandId:  aws.String(commandID),
+		InstanceId: aws.String(instanceID),
+	}
+
+	result, err := s.Client.GetCommandInvocationWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeInvocationDoesNotExist {
+			return ssm.CommandInvocationStatusPending, 0, nil
+		}
+		return "", 0, err
+	}
+
+	return aws.StringValue(result.Status), aws.Int64Value(result.ResponseCode), nil
+}
+
+// GetCommandOutput returns status and standard output of the command on the instance
+func (s SSMClient) GetCommandOutput(ctx context.Context, commandID, instanceID string) (string, string, error) {
+	input := &ssm.GetCommandInvocationInput{
+		CommandId:  aws.String(commandID),
+		InstanceId: aws.String(instanceID),
+	}
+
+	result, err := s.Client.GetCommandInvocationWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeInvocationDoesNotExist {
+			return ssm.CommandInvocationStatusPending, "", nil
+		}
+		return "", "", err
+	}
+
+	return aws.StringValue(result.Status), aws.StringValue(result.StandardOutputContent), nil
+}
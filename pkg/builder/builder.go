@@ -25,6 +25,7 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"text/tabwriter"
@@ -71,6 +72,10 @@ type S3Provider struct {
 	Path string
 }
 
+type StaticProvider struct {
+	Userdata string
+}
+
 // Provide provides userdata from local file
 func (l LocalProvider) Provide() (string, error) {
 	if l.Path == "" {
@@ -94,6 +99,15 @@ func (s S3Provider) Provide() (string, error) {
 	return constants.EmptyString, nil
 }
 
+// Provide provides userdata which is already encoded with base64
+func (s StaticProvider) Provide() (string, error) {
+	if s.Userdata == "" {
+		return constants.EmptyString, errors.New("userdata is empty")
+	}
+
+	return s.Userdata, nil
+}
+
 // NewBuilder create new builder
 func NewBuilder(config *schemas.Config) (Builder, error) {
 	builder := Builder{}
@@ -316,6 +330,10 @@ func (b Builder) CheckValidation() error {
 		stackMap[stack.Env]++
 	}
 
+	if err := checkDependencies(b.Stacks); err != nil {
+		return err
+	}
+
 	// check validations in API test templates
 	if len(b.APITestTemplates) > 0 {
 		for _, att := range b.APITestTemplates {
@@ -459,6 +477,30 @@ func (b Builder) CheckValidation() error {
 			}
 		}
 
+		if err := checkRollout(stack); err != nil {
+			return err
+		}
+
+		if err := checkHealthyThreshold(stack); err != nil {
+			return err
+		}
+
+		if err := checkBake(stack); err != nil {
+			return err
+		}
+
+		if err := checkApproval(stack); err != nil {
+			return err
+		}
+
+		if err := checkSpotInterruption(stack); err != nil {
+			return err
+		}
+
+		if err := checkCanary(stack); err != nil {
+			return err
+		}
+
 		if stack.ReplacementType == constants.BlueGreenDeployment {
 			if stack.TerminationDelayRate > 100 {
 				return fmt.Errorf("termination_delay_rate cannot exceed 100. It should be 0<=x<=100")
@@ -481,23 +523,21 @@ func (b Builder) CheckValidation() error {
 			}
 
 			// Check target group
-			if len(region.TargetGroups) > 0 && region.HealthcheckTargetGroup == "" {
+			if len(region.TargetGroups) > 0 && region.HealthcheckTargetGroup == "" && len(region.HealthcheckTargetGroups) == 0 {
 				return errors.New("you have to choose one target group as healthcheck_target_group")
 			}
 
 			// Check load balancer
-			if len(region.LoadBalancers) > 0 && region.HealthcheckLB == "" {
+			if len(region.LoadBalancers) > 0 && region.HealthcheckLB == "" && len(region.HealthcheckLBs) == 0 {
 				return errors.New("you have to choose one load balancer as healthcheck_load_balancer")
 			}
 
-			// Check load balancer
-			if region.HealthcheckLB != "" && len(region.TargetGroups) > 0 {
-				return errors.New("you cannot use healthcheck_load_balancer with target_groups")
+			if err := checkHealthcheckQuorum(region); err != nil {
+				return err
 			}
 
-			// Check load balancer and target group
-			if region.HealthcheckLB != "" && region.HealthcheckTargetGroup != "" {
-				return errors.New("you cannot use healthcheck_target_group and healthcheck_load_balancer at the same time")
+			if err := checkHealthCheck(region); err != nil {
+				return err
 			}
 
 			// Check userdata
@@ -716,6 +756,18 @@ func (b Builder) PreConfigValidation() error {
 		return errors.New(constants.NoManifestFileExists)
 	}
 
+	if b.Config.MaxParallel < 0 {
+		return fmt.Errorf("max-parallel should not be negative: %d", b.Config.MaxParallel)
+	}
+
+	if b.Config.LaunchFailureThreshold < 0 {
+		return fmt.Errorf("launch-failure-threshold should not be negative: %d", b.Config.LaunchFailureThreshold)
+	}
+
+	if len(b.Config.OnInterrupt) > 0 && b.Config.OnInterrupt != constants.InterruptRollback && b.Config.OnInterrupt != constants.InterruptDetach {
+		return fmt.Errorf("on-interrupt should be one of %s or %s: %s", constants.InterruptRollback, constants.InterruptDetach, b.Config.OnInterrupt)
+	}
+
 	return nil
 }
 
@@ -767,6 +819,401 @@ func ContainsActions(target string, sas []schemas.ScheduledAction) bool {
 	return false
 }
 
+// checkHealthyThreshold validates thresholds of healthy instances for health checking
+func checkHealthyThreshold(stack schemas.Stack) error {
+	if stack.HealthyThresholdPercent < 0 || stack.HealthyThresholdPercent > 100 {
+		return fmt.Errorf("healthy_threshold_percent should be 0<=x<=100: %s", stack.Stack)
+	}
+
+	if stack.MinHealthyPerAZ < 0 {
+		return fmt.Errorf("min_healthy_per_az cannot be negative: %s", stack.Stack)
+	}
+
+	for _, region := range stack.Regions {
+		if len(region.AvailabilityZones) > 0 && stack.MinHealthyPerAZ*int64(len(region.AvailabilityZones)) > stack.Capacity.Desired {
+			return fmt.Errorf("desired capacity is smaller than min_healthy_per_az of all availability zones: %s(%s)", stack.Stack, region.Region)
+		}
+	}
+
+	return nil
+}
+
+// checkApproval validates approval gate of stack
+func checkApproval(stack schemas.Stack) error {
+	if stack.Approval == nil {
+		return nil
+	}
+
+	if _, ok := constants.ApprovalSteps[stack.Approval.Before]; !ok {
+		var steps []string
+		for step := range constants.ApprovalSteps {
+			steps = append(steps, step)
+		}
+		sort.Strings(steps)
+		return fmt.Errorf("step of approval gate is not supported: %s, available steps are %s", stack.Approval.Before, strings.Join(steps, ", "))
+	}
+
+	if stack.Approval.Timeout < 0 {
+		return fmt.Errorf("timeout of approval gate cannot be negative: %s", stack.Stack)
+	}
+
+	return nil
+}
+
+// checkSpotInterruption validates handling of spot interruptions of stack
+func checkSpotInterruption(stack schemas.Stack) error {
+	sc := stack.SpotInterruption
+	if sc == nil {
+		return nil
+	}
+
+	if sc.GracePeriod < 0 || sc.MaxExtension < 0 {
+		return fmt.Errorf("grace_period and max_extension of spot_interruption cannot be negative: %s", stack.Stack)
+	}
+
+	if sc.FallbackToOnDemand && !stack.MixedInstancesPolicy.Enabled {
+		return fmt.Errorf("fallback_to_on_demand of spot_interruption needs mixed_instances_policy: %s", stack.Stack)
+	}
+
+	return nil
+}
+
+// checkCanary validates canary configuration of stack
+func checkCanary(stack schemas.Stack) error {
+	if stack.Canary == nil {
+		return nil
+	}
+
+	if stack.ReplacementType != constants.CanaryDeployment {
+		return fmt.Errorf("canary configuration is only available with canary replacement type: %s", stack.Stack)
+	}
+
+	if !tool.IsStringInArray(stack.Canary.Mode, []string{constants.EmptyString, constants.CanaryLoadBalancerMode, constants.CanaryWeightedMode}) {
+		return fmt.Errorf("canary mode is not supported: %s, available modes are %s, %s", stack.Canary.Mode, constants.CanaryLoadBalancerMode, constants.CanaryWeightedMode)
+	}
+
+	if stack.Canary.Weight < 0 || stack.Canary.Weight >= 100 {
+		return fmt.Errorf("weight of canary should be 0<x<100: %d", stack.Canary.Weight)
+	}
+
+	for _, step := range stack.Canary.Steps {
+		if step.Weight <= 0 || step.Weight > 100 {
+			return fmt.Errorf("weight of canary step should be 0<x<=100: %d", step.Weight)
+		}
+
+		if step.Pause < 0 {
+			return fmt.Errorf("pause of canary step cannot be negative: %s", step.Pause)
+		}
+	}
+
+	if err := checkCanaryLoadBalancer(stack); err != nil {
+		return err
+	}
+
+	return checkCanaryAnalysis(stack)
+}
+
+// checkCanaryLoadBalancer validates listener and scheme of canary load balancer
+func checkCanaryLoadBalancer(stack schemas.Stack) error {
+	canary := stack.Canary
+	configured := canary.ListenerPort != 0 || len(canary.ListenerProtocol) > 0 || len(canary.CertificateArn) > 0 || len(canary.SslPolicy) > 0 || len(canary.Scheme) > 0 || len(canary.IngressCidrs) > 0
+	if configured && canary.Mode == constants.CanaryWeightedMode {
+		return fmt.Errorf("canary load balancer cannot be configured in weighted mode: %s", stack.Stack)
+	}
+
+	if canary.ListenerPort < 0 || canary.ListenerPort > 65535 {
+		return fmt.Errorf("listener port of canary is out of range: %d", canary.ListenerPort)
+	}
+
+	protocol := strings.ToUpper(canary.ListenerProtocol)
+	if !tool.IsStringInArray(protocol, []string{constants.EmptyString, constants.HTTPProtocol, constants.HTTPSProtocol}) {
+		return fmt.Errorf("listener protocol of canary is not supported: %s, available protocols are %s, %s", canary.ListenerProtocol, constants.HTTPProtocol, constants.HTTPSProtocol)
+	}
+
+	if protocol == constants.HTTPSProtocol && len(canary.CertificateArn) == 0 {
+		return fmt.Errorf("certificate_arn is required for HTTPS listener of canary: %s", stack.Stack)
+	}
+
+	if protocol != constants.HTTPSProtocol && (len(canary.CertificateArn) > 0 || len(canary.SslPolicy) > 0) {
+		return fmt.Errorf("certificate_arn and ssl_policy are only for HTTPS listener of canary: %s", stack.Stack)
+	}
+
+	if !tool.IsStringInArray(canary.Scheme, []string{constants.EmptyString, constants.InternetFacingScheme, constants.InternalScheme}) {
+		return fmt.Errorf("scheme of canary load balancer is not supported: %s, available schemes are %s, %s", canary.Scheme, constants.InternetFacingScheme, constants.InternalScheme)
+	}
+
+	for _, cidr := range canary.IngressCidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("ingress CIDR of canary is not valid: %s", cidr)
+		}
+	}
+
+	return nil
+}
+
+// checkCanaryAnalysis validates canary analysis of stack
+func checkCanaryAnalysis(stack schemas.Stack) error {
+	analysis := stack.Canary.Analysis
+	if analysis == nil {
+		return nil
+	}
+
+	if stack.Canary.Mode != constants.CanaryWeightedMode || len(stack.Canary.Steps) == 0 {
+		return fmt.Errorf("canary analysis needs weighted mode and steps: %s", stack.Stack)
+	}
+
+	if analysis.Alpha < 0 || analysis.Alpha >= 1 {
+		return fmt.Errorf("alpha of canary analysis should be 0<=x<1: %.3f", analysis.Alpha)
+	}
+
+	if analysis.PassScore < 0 || analysis.PassScore > 100 || analysis.MarginalScore < 0 || analysis.MarginalScore > 100 {
+		return fmt.Errorf("scores of canary analysis should be 0<=x<=100: %s", stack.Stack)
+	}
+
+	if analysis.PassScore > 0 && analysis.MarginalScore > analysis.PassScore {
+		return fmt.Errorf("marginal_score cannot exceed pass_score: %.1f > %.1f", analysis.MarginalScore, analysis.PassScore)
+	}
+
+	builtins := []string{constants.Target5xxRateMetric, constants.TargetResponseTimeP50Metric, constants.TargetResponseTimeP99Metric, constants.RequestCountMetric}
+	for _, metric := range analysis.Metrics {
+		if len(metric.Name) == 0 {
+			return errors.New("name of canary metric is required")
+		}
+
+		if !tool.IsStringInArray(metric.FailOn, []string{constants.EmptyString, constants.FailOnIncrease, constants.FailOnDecrease, constants.FailOnBoth}) {
+			return fmt.Errorf("fail_on of canary metric is not supported: %s, available values are %s, %s, %s", metric.FailOn, constants.FailOnIncrease, constants.FailOnDecrease, constants.FailOnBoth)
+		}
+
+		if metric.Tolerance < 0 {
+			return fmt.Errorf("tolerance of canary metric cannot be negative: %s", metric.Name)
+		}
+
+		if !tool.IsStringInArray(metric.Name, builtins) && (len(metric.Namespace) == 0 || len(metric.MetricName) == 0) {
+			return fmt.Errorf("custom canary metric needs namespace and metric_name: %s", metric.Name)
+		}
+	}
+
+	return nil
+}
+
+// checkBake validates bake configuration of stack
+func checkBake(stack schemas.Stack) error {
+	if stack.Bake == nil {
+		return nil
+	}
+
+	if stack.Bake.Duration <= 0 {
+		return fmt.Errorf("duration of bake should be positive: %s", stack.Stack)
+	}
+
+	for _, metric := range stack.Bake.Metrics {
+		if metric.Type != constants.Target5xxRateMetric && metric.Type != constants.TargetResponseTimeMetric {
+			return fmt.Errorf("metric type of bake is not supported: %s", metric.Type)
+		}
+
+		if metric.Period < 0 || metric.Period%time.Minute != 0 {
+			return fmt.Errorf("period of bake metric should be multiple of 1m: %s", metric.Period)
+		}
+
+		if len(metric.TargetGroup) > 0 {
+			continue
+		}
+
+		for _, region := range stack.Regions {
+			if region.HealthcheckTargetGroup == "" && len(region.HealthcheckTargetGroups) == 0 {
+				return fmt.Errorf("target_group of bake metric is needed if there is no healthcheck target group: %s(%s)", stack.Stack, region.Region)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRollout validates rollout configuration of stack
+func checkRollout(stack schemas.Stack) error {
+	if stack.Rollout == nil {
+		return nil
+	}
+
+	switch stack.Rollout.Strategy {
+	case constants.EmptyString, constants.AllAtOnceRollout:
+		if len(stack.Rollout.Waves) > 0 {
+			return fmt.Errorf("waves can only be used with %s rollout strategy: %s", constants.WavesRollout, stack.Stack)
+		}
+	case constants.WavesRollout:
+		if len(stack.Rollout.Waves) == 0 {
+			return fmt.Errorf("you have to specify waves for %s rollout strategy: %s", constants.WavesRollout, stack.Stack)
+		}
+
+		var regions []string
+		for _, region := range stack.Regions {
+			regions = append(regions, region.Region)
+		}
+
+		waved := map[string]bool{}
+		for i, wave := range stack.Rollout.Waves {
+			if len(wave) == 0 {
+				return fmt.Errorf("wave %d of %s has no region", i+1, stack.Stack)
+			}
+
+			for _, region := range wave {
+				if !tool.IsStringInArray(region, regions) {
+					return fmt.Errorf("region of wave does not exist in the stack: %s(%s)", stack.Stack, region)
+				}
+
+				if waved[region] {
+					return fmt.Errorf("region is duplicated in waves: %s(%s)", stack.Stack, region)
+				}
+				waved[region] = true
+			}
+		}
+
+		for _, region := range regions {
+			if !waved[region] {
+				return fmt.Errorf("region is not included in any wave: %s(%s)", stack.Stack, region)
+			}
+		}
+	default:
+		return fmt.Errorf("rollout strategy is not supported: %s", stack.Rollout.Strategy)
+	}
+
+	if stack.Rollout.BakeTime < 0 {
+		return fmt.Errorf("bake_time cannot be negative: %s", stack.Stack)
+	}
+
+	return nil
+}
+
+// checkHealthcheckQuorum checks if quorum does not exceed the number of target groups and load balancers for healthcheck
+func checkHealthcheckQuorum(region schemas.RegionConfig) error {
+	if region.HealthcheckQuorum < 0 {
+		return fmt.Errorf("healthcheck_quorum cannot be negative: %s", region.Region)
+	}
+
+	targets := len(region.HealthcheckTargetGroups) + len(region.HealthcheckLBs)
+	if region.HealthcheckTargetGroup != "" && !tool.IsStringInArray(region.HealthcheckTargetGroup, region.HealthcheckTargetGroups) {
+		targets++
+	}
+
+	if region.HealthcheckLB != "" && !tool.IsStringInArray(region.HealthcheckLB, region.HealthcheckLBs) {
+		targets++
+	}
+
+	if region.HealthcheckQuorum > targets {
+		return fmt.Errorf("healthcheck_quorum cannot exceed the number of target groups and load balancers for healthcheck: %s(%d)", region.Region, targets)
+	}
+
+	return nil
+}
+
+// checkHealthCheck checks if health check against instances is valid
+func checkHealthCheck(region schemas.RegionConfig) error {
+	hc := region.HealthCheck
+	if hc == nil {
+		return nil
+	}
+
+	if region.HealthcheckLB != "" || region.HealthcheckTargetGroup != "" || len(region.HealthcheckLBs) > 0 || len(region.HealthcheckTargetGroups) > 0 {
+		return fmt.Errorf("you cannot use health_check with healthcheck_target_group or healthcheck_load_balancer: %s", region.Region)
+	}
+
+	switch hc.Type {
+	case constants.HTTPHealthCheck:
+		if hc.Port <= 0 || hc.Port > 65535 {
+			return fmt.Errorf("port of health check should be between 1 and 65535: %d", hc.Port)
+		}
+
+		if len(hc.Commands) > 0 {
+			return fmt.Errorf("commands can only be used with %s health check", constants.SSMHealthCheck)
+		}
+	case constants.SSMHealthCheck:
+		if len(hc.Commands) == 0 {
+			return fmt.Errorf("you have to specify commands for %s health check: %s", constants.SSMHealthCheck, region.Region)
+		}
+	default:
+		return fmt.Errorf("health check type is not supported: %s", hc.Type)
+	}
+
+	if len(hc.Path) > 0 && !strings.HasPrefix(hc.Path, "/") {
+		return fmt.Errorf("path of health check should start with /: %s", hc.Path)
+	}
+
+	if hc.ExpectedStatus != 0 && (hc.ExpectedStatus < 100 || hc.ExpectedStatus > 599) {
+		return fmt.Errorf("expected_status of health check is not valid: %d", hc.ExpectedStatus)
+	}
+
+	if hc.Timeout < 0 {
+		return fmt.Errorf("timeout of health check cannot be negative: %s", hc.Timeout)
+	}
+
+	if hc.ConsecutiveSuccesses < 0 {
+		return fmt.Errorf("consecutive_successes of health check cannot be negative: %d", hc.ConsecutiveSuccesses)
+	}
+
+	return nil
+}
+
+// checkDependencies checks if stacks in depends_on exist and dependencies between stacks have no cycle
+func checkDependencies(stacks []schemas.Stack) error {
+	dependencies := map[string][]string{}
+	for _, stack := range stacks {
+		dependencies[stack.Stack] = stack.DependsOn
+	}
+
+	for _, stack := range stacks {
+		for _, dep := range stack.DependsOn {
+			if dep == stack.Stack {
+				return fmt.Errorf("stack cannot depend on itself: %s", stack.Stack)
+			}
+
+			if _, ok := dependencies[dep]; !ok {
+				return fmt.Errorf("stack in depends_on does not exist: %s(%s)", stack.Stack, dep)
+			}
+		}
+	}
+
+	// depth first search with visiting stacks on the current path
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, p := range path {
+				if p == name {
+					return fmt.Errorf("circular dependency between stacks: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		}
+
+		marks[name] = visiting
+		path = append(path, name)
+		for _, dep := range dependencies[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[name] = visited
+
+		return nil
+	}
+
+	for _, stack := range stacks {
+		if err := visit(stack.Stack); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // ValidCronExpression checks if the cron expression is valid or not
 // It should be [Minute] [Hour] [Day_of_Month] [Month_of_Year] [Day_of_Week]
 func ValidCronExpression(expression string) (bool, error) {
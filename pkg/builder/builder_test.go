@@ -450,16 +450,9 @@ func TestCheckValidationStack(t *testing.T) {
 	}
 	b.Stacks[0].Regions[0].HealthcheckLB = "test-lb"
 
-	if err := b.CheckValidation(); err == nil || err.Error() != "you cannot use healthcheck_load_balancer with target_groups" {
-		t.Errorf("validation failed: mixing healthcheck load balancer and target groups")
-	}
-	b.Stacks[0].Regions[0].TargetGroups = nil
-
-	if err := b.CheckValidation(); err == nil || err.Error() != "you cannot use healthcheck_target_group and healthcheck_load_balancer at the same time" {
-		t.Errorf("validation failed: mixing healthcheck target group and healthcheck load balancer")
-	}
-	b.Stacks[0].Regions[0].HealthcheckLB = ""
-	b.Stacks[0].Regions[0].LoadBalancers = nil
+	// target groups and load balancers can be mixed for healthcheck
+	b.Stacks[0].Regions[0].HealthcheckTargetGroups = []string{"grpc-tg"}
+	b.Stacks[0].Regions[0].HealthcheckLBs = []string{"internal-lb"}
 
 	b.Stacks[0].Userdata = schemas.Userdata{
 		Type: "local",
@@ -588,6 +581,313 @@ func TestHasProhibited(t *testing.T) {
 	}
 }
 
+func TestCheckRollout(t *testing.T) {
+	regions := []schemas.RegionConfig{{Region: "us-east-1"}, {Region: "eu-west-1"}, {Region: "ap-northeast-2"}}
+	testData := []struct {
+		rollout  *schemas.RolloutConfig
+		expected error
+	}{
+		{
+			rollout:  nil,
+			expected: nil,
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.AllAtOnceRollout},
+			expected: nil,
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout, Waves: [][]string{{"us-east-1"}, {"eu-west-1", "ap-northeast-2"}}, BakeTime: 10 * time.Minute},
+			expected: nil,
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Waves: [][]string{{"us-east-1"}}},
+			expected: fmt.Errorf("waves can only be used with waves rollout strategy: artd"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout},
+			expected: fmt.Errorf("you have to specify waves for waves rollout strategy: artd"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout, Waves: [][]string{{"us-east-1"}, {}, {"eu-west-1", "ap-northeast-2"}}},
+			expected: fmt.Errorf("wave 2 of artd has no region"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout, Waves: [][]string{{"us-east-1"}, {"eu-west-1", "us-west-2"}}},
+			expected: fmt.Errorf("region of wave does not exist in the stack: artd(us-west-2)"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout, Waves: [][]string{{"us-east-1"}, {"us-east-1", "eu-west-1", "ap-northeast-2"}}},
+			expected: fmt.Errorf("region is duplicated in waves: artd(us-east-1)"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout, Waves: [][]string{{"us-east-1"}, {"eu-west-1"}}},
+			expected: fmt.Errorf("region is not included in any wave: artd(ap-northeast-2)"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: "canary"},
+			expected: fmt.Errorf("rollout strategy is not supported: canary"),
+		},
+		{
+			rollout:  &schemas.RolloutConfig{Strategy: constants.WavesRollout, Waves: [][]string{{"us-east-1", "eu-west-1", "ap-northeast-2"}}, BakeTime: -time.Minute},
+			expected: fmt.Errorf("bake_time cannot be negative: artd"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkRollout(schemas.Stack{Stack: "artd", Regions: regions, Rollout: td.rollout})
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckHealthyThreshold(t *testing.T) {
+	regions := []schemas.RegionConfig{{Region: "ap-northeast-2", AvailabilityZones: []string{"ap-northeast-2a", "ap-northeast-2c"}}}
+	testData := []struct {
+		stack    schemas.Stack
+		expected error
+	}{
+		{
+			stack:    schemas.Stack{Stack: "artd"},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", HealthyThresholdPercent: 95, MinHealthyPerAZ: 1, Capacity: schemas.Capacity{Desired: 2}, Regions: regions},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", HealthyThresholdPercent: 101},
+			expected: fmt.Errorf("healthy_threshold_percent should be 0<=x<=100: artd"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", MinHealthyPerAZ: -1},
+			expected: fmt.Errorf("min_healthy_per_az cannot be negative: artd"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", MinHealthyPerAZ: 2, Capacity: schemas.Capacity{Desired: 3}, Regions: regions},
+			expected: fmt.Errorf("desired capacity is smaller than min_healthy_per_az of all availability zones: artd(ap-northeast-2)"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkHealthyThreshold(td.stack)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckHealthcheckQuorum(t *testing.T) {
+	testData := []struct {
+		region   schemas.RegionConfig
+		expected error
+	}{
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthcheckTargetGroup: "public-tg"},
+			expected: nil,
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthcheckTargetGroup: "public-tg", HealthcheckTargetGroups: []string{"grpc-tg"}, HealthcheckLBs: []string{"internal-elb"}, HealthcheckQuorum: 3},
+			expected: nil,
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthcheckQuorum: -1},
+			expected: fmt.Errorf("healthcheck_quorum cannot be negative: ap-northeast-2"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthcheckTargetGroup: "public-tg", HealthcheckTargetGroups: []string{"public-tg", "grpc-tg"}, HealthcheckQuorum: 3},
+			expected: fmt.Errorf("healthcheck_quorum cannot exceed the number of target groups and load balancers for healthcheck: ap-northeast-2(2)"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkHealthcheckQuorum(td.region)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckBake(t *testing.T) {
+	regions := []schemas.RegionConfig{{Region: "ap-northeast-2", HealthcheckTargetGroup: "hello-tg"}}
+	testData := []struct {
+		stack    schemas.Stack
+		expected error
+	}{
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: regions},
+			expected: nil,
+		},
+		{
+			stack: schemas.Stack{Stack: "artd", Regions: regions, Bake: &schemas.BakeConfig{
+				Duration: 15 * time.Minute,
+				Alarms:   []string{"hello-5xx"},
+				Metrics:  []schemas.BakeMetric{{Type: constants.Target5xxRateMetric, Threshold: 1}, {Type: constants.TargetResponseTimeMetric, Threshold: 0.5, Period: 5 * time.Minute}},
+			}},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: regions, Bake: &schemas.BakeConfig{}},
+			expected: fmt.Errorf("duration of bake should be positive: artd"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: regions, Bake: &schemas.BakeConfig{Duration: time.Minute, Metrics: []schemas.BakeMetric{{Type: "cpu"}}}},
+			expected: fmt.Errorf("metric type of bake is not supported: cpu"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: regions, Bake: &schemas.BakeConfig{Duration: time.Minute, Metrics: []schemas.BakeMetric{{Type: constants.Target5xxRateMetric, Period: 30 * time.Second}}}},
+			expected: fmt.Errorf("period of bake metric should be multiple of 1m: 30s"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}, Bake: &schemas.BakeConfig{Duration: time.Minute, Metrics: []schemas.BakeMetric{{Type: constants.Target5xxRateMetric}}}},
+			expected: fmt.Errorf("target_group of bake metric is needed if there is no healthcheck target group: artd(ap-northeast-2)"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkBake(td.stack)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckHealthCheck(t *testing.T) {
+	testData := []struct {
+		region   schemas.RegionConfig
+		expected error
+	}{
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2"},
+			expected: nil,
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "http", Port: 8080, Path: "/health", ExpectedStatus: 204, Timeout: 3 * time.Second, ConsecutiveSuccesses: 2}},
+			expected: nil,
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthcheckTargetGroup: "hello-tg", HealthCheck: &schemas.HealthCheckConfig{Type: "http", Port: 8080}},
+			expected: fmt.Errorf("you cannot use health_check with healthcheck_target_group or healthcheck_load_balancer: ap-northeast-2"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "tcp", Port: 8080}},
+			expected: fmt.Errorf("health check type is not supported: tcp"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "http"}},
+			expected: fmt.Errorf("port of health check should be between 1 and 65535: 0"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "ssm", Commands: []string{"systemctl is-active app"}, Timeout: 30 * time.Second}},
+			expected: nil,
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "ssm"}},
+			expected: fmt.Errorf("you have to specify commands for ssm health check: ap-northeast-2"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "http", Port: 8080, Commands: []string{"systemctl is-active app"}}},
+			expected: fmt.Errorf("commands can only be used with ssm health check"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "http", Port: 8080, Path: "health"}},
+			expected: fmt.Errorf("path of health check should start with /: health"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "http", Port: 8080, ExpectedStatus: 1000}},
+			expected: fmt.Errorf("expected_status of health check is not valid: 1000"),
+		},
+		{
+			region:   schemas.RegionConfig{Region: "ap-northeast-2", HealthCheck: &schemas.HealthCheckConfig{Type: "http", Port: 8080, ConsecutiveSuccesses: -1}},
+			expected: fmt.Errorf("consecutive_successes of health check cannot be negative: -1"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkHealthCheck(td.region)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	testData := []struct {
+		stacks   []schemas.Stack
+		expected error
+	}{
+		{
+			stacks:   []schemas.Stack{{Stack: "backend"}, {Stack: "frontend", DependsOn: []string{"backend"}}},
+			expected: nil,
+		},
+		{
+			stacks:   []schemas.Stack{{Stack: "db"}, {Stack: "backend", DependsOn: []string{"db"}}, {Stack: "batch", DependsOn: []string{"db"}}, {Stack: "frontend", DependsOn: []string{"backend", "batch"}}},
+			expected: nil,
+		},
+		{
+			stacks:   []schemas.Stack{{Stack: "frontend", DependsOn: []string{"backend"}}},
+			expected: fmt.Errorf("stack in depends_on does not exist: frontend(backend)"),
+		},
+		{
+			stacks:   []schemas.Stack{{Stack: "frontend", DependsOn: []string{"frontend"}}},
+			expected: fmt.Errorf("stack cannot depend on itself: frontend"),
+		},
+		{
+			stacks:   []schemas.Stack{{Stack: "db", DependsOn: []string{"frontend"}}, {Stack: "backend", DependsOn: []string{"db"}}, {Stack: "frontend", DependsOn: []string{"backend"}}},
+			expected: fmt.Errorf("circular dependency between stacks: db -> frontend -> backend -> db"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkDependencies(td.stacks)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
 func TestValidCronExpression(t *testing.T) {
 	testData := []struct {
 		input    string
@@ -932,3 +1232,263 @@ func TestBuilder_CheckValidation_ENIErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckApproval(t *testing.T) {
+	testData := []struct {
+		stack    schemas.Stack
+		expected error
+	}{
+		{
+			stack:    schemas.Stack{Stack: "artd"},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Approval: &schemas.ApprovalConfig{Before: "clean_previous_version", Timeout: 2 * time.Hour}},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Approval: &schemas.ApprovalConfig{Before: "deploy"}},
+			expected: fmt.Errorf("step of approval gate is not supported: deploy, available steps are bake, clean_previous_version, finish_additional_work, trigger_lifecycle_callbacks"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Approval: &schemas.ApprovalConfig{Before: "bake", Timeout: -time.Minute}},
+			expected: fmt.Errorf("timeout of approval gate cannot be negative: artd"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkApproval(td.stack)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckSpotInterruption(t *testing.T) {
+	testData := []struct {
+		stack    schemas.Stack
+		expected error
+	}{
+		{
+			stack:    schemas.Stack{Stack: "artd"},
+			expected: nil,
+		},
+		{
+			stack: schemas.Stack{
+				Stack:                "artd",
+				MixedInstancesPolicy: schemas.MixedInstancesPolicy{Enabled: true},
+				SpotInterruption:     &schemas.SpotInterruptionConfig{GracePeriod: 3 * time.Minute, FallbackToOnDemand: true},
+			},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", SpotInterruption: &schemas.SpotInterruptionConfig{MaxExtension: -time.Minute}},
+			expected: fmt.Errorf("grace_period and max_extension of spot_interruption cannot be negative: artd"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", SpotInterruption: &schemas.SpotInterruptionConfig{FallbackToOnDemand: true}},
+			expected: fmt.Errorf("fallback_to_on_demand of spot_interruption needs mixed_instances_policy: artd"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkSpotInterruption(td.stack)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckCanary(t *testing.T) {
+	testData := []struct {
+		stack    schemas.Stack
+		expected error
+	}{
+		{
+			stack:    schemas.Stack{Stack: "artd", ReplacementType: constants.BlueGreenDeployment},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Weight: 10}},
+			expected: nil,
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", ReplacementType: constants.BlueGreenDeployment, Canary: &schemas.CanaryConfig{Mode: constants.CanaryWeightedMode}},
+			expected: fmt.Errorf("canary configuration is only available with canary replacement type: artd"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &schemas.CanaryConfig{Mode: "dns"}},
+			expected: fmt.Errorf("canary mode is not supported: dns, available modes are load_balancer, weighted"),
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Weight: 100}},
+			expected: fmt.Errorf("weight of canary should be 0<x<100: 100"),
+		},
+		{
+			stack: schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &schemas.CanaryConfig{
+				Steps: []schemas.CanaryStep{{Weight: 5, Pause: 10 * time.Minute}, {Weight: 50, Pause: 15 * time.Minute}},
+			}},
+			expected: nil,
+		},
+		{
+			stack: schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &schemas.CanaryConfig{
+				Steps: []schemas.CanaryStep{{Weight: 5, Pause: 10 * time.Minute}, {Weight: 0}},
+			}},
+			expected: fmt.Errorf("weight of canary step should be 0<x<=100: 0"),
+		},
+		{
+			stack: schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &schemas.CanaryConfig{
+				Steps: []schemas.CanaryStep{{Weight: 5, Pause: -time.Minute}},
+			}},
+			expected: fmt.Errorf("pause of canary step cannot be negative: -1m0s"),
+		},
+	}
+
+	for _, td := range testData {
+		err := checkCanary(td.stack)
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckCanaryAnalysis(t *testing.T) {
+	steps := []schemas.CanaryStep{{Weight: 10, Pause: 10 * time.Minute}}
+	testData := []struct {
+		canary   schemas.CanaryConfig
+		expected error
+	}{
+		{
+			canary:   schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Steps: steps},
+			expected: nil,
+		},
+		{
+			canary: schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Steps: steps, Analysis: &schemas.CanaryAnalysis{
+				Alpha: 0.01,
+				Metrics: []schemas.CanaryMetric{
+					{Name: constants.TargetResponseTimeP99Metric, Tolerance: 20},
+					{Name: "jvm_heap", Namespace: "App", MetricName: "HeapUsage", FailOn: constants.FailOnIncrease},
+				},
+			}},
+			expected: nil,
+		},
+		{
+			canary:   schemas.CanaryConfig{Steps: steps, Analysis: &schemas.CanaryAnalysis{}},
+			expected: fmt.Errorf("canary analysis needs weighted mode and steps: artd"),
+		},
+		{
+			canary:   schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Steps: steps, Analysis: &schemas.CanaryAnalysis{PassScore: 60, MarginalScore: 80}},
+			expected: fmt.Errorf("marginal_score cannot exceed pass_score: 80.0 > 60.0"),
+		},
+		{
+			canary:   schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Steps: steps, Analysis: &schemas.CanaryAnalysis{Metrics: []schemas.CanaryMetric{{Name: "jvm_heap"}}}},
+			expected: fmt.Errorf("custom canary metric needs namespace and metric_name: jvm_heap"),
+		},
+		{
+			canary:   schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Steps: steps, Analysis: &schemas.CanaryAnalysis{Metrics: []schemas.CanaryMetric{{Name: constants.RequestCountMetric, FailOn: "up"}}}},
+			expected: fmt.Errorf("fail_on of canary metric is not supported: up, available values are increase, decrease, both"),
+		},
+	}
+
+	for _, td := range testData {
+		canary := td.canary
+		err := checkCanary(schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &canary})
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
+
+func TestCheckCanaryLoadBalancer(t *testing.T) {
+	testData := []struct {
+		canary   schemas.CanaryConfig
+		expected error
+	}{
+		{
+			canary:   schemas.CanaryConfig{},
+			expected: nil,
+		},
+		{
+			canary: schemas.CanaryConfig{
+				ListenerProtocol: "https",
+				ListenerPort:     8443,
+				CertificateArn:   "arn:aws:acm:ap-northeast-2:123456789012:certificate/12345678-1234-1234-1234-123456789012",
+				SslPolicy:        "ELBSecurityPolicy-TLS-1-2-2017-01",
+				Scheme:           constants.InternalScheme,
+				IngressCidrs:     []string{"10.0.0.0/8", "172.16.0.0/12"},
+			},
+			expected: nil,
+		},
+		{
+			canary:   schemas.CanaryConfig{Mode: constants.CanaryWeightedMode, Scheme: constants.InternalScheme},
+			expected: fmt.Errorf("canary load balancer cannot be configured in weighted mode: artd"),
+		},
+		{
+			canary:   schemas.CanaryConfig{ListenerPort: 70000},
+			expected: fmt.Errorf("listener port of canary is out of range: 70000"),
+		},
+		{
+			canary:   schemas.CanaryConfig{ListenerProtocol: "TCP"},
+			expected: fmt.Errorf("listener protocol of canary is not supported: TCP, available protocols are HTTP, HTTPS"),
+		},
+		{
+			canary:   schemas.CanaryConfig{ListenerProtocol: constants.HTTPSProtocol},
+			expected: fmt.Errorf("certificate_arn is required for HTTPS listener of canary: artd"),
+		},
+		{
+			canary:   schemas.CanaryConfig{SslPolicy: "ELBSecurityPolicy-TLS-1-2-2017-01"},
+			expected: fmt.Errorf("certificate_arn and ssl_policy are only for HTTPS listener of canary: artd"),
+		},
+		{
+			canary:   schemas.CanaryConfig{Scheme: "private"},
+			expected: fmt.Errorf("scheme of canary load balancer is not supported: private, available schemes are internet-facing, internal"),
+		},
+		{
+			canary:   schemas.CanaryConfig{IngressCidrs: []string{"10.0.0.0"}},
+			expected: fmt.Errorf("ingress CIDR of canary is not valid: 10.0.0.0"),
+		},
+	}
+
+	for _, td := range testData {
+		canary := td.canary
+		err := checkCanary(schemas.Stack{Stack: "artd", ReplacementType: constants.CanaryDeployment, Canary: &canary})
+		if td.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != td.expected.Error() {
+			t.Errorf("expected %s, got %v", td.expected.Error(), err)
+		}
+	}
+}
@@ -160,6 +160,77 @@ func (c Collector) UpdateStatus(asg string, status string, updateFields map[stri
 	return nil
 }
 
+// DeploymentRecord is the deployment information stored in the metric table
+type DeploymentRecord struct {
+	Name     string
+	Status   string
+	Stack    schemas.Stack
+	Config   schemas.Config
+	Userdata string
+}
+
+// GetDeploymentStatuses returns deployment statuses of autoscaling groups whose name starts with prefix
+func (c Collector) GetDeploymentStatuses(prefix string) (map[string]string, error) {
+	items, err := c.MetricClient.DynamoDBService.GetItemsWithPrefix(prefix, c.MetricConfig.Storage.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]string{}
+	for _, item := range items {
+		if item[constants.HashKey] == nil || item[constants.HashKey].S == nil {
+			continue
+		}
+
+		status := constants.EmptyString
+		if item["deployment_status"] != nil && item["deployment_status"].S != nil {
+			status = *item["deployment_status"].S
+		}
+		ret[*item[constants.HashKey].S] = status
+	}
+
+	return ret, nil
+}
+
+// GetDeploymentRecord retrieves the stored deployment information of autoscaling group
+func (c Collector) GetDeploymentRecord(asg string) (*DeploymentRecord, error) {
+	item, err := c.MetricClient.DynamoDBService.GetSingleItem(asg, c.MetricConfig.Storage.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		return nil, fmt.Errorf("there is no dynamodb record for %s", asg)
+	}
+
+	record := DeploymentRecord{
+		Name: asg,
+	}
+
+	for k, v := range item {
+		if v.S == nil {
+			continue
+		}
+
+		switch k {
+		case "deployment_status":
+			record.Status = *v.S
+		case "stack":
+			if err := json.Unmarshal([]byte(*v.S), &record.Stack); err != nil {
+				return nil, err
+			}
+		case "config":
+			if err := json.Unmarshal([]byte(*v.S), &record.Config); err != nil {
+				return nil, err
+			}
+		case "userdata":
+			record.Userdata = *v.S
+		}
+	}
+
+	return &record, nil
+}
+
 // UpdateStatistics update value of metric table
 func (c Collector) UpdateStatistics(asg string, updateFields map[string]interface{}) error {
 	if err := c.MetricClient.DynamoDBService.UpdateStatistics(asg, c.MetricConfig.Storage.Name, c.MetricConfig.Metrics.BaseTimezone, updateFields); err != nil {
@@ -87,6 +87,64 @@ const (
 	// DefaultHealthcheckGracePeriod is the default healthcheck grace period
 	DefaultHealthcheckGracePeriod = 300
 
+	// DefaultHealthCheckPath is the default path of http health check
+	DefaultHealthCheckPath = "/"
+
+	// DefaultHealthCheckExpectedStatus is the default status code of healthy response
+	DefaultHealthCheckExpectedStatus = 200
+
+	// DefaultHealthCheckTimeout is the default timeout of each health check request
+	DefaultHealthCheckTimeout = 5 * time.Second
+
+	// DefaultHealthCheckConsecutiveSuccesses is the default number of consecutive successes for an instance to be healthy
+	DefaultHealthCheckConsecutiveSuccesses = 1
+
+	// DefaultLaunchFailureThreshold is the default number of consecutive failed launches to stop health checking
+	DefaultLaunchFailureThreshold = 3
+
+	// DefaultDiagnosticsDir is the default directory to save diagnostics bundles of unhealthy instances
+	DefaultDiagnosticsDir = "goployer-diagnostics"
+
+	// DiagnosticsCloudInitLines is the number of lines of cloud-init output log in diagnostics bundle
+	DiagnosticsCloudInitLines = 500
+
+	// DiagnosticsCommandTimeout is the timeout of SSM command to collect diagnostics
+	DiagnosticsCommandTimeout = 60 * time.Second
+
+	// DefaultBakeMetricStatistic is the default statistic of response time metric during bake
+	DefaultBakeMetricStatistic = "p99"
+
+	// DefaultBakeMetricPeriod is the default period of metrics during bake
+	DefaultBakeMetricPeriod = 60 * time.Second
+
+	// DefaultCanaryAnalysisPeriod is the default period of datapoints compared in canary analysis
+	DefaultCanaryAnalysisPeriod = 60 * time.Second
+
+	// DefaultCanaryAnalysisAlpha is the default significance level of Mann-Whitney U test in canary analysis
+	DefaultCanaryAnalysisAlpha = 0.05
+
+	// DefaultCanaryTolerance is the default percentage of difference of medians which is allowed in canary analysis
+	DefaultCanaryTolerance = 10.0
+
+	// Default scores of canary analysis to pass and to be marginal
+	DefaultCanaryPassScore     = 90.0
+	DefaultCanaryMarginalScore = 70.0
+
+	// MinCanaryAnalysisSamples is the minimum number of datapoints of each version to compare a metric
+	MinCanaryAnalysisSamples = 3
+
+	// DefaultSpotInterruptionGracePeriod is the default time added to the health check timeout for each spot interruption
+	DefaultSpotInterruptionGracePeriod = 5 * time.Minute
+
+	// DrainingTimeoutMargin is the time to wait for draining in addition to the longest deregistration delay
+	DrainingTimeoutMargin = 30 * time.Second
+
+	// DefaultApprovalTimeout is the default time to wait for the decision of approval gate
+	DefaultApprovalTimeout = time.Hour
+
+	// ApprovalPollingInterval is the interval to check decisions of approval gate in the state backend
+	ApprovalPollingInterval = 5 * time.Second
+
 	// DefaultInstanceWarmup is the default duration for instance warmup
 	DefaultInstanceWarmup = 300
 
@@ -141,6 +199,12 @@ const (
 	// StepRunAPI = RunAPI
 	StepRunAPI = int64(8)
 
+	// StepBake = Bake
+	StepBake = int64(9)
+
+	// StepCanary = RunCanarySteps
+	StepCanary = int64(10)
+
 	// DefaultEnableStats is whether or not to enable gathering stats
 	DefaultEnableStats = true
 
@@ -156,6 +220,15 @@ const (
 	// SlackWebHookURL is environment key for slack webhook url
 	SlackWebHookURL = "SLACK_WEBHOOK_URL"
 
+	// ApprovalTokensVariable is environment key for tokens of approvers like approver=token,approver=token
+	ApprovalTokensVariable = "GOPLOYER_APPROVAL_TOKENS"
+
+	// StateBackendVariable is environment key for state backend of goployer server
+	StateBackendVariable = "GOPLOYER_STATE_BACKEND"
+
+	// StateLocationVariable is environment key for state location of goployer server
+	StateLocationVariable = "GOPLOYER_STATE_LOCATION"
+
 	// MinAPITestDuration is minimum duration of API test
 	MinAPITestDuration = 1 * time.Second
 
@@ -165,13 +238,139 @@ const (
 	// CanaryMark is a mark indicating that resources are related to Canary deployment
 	CanaryMark = "canary"
 
+	// Modes of routing traffic to canary target group
+	CanaryLoadBalancerMode = "load_balancer"
+	CanaryWeightedMode     = "weighted"
+
+	// DefaultCanaryWeight is the default percentage of traffic forwarded to canary target group in weighted mode
+	DefaultCanaryWeight = int64(5)
+
+	// Protocols and default ports of the listener of canary load balancer
+	HTTPProtocol     = "HTTP"
+	HTTPSProtocol    = "HTTPS"
+	DefaultHTTPPort  = int64(80)
+	DefaultHTTPSPort = int64(443)
+
+	// Schemes of canary load balancer
+	InternetFacingScheme = "internet-facing"
+	InternalScheme       = "internal"
+
+	// DefaultCanaryIngressCidr is the default CIDR which is allowed to access canary load balancer
+	DefaultCanaryIngressCidr = "0.0.0.0/0"
+
 	// Deployment Methods
 	BlueGreenDeployment     = "bluegreen"
 	CanaryDeployment        = "canary"
 	RollingUpdateDeployment = "rollingupdate"
 	DeployOnly              = "deployonly"
 
+	// StatusRolledBack is the deployment status when the new autoscaling group is rolled back
+	StatusRolledBack = "rolled_back"
+
+	// StatusAborted is the deployment status when the deployment is aborted by user and rolled back
+	StatusAborted = "aborted"
+
+	// StatusDetached is the deployment status when goployer is detached from the deployment by user
+	StatusDetached = "detached"
+
+	// Results of deployment pipeline in result file
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultSkipped   = "skipped"
+
+	// Exit codes of failed deployment
+	ExitCodeFailure                  = 1
+	ExitCodeDeployFailure            = 10
+	ExitCodeHealthCheckFailure       = 11
+	ExitCodeAdditionalWorkFailure    = 12
+	ExitCodeLifecycleCallbackFailure = 13
+	ExitCodeCleanFailure             = 14
+	ExitCodeMetricsFailure           = 15
+	ExitCodeAPITestFailure           = 16
+	ExitCodeRollbackFailure          = 17
+	ExitCodeBakeFailure              = 18
+	ExitCodeApprovalRejected         = 19
+	ExitCodeCanaryFailure            = 20
+	ExitCodeLockLost                 = 21
+
+	// Types of health check against instances
+	HTTPHealthCheck = "http"
+	SSMHealthCheck  = "ssm"
+
+	// Types of spot events found during health checking
+	SpotInterruptionEvent = "interruption"
+	SpotRebalanceEvent    = "rebalance_recommendation"
+
+	// Types of metrics watched during bake
+	Target5xxRateMetric      = "target_5xx_rate"
+	TargetResponseTimeMetric = "target_response_time"
+
+	// Built-in metrics of canary analysis
+	TargetResponseTimeP50Metric = "target_response_time_p50"
+	TargetResponseTimeP99Metric = "target_response_time_p99"
+	RequestCountMetric          = "request_count"
+
+	// Directions of difference which fail a metric of canary analysis
+	FailOnIncrease = "increase"
+	FailOnDecrease = "decrease"
+	FailOnBoth     = "both"
+
+	// Results of a metric of canary analysis
+	MetricPass   = "pass"
+	MetricHigh   = "high"
+	MetricLow    = "low"
+	MetricNoData = "nodata"
+
+	// Classifications of canary analysis
+	CanaryPass     = "pass"
+	CanaryMarginal = "marginal"
+	CanaryFail     = "fail"
+
+	// Rollout strategies across regions
+	AllAtOnceRollout = "all"
+	WavesRollout     = "waves"
+
+	// Status of approval gate
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalTimedOut = "timed_out"
+
+	// Sources of approval decision
+	ApprovalSourceTerminal = "terminal"
+	ApprovalSourceCommand  = "command"
+	ApprovalSourceHTTP     = "http"
+
+	// Actions on interrupt
+	InterruptRollback = "rollback"
+	InterruptDetach   = "detach"
+
 	DelimiterRegex = "[,/|!@$%^&*_=`~]+"
+
+	// LockKeyPrefix is the prefix of lock identifier in the lock table
+	LockKeyPrefix = "lock:"
+
+	// DefaultLockLeaseDuration is the default lease duration of deployment lock
+	DefaultLockLeaseDuration = 2 * time.Minute
+
+	// Lock Backends
+	DynamoDBLockBackend = "dynamodb"
+	FileLockBackend     = "file"
+	NoLockBackend       = "none"
+
+	// StateKeyPrefix is the prefix of state identifier in the state table
+	StateKeyPrefix = "state:"
+
+	// State Backends
+	FileStateBackend     = "file"
+	S3StateBackend       = "s3"
+	DynamoDBStateBackend = "dynamodb"
+	NoStateBackend       = "none"
+
+	// Deployment state status
+	StateRunning  = "running"
+	StateFailed   = "failed"
+	StateDetached = "detached"
 )
 
 var (
@@ -191,6 +390,12 @@ var (
 	// AWSConfigPath is the file path of aws config
 	AWSConfigPath = HomeDir() + "/.aws/config"
 
+	// DefaultLockDirectory is the directory of file lock backend
+	DefaultLockDirectory = HomeDir() + "/.goployer/locks"
+
+	// DefaultStateDirectory is the directory of file state backend
+	DefaultStateDirectory = HomeDir() + "/.goployer/states"
+
 	// AvailableBlockTypes is a list of available ebs block types
 	AvailableBlockTypes = []string{"io1", "io2", "gp2", "gp3", "st1", "sc1"}
 
@@ -208,8 +413,11 @@ var (
 
 	// StatusTimeStampKey is a map of timestamp keys with deployment status
 	StatusTimeStampKey = map[string]string{
-		"deployed":   "deployed_date",
-		"terminated": "terminated_date",
+		"deployed":       "deployed_date",
+		"terminated":     "terminated_date",
+		StatusRolledBack: "rolled_back_date",
+		StatusAborted:    "aborted_date",
+		StatusDetached:   "detached_date",
 	}
 
 	// AllowedAnswerYes is a list of allowed answers with yes
@@ -218,6 +426,14 @@ var (
 	// DaysOfWeek is a list of possible string value for cron expression
 	DaysOfWeek = []string{"MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN", "0", "1", "2", "3", "4", "5", "6", "7"}
 
+	// ApprovalSteps is a map of steps which can be gated by approval
+	ApprovalSteps = map[string]int64{
+		"finish_additional_work":      StepAdditionalWork,
+		"bake":                        StepBake,
+		"trigger_lifecycle_callbacks": StepTriggerLifecycleCallback,
+		"clean_previous_version":      StepCleanPreviousVersion,
+	}
+
 	// MinTimestamp means minimum timestamp YEAR/01/01 00:00:00 UTC
 	MinTimestamp = time.Date(YearNow, time.January, 1, 0, 0, 0, 0, time.UTC)
 )
@@ -0,0 +1,294 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// CanaryMetricResult is the comparison of a metric between canary and baseline
+type CanaryMetricResult struct {
+	Name           string
+	CanaryMedian   float64
+	BaselineMedian float64
+	PValue         float64
+	Result         string
+}
+
+// CanaryAnalysisResult is the score and classification of canary analysis with the result of each metric
+type CanaryAnalysisResult struct {
+	Score          float64
+	Classification string
+	Metrics        []CanaryMetricResult
+}
+
+// Summary returns the score and the breakdown of metrics of canary analysis
+func (r CanaryAnalysisResult) Summary() string {
+	lines := []string{fmt.Sprintf("score %.1f (%s)", r.Score, r.Classification)}
+	for _, m := range r.Metrics {
+		if m.Result == constants.MetricNoData {
+			lines = append(lines, fmt.Sprintf("- %s : not enough data", m.Name))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s : %s, canary %.3f / baseline %.3f, p-value %.3f", m.Name, m.Result, m.CanaryMedian, m.BaselineMedian, m.PValue))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// AnalyzeCanary compares metrics of canary and baseline target groups since the start
+// Datapoints of each metric are compared with Mann-Whitney U test, and a metric fails if the difference is significant
+// and the difference of medians exceeds the tolerance in the failing direction.
+func (c *Canary) AnalyzeCanary(ctx context.Context, client aws.Client, region string, start time.Time) (CanaryAnalysisResult, error) {
+	analysis := c.Stack.Canary.Analysis
+
+	canaryArn, err := c.findCanaryTargetGroup(ctx, client, region)
+	if err != nil {
+		return CanaryAnalysisResult{}, err
+	}
+
+	baselineArn, err := c.findBaselineTargetGroup(ctx, client, canaryArn)
+	if err != nil {
+		return CanaryAnalysisResult{}, err
+	}
+
+	lbs, err := client.ELBV2Service.GetLoadBalancerFromTG([]*string{&canaryArn})
+	if err != nil {
+		return CanaryAnalysisResult{}, err
+	}
+
+	if len(lbs) == 0 {
+		return CanaryAnalysisResult{}, fmt.Errorf("target group is not attached to any load balancer: %s", tool.ParseTargetGroupName(canaryArn))
+	}
+
+	period := analysis.Period
+	if period == 0 {
+		period = constants.DefaultCanaryAnalysisPeriod
+	}
+
+	alpha := analysis.Alpha
+	if alpha == 0 {
+		alpha = constants.DefaultCanaryAnalysisAlpha
+	}
+
+	end := time.Now()
+	var results []CanaryMetricResult
+	for _, metric := range canaryMetrics(analysis) {
+		canaryValues, err := getCanaryMetricValues(ctx, client, metric, *lbs[0], canaryArn, c.AsgNames[region], start, end, int64(period.Seconds()))
+		if err != nil {
+			return CanaryAnalysisResult{}, err
+		}
+
+		baselineValues, err := getCanaryMetricValues(ctx, client, metric, *lbs[0], baselineArn, c.LatestAsg[region], start, end, int64(period.Seconds()))
+		if err != nil {
+			return CanaryAnalysisResult{}, err
+		}
+
+		results = append(results, compareCanaryMetric(metric, canaryValues, baselineValues, alpha))
+	}
+
+	passScore, marginalScore := analysis.PassScore, analysis.MarginalScore
+	if passScore == 0 {
+		passScore = constants.DefaultCanaryPassScore
+	}
+
+	if marginalScore == 0 {
+		marginalScore = constants.DefaultCanaryMarginalScore
+	}
+
+	score, classification := scoreCanaryAnalysis(results, passScore, marginalScore)
+
+	return CanaryAnalysisResult{
+		Score:          score,
+		Classification: classification,
+		Metrics:        results,
+	}, nil
+}
+
+// findBaselineTargetGroup returns the target group which is forwarded together with the canary target group
+func (c *Canary) findBaselineTargetGroup(ctx context.Context, client aws.Client, canaryArn string) (string, error) {
+	rules, err := client.ELBV2Service.GetForwardRules(ctx, canaryArn)
+	if err != nil {
+		return constants.EmptyString, err
+	}
+
+	for _, rule := range rules {
+		for _, action := range rule.Actions {
+			if !hasTargetGroup(action, canaryArn) {
+				continue
+			}
+
+			for _, tg := range aws.ForwardTargetGroups(action) {
+				if arn := eaws.StringValue(tg.TargetGroupArn); arn != canaryArn {
+					return arn, nil
+				}
+			}
+		}
+	}
+
+	return constants.EmptyString, fmt.Errorf("there is no baseline target group forwarded with canary target group: %s", tool.ParseTargetGroupName(canaryArn))
+}
+
+// getCanaryMetricValues returns datapoints of metric of the version
+// Built-in metrics are taken from the target group, and custom metrics from the autoscaling group dimension.
+func getCanaryMetricValues(ctx context.Context, client aws.Client, metric schemas.CanaryMetric, lb, tg, asg string, start, end time.Time, period int64) ([]float64, error) {
+	switch metric.Name {
+	case constants.Target5xxRateMetric:
+		return client.CloudWatchService.GetTargetGroup5xxRates(ctx, lb, tg, start, end, period)
+	case constants.TargetResponseTimeP50Metric:
+		return client.CloudWatchService.GetTargetGroupResponseTimes(ctx, lb, tg, "p50", start, end, period)
+	case constants.TargetResponseTimeP99Metric:
+		return client.CloudWatchService.GetTargetGroupResponseTimes(ctx, lb, tg, "p99", start, end, period)
+	case constants.RequestCountMetric:
+		return client.CloudWatchService.GetTargetGroupRequestCountPerTarget(ctx, lb, tg, start, end, period)
+	}
+
+	stat := metric.Statistic
+	if len(stat) == 0 {
+		stat = "Average"
+	}
+
+	asgDimension := metric.AsgDimension
+	if len(asgDimension) == 0 {
+		asgDimension = "AutoScalingGroupName"
+	}
+
+	dimensions := map[string]string{asgDimension: asg}
+	for k, v := range metric.Dimensions {
+		dimensions[k] = v
+	}
+
+	return client.CloudWatchService.GetMetricValues(ctx, metric.Namespace, metric.MetricName, stat, dimensions, start, end, period)
+}
+
+// canaryMetrics returns built-in metrics with tolerances of the configuration and custom metrics
+func canaryMetrics(analysis *schemas.CanaryAnalysis) []schemas.CanaryMetric {
+	ret := []schemas.CanaryMetric{
+		{Name: constants.Target5xxRateMetric},
+		{Name: constants.TargetResponseTimeP50Metric},
+		{Name: constants.TargetResponseTimeP99Metric},
+		{Name: constants.RequestCountMetric, FailOn: constants.FailOnBoth},
+	}
+	builtins := len(ret)
+
+	for _, metric := range analysis.Metrics {
+		builtin := false
+		for i := range ret[:builtins] {
+			if ret[i].Name != metric.Name {
+				continue
+			}
+
+			builtin = true
+			ret[i].Tolerance = metric.Tolerance
+			if len(metric.FailOn) > 0 {
+				ret[i].FailOn = metric.FailOn
+			}
+		}
+
+		if !builtin {
+			ret = append(ret, metric)
+		}
+	}
+
+	return ret
+}
+
+// compareCanaryMetric compares datapoints of canary with the ones of baseline
+func compareCanaryMetric(metric schemas.CanaryMetric, canary, baseline []float64, alpha float64) CanaryMetricResult {
+	ret := CanaryMetricResult{
+		Name:           metric.Name,
+		CanaryMedian:   tool.Median(canary),
+		BaselineMedian: tool.Median(baseline),
+		PValue:         1,
+		Result:         constants.MetricPass,
+	}
+
+	if len(canary) < constants.MinCanaryAnalysisSamples || len(baseline) < constants.MinCanaryAnalysisSamples {
+		ret.Result = constants.MetricNoData
+		return ret
+	}
+
+	_, ret.PValue = tool.MannWhitneyU(canary, baseline)
+	if ret.PValue >= alpha {
+		return ret
+	}
+
+	tolerance := metric.Tolerance
+	if tolerance == 0 {
+		tolerance = constants.DefaultCanaryTolerance
+	}
+
+	diff := ret.CanaryMedian - ret.BaselineMedian
+	if math.Abs(diff) <= math.Abs(ret.BaselineMedian)*tolerance/100 {
+		return ret
+	}
+
+	failOn := metric.FailOn
+	if len(failOn) == 0 {
+		failOn = constants.FailOnIncrease
+	}
+
+	switch {
+	case diff > 0 && failOn != constants.FailOnDecrease:
+		ret.Result = constants.MetricHigh
+	case diff < 0 && failOn != constants.FailOnIncrease:
+		ret.Result = constants.MetricLow
+	}
+
+	return ret
+}
+
+// scoreCanaryAnalysis returns the percentage of passed metrics and the classification by the score
+// Metrics without enough data are not scored, and the analysis is marginal if no metric is scored.
+func scoreCanaryAnalysis(results []CanaryMetricResult, passScore, marginalScore float64) (float64, string) {
+	scored, passed := 0, 0
+	for _, r := range results {
+		if r.Result == constants.MetricNoData {
+			continue
+		}
+
+		scored++
+		if r.Result == constants.MetricPass {
+			passed++
+		}
+	}
+
+	if scored == 0 {
+		return 0, constants.CanaryMarginal
+	}
+
+	score := 100 * float64(passed) / float64(scored)
+	switch {
+	case score >= passScore:
+		return score, constants.CanaryPass
+	case score >= marginalScore:
+		return score, constants.CanaryMarginal
+	}
+
+	return score, constants.CanaryFail
+}
@@ -0,0 +1,143 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestCompareCanaryMetric(t *testing.T) {
+	baseline := []float64{100, 102, 98, 101, 99, 100, 103, 97}
+	testData := []struct {
+		metric   schemas.CanaryMetric
+		canary   []float64
+		baseline []float64
+		expected string
+	}{
+		{
+			metric:   schemas.CanaryMetric{Name: constants.TargetResponseTimeP99Metric},
+			canary:   []float64{101, 99, 100, 102, 98, 100, 97, 103},
+			baseline: baseline,
+			expected: constants.MetricPass,
+		},
+		{
+			metric:   schemas.CanaryMetric{Name: constants.TargetResponseTimeP99Metric},
+			canary:   []float64{150, 152, 148, 151, 149, 150, 153, 147},
+			baseline: baseline,
+			expected: constants.MetricHigh,
+		},
+		{
+			metric:   schemas.CanaryMetric{Name: constants.TargetResponseTimeP99Metric},
+			canary:   []float64{50, 52, 48, 51, 49, 50, 53, 47},
+			baseline: baseline,
+			expected: constants.MetricPass,
+		},
+		{
+			metric:   schemas.CanaryMetric{Name: constants.RequestCountMetric, FailOn: constants.FailOnBoth},
+			canary:   []float64{50, 52, 48, 51, 49, 50, 53, 47},
+			baseline: baseline,
+			expected: constants.MetricLow,
+		},
+		{
+			metric:   schemas.CanaryMetric{Name: constants.TargetResponseTimeP99Metric, Tolerance: 60},
+			canary:   []float64{150, 152, 148, 151, 149, 150, 153, 147},
+			baseline: baseline,
+			expected: constants.MetricPass,
+		},
+		{
+			metric:   schemas.CanaryMetric{Name: constants.TargetResponseTimeP99Metric},
+			canary:   []float64{150, 152},
+			baseline: baseline,
+			expected: constants.MetricNoData,
+		},
+	}
+
+	for _, td := range testData {
+		result := compareCanaryMetric(td.metric, td.canary, td.baseline, constants.DefaultCanaryAnalysisAlpha)
+		if result.Result != td.expected {
+			t.Errorf("%s: expected %s, got %s (p=%.4f)", td.metric.Name, td.expected, result.Result, result.PValue)
+		}
+	}
+}
+
+func TestScoreCanaryAnalysis(t *testing.T) {
+	testData := []struct {
+		results        []string
+		expectedScore  float64
+		expectedResult string
+	}{
+		{
+			results:        []string{constants.MetricPass, constants.MetricPass, constants.MetricPass, constants.MetricNoData},
+			expectedScore:  100,
+			expectedResult: constants.CanaryPass,
+		},
+		{
+			results:        []string{constants.MetricPass, constants.MetricPass, constants.MetricPass, constants.MetricHigh},
+			expectedScore:  75,
+			expectedResult: constants.CanaryMarginal,
+		},
+		{
+			results:        []string{constants.MetricPass, constants.MetricLow, constants.MetricHigh},
+			expectedScore:  100.0 / 3,
+			expectedResult: constants.CanaryFail,
+		},
+		{
+			results:        []string{constants.MetricNoData},
+			expectedScore:  0,
+			expectedResult: constants.CanaryMarginal,
+		},
+	}
+
+	for _, td := range testData {
+		var results []CanaryMetricResult
+		for _, r := range td.results {
+			results = append(results, CanaryMetricResult{Result: r})
+		}
+
+		score, result := scoreCanaryAnalysis(results, constants.DefaultCanaryPassScore, constants.DefaultCanaryMarginalScore)
+		if score != td.expectedScore || result != td.expectedResult {
+			t.Errorf("expected %.1f(%s), got %.1f(%s)", td.expectedScore, td.expectedResult, score, result)
+		}
+	}
+}
+
+func TestCanaryMetrics(t *testing.T) {
+	analysis := &schemas.CanaryAnalysis{
+		Metrics: []schemas.CanaryMetric{
+			{Name: constants.TargetResponseTimeP99Metric, Tolerance: 20},
+			{Name: constants.RequestCountMetric, FailOn: constants.FailOnDecrease},
+			{Name: "jvm_heap", Namespace: "App", MetricName: "HeapUsage", FailOn: constants.FailOnIncrease},
+		},
+	}
+
+	expected := []schemas.CanaryMetric{
+		{Name: constants.Target5xxRateMetric},
+		{Name: constants.TargetResponseTimeP50Metric},
+		{Name: constants.TargetResponseTimeP99Metric, Tolerance: 20},
+		{Name: constants.RequestCountMetric, FailOn: constants.FailOnDecrease},
+		{Name: "jvm_heap", Namespace: "App", MetricName: "HeapUsage", FailOn: constants.FailOnIncrease},
+	}
+
+	if diff := deep.Equal(canaryMetrics(analysis), expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// Bake watches CloudWatch alarms and metrics of the new version for the bake duration
+// The previous version is not cleaned until the bake is finished, so the deployment can be rolled back if anything is breached.
+func (d *Deployer) Bake(ctx context.Context, config schemas.Config) error {
+	bake := d.Stack.Bake
+	if bake == nil || !d.StepStatus[constants.StepAdditionalWork] {
+		d.StepStatus[constants.StepBake] = true
+		return nil
+	}
+
+	d.Logger.Infof("Start baking %s for %s", d.GetPipelineName(), bake.Duration)
+	d.Slack.SendSimpleMessage(fmt.Sprintf(":bread: Start baking %s for %s", d.GetPipelineName(), bake.Duration))
+
+	start := time.Now()
+	for {
+		for _, region := range d.Stack.Regions {
+			if config.Region != "" && config.Region != region.Region {
+				continue
+			}
+
+			client, err := selectClientFromList(d.AWSClients, region.Region)
+			if err != nil {
+				return err
+			}
+
+			breaches, err := d.checkBake(ctx, client, region, start)
+			if err != nil {
+				return err
+			}
+
+			if len(breaches) > 0 {
+				err := fmt.Errorf("bake of %s failed: %s", d.AsgNames[region.Region], strings.Join(breaches, ", "))
+				d.Slack.SendSimpleMessage(fmt.Sprintf(":x: %s", err.Error()))
+				return err
+			}
+		}
+
+		elapsed := time.Since(start)
+		if elapsed >= bake.Duration {
+			break
+		}
+
+		interval := config.PollingInterval
+		if remain := bake.Duration - elapsed; remain < interval {
+			interval = remain
+		}
+
+		d.Logger.Infof("Baking %s : %s left", d.GetPipelineName(), tool.RoundTime(bake.Duration-elapsed))
+		if err := tool.SleepWithContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+
+	d.Logger.Infof("Bake is finished without breach : %s", d.GetPipelineName())
+	d.Slack.SendSimpleMessage(fmt.Sprintf(":white_check_mark: Bake is finished without breach : %s", d.GetPipelineName()))
+	d.StepStatus[constants.StepBake] = true
+
+	return nil
+}
+
+// checkBake returns breached alarms and metrics of region since the bake started
+func (d *Deployer) checkBake(ctx context.Context, client aws.Client, region schemas.RegionConfig, start time.Time) ([]string, error) {
+	var breaches []string
+	bake := d.Stack.Bake
+
+	if len(bake.Alarms) > 0 {
+		states, err := client.CloudWatchService.GetAlarmStates(ctx, bake.Alarms)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alarm := range bake.Alarms {
+			state, ok := states[alarm]
+			if !ok {
+				return nil, fmt.Errorf("alarm does not exist: %s(%s)", alarm, region.Region)
+			}
+
+			if state == cloudwatch.StateValueAlarm {
+				breaches = append(breaches, fmt.Sprintf("alarm %s is in ALARM state", alarm))
+			}
+		}
+	}
+
+	for _, metric := range bake.Metrics {
+		values, err := d.getBakeMetricValues(ctx, client, region, metric, start)
+		if err != nil {
+			return nil, err
+		}
+
+		if breach := checkBakeMetric(metric, values); len(breach) > 0 {
+			breaches = append(breaches, breach)
+		}
+	}
+
+	return breaches, nil
+}
+
+// getBakeMetricValues returns datapoints of metric of the target group since the bake started
+func (d *Deployer) getBakeMetricValues(ctx context.Context, client aws.Client, region schemas.RegionConfig, metric schemas.BakeMetric, start time.Time) ([]float64, error) {
+	tg := metric.TargetGroup
+	if len(tg) == 0 {
+		tg = healthcheckTargetGroups(region)[0]
+	}
+
+	targetGroupArn := tg
+	if !tool.IsTargetGroupArn(tg, region.Region) {
+		tgARNs, err := client.ELBV2Service.GetTargetGroupARNs([]string{tg})
+		if err != nil {
+			return nil, err
+		}
+		targetGroupArn = *tgARNs[0]
+	}
+
+	lbs, err := client.ELBV2Service.GetLoadBalancerFromTG([]*string{&targetGroupArn})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lbs) == 0 {
+		return nil, fmt.Errorf("target group is not attached to any load balancer: %s", tg)
+	}
+
+	period := metric.Period
+	if period == 0 {
+		period = constants.DefaultBakeMetricPeriod
+	}
+
+	switch metric.Type {
+	case constants.Target5xxRateMetric:
+		return client.CloudWatchService.GetTargetGroup5xxRates(ctx, *lbs[0], targetGroupArn, start, time.Now(), int64(period.Seconds()))
+	case constants.TargetResponseTimeMetric:
+		stat := metric.Statistic
+		if len(stat) == 0 {
+			stat = constants.DefaultBakeMetricStatistic
+		}
+		return client.CloudWatchService.GetTargetGroupResponseTimes(ctx, *lbs[0], targetGroupArn, stat, start, time.Now(), int64(period.Seconds()))
+	}
+
+	return nil, fmt.Errorf("metric type is not supported: %s", metric.Type)
+}
+
+// checkBakeMetric returns the breach of metric with the largest datapoint if it exceeds the threshold
+func checkBakeMetric(metric schemas.BakeMetric, values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	peak := values[0]
+	for _, v := range values[1:] {
+		if v > peak {
+			peak = v
+		}
+	}
+
+	if peak <= metric.Threshold {
+		return ""
+	}
+
+	name := metric.Type
+	if metric.Type == constants.TargetResponseTimeMetric {
+		stat := metric.Statistic
+		if len(stat) == 0 {
+			stat = constants.DefaultBakeMetricStatistic
+		}
+		name = fmt.Sprintf("%s(%s)", metric.Type, stat)
+	}
+
+	return fmt.Sprintf("%s %.3f exceeds %.3f", name, peak, metric.Threshold)
+}
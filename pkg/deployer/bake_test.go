@@ -0,0 +1,63 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestCheckBakeMetric(t *testing.T) {
+	testData := []struct {
+		metric   schemas.BakeMetric
+		values   []float64
+		expected string
+	}{
+		{
+			metric:   schemas.BakeMetric{Type: "target_5xx_rate", Threshold: 1},
+			values:   nil,
+			expected: "",
+		},
+		{
+			metric:   schemas.BakeMetric{Type: "target_5xx_rate", Threshold: 1},
+			values:   []float64{0, 0.5, 1},
+			expected: "",
+		},
+		{
+			metric:   schemas.BakeMetric{Type: "target_5xx_rate", Threshold: 1},
+			values:   []float64{0.2, 3.5, 1.5},
+			expected: "target_5xx_rate 3.500 exceeds 1.000",
+		},
+		{
+			metric:   schemas.BakeMetric{Type: "target_response_time", Threshold: 0.5},
+			values:   []float64{0.3, 0.8},
+			expected: "target_response_time(p99) 0.800 exceeds 0.500",
+		},
+		{
+			metric:   schemas.BakeMetric{Type: "target_response_time", Statistic: "Average", Threshold: 0.5},
+			values:   []float64{0.6},
+			expected: "target_response_time(Average) 0.600 exceeds 0.500",
+		},
+	}
+
+	for _, td := range testData {
+		if got := checkBakeMetric(td.metric, td.values); got != td.expected {
+			t.Errorf("expected %q, got %q", td.expected, got)
+		}
+	}
+}
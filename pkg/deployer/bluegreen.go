@@ -17,9 +17,9 @@ limitations under the license.
 package deployer
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	Logger "github.com/sirupsen/logrus"
 
@@ -59,7 +59,7 @@ func (b *BlueGreen) GetDeployer() *Deployer {
 }
 
 // CheckPreviousResources checks if there is any previous version of autoscaling group
-func (b *BlueGreen) CheckPreviousResources(config schemas.Config) error {
+func (b *BlueGreen) CheckPreviousResources(ctx context.Context, config schemas.Config) error {
 	err := b.CheckPrevious(config)
 	if err != nil {
 		return err
@@ -69,7 +69,7 @@ func (b *BlueGreen) CheckPreviousResources(config schemas.Config) error {
 }
 
 // Deploy function
-func (b *BlueGreen) Deploy(config schemas.Config) error {
+func (b *BlueGreen) Deploy(ctx context.Context, config schemas.Config) error {
 	if !b.StepStatus[constants.StepCheckPrevious] {
 		return nil
 	}
@@ -98,25 +98,26 @@ func (b *BlueGreen) Deploy(config schemas.Config) error {
 }
 
 // HealthChecking does health checking for blue-green deployment
-func (b *BlueGreen) HealthChecking(config schemas.Config) error {
+func (b *BlueGreen) HealthChecking(ctx context.Context, config schemas.Config) error {
 	healthy := false
 
 	for !healthy {
-		b.Logger.Debugf("Start Timestamp: %d, timeout: %s", config.StartTimestamp, config.Timeout)
-		isTimeout, _ := tool.CheckTimeout(config.StartTimestamp, config.Timeout)
-		if isTimeout {
-			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
+		b.Logger.Debugf("Start Timestamp: %d, timeout: %s, extension: %s", config.StartTimestamp, config.Timeout, b.HealthCheckExtension)
+		if b.HealthCheckTimeoutExceeded(config) {
+			return b.Deployer.HealthCheckTimeout(ctx, config)
 		}
 
-		isDone, err := b.Deployer.HealthChecking(config)
+		isDone, err := b.Deployer.HealthChecking(ctx, config)
 		if err != nil {
-			return errors.New("error happened while health checking")
+			return fmt.Errorf("error happened while health checking: %s", err.Error())
 		}
 
 		if isDone {
 			healthy = true
 		} else {
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -124,7 +125,7 @@ func (b *BlueGreen) HealthChecking(config schemas.Config) error {
 }
 
 // FinishAdditionalWork processes additional work for the new deployment
-func (b *BlueGreen) FinishAdditionalWork(config schemas.Config) error {
+func (b *BlueGreen) FinishAdditionalWork(ctx context.Context, config schemas.Config) error {
 	if !b.StepStatus[constants.StepDeploy] {
 		return nil
 	}
@@ -143,7 +144,7 @@ func (b *BlueGreen) FinishAdditionalWork(config schemas.Config) error {
 }
 
 // TriggerLifecycleCallbacks runs lifecycle callbacks before cleaning.
-func (b *BlueGreen) TriggerLifecycleCallbacks(config schemas.Config) error {
+func (b *BlueGreen) TriggerLifecycleCallbacks(ctx context.Context, config schemas.Config) error {
 	if !b.StepStatus[constants.StepAdditionalWork] {
 		return nil
 	}
@@ -151,7 +152,7 @@ func (b *BlueGreen) TriggerLifecycleCallbacks(config schemas.Config) error {
 }
 
 // CleanPreviousVersion cleans previous version of autoscaling group
-func (b *BlueGreen) CleanPreviousVersion(config schemas.Config) error {
+func (b *BlueGreen) CleanPreviousVersion(ctx context.Context, config schemas.Config) error {
 	if !b.StepStatus[constants.StepTriggerLifecycleCallback] {
 		return nil
 	}
@@ -165,7 +166,7 @@ func (b *BlueGreen) CleanPreviousVersion(config schemas.Config) error {
 	}
 
 	if !skipped {
-		if err := b.CleanPreviousAutoScalingGroup(config); err != nil {
+		if err := b.CleanPreviousAutoScalingGroup(ctx, config); err != nil {
 			return err
 		}
 	}
@@ -174,7 +175,7 @@ func (b *BlueGreen) CleanPreviousVersion(config schemas.Config) error {
 }
 
 // CleanChecking checks Termination status
-func (b *BlueGreen) CleanChecking(config schemas.Config) error {
+func (b *BlueGreen) CleanChecking(ctx context.Context, config schemas.Config) error {
 	if !b.StepStatus[constants.StepCleanPreviousVersion] {
 		return nil
 	}
@@ -186,7 +187,7 @@ func (b *BlueGreen) CleanChecking(config schemas.Config) error {
 			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
 		}
 
-		isDone, err := b.Deployer.CleanChecking(config)
+		isDone, err := b.Deployer.CleanChecking(ctx, config)
 		if err != nil {
 			return errors.New("error happened while health checking")
 		}
@@ -195,7 +196,9 @@ func (b *BlueGreen) CleanChecking(config schemas.Config) error {
 			done = true
 		} else {
 			b.Logger.Info("All stacks are not ready to be terminated... Please waiting...")
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -204,7 +207,7 @@ func (b *BlueGreen) CleanChecking(config schemas.Config) error {
 }
 
 // GatherMetrics gathers the whole metrics from deployer
-func (b *BlueGreen) GatherMetrics(config schemas.Config) error {
+func (b *BlueGreen) GatherMetrics(ctx context.Context, config schemas.Config) error {
 	if config.DisableMetrics {
 		return nil
 	}
@@ -223,6 +226,11 @@ func (b *BlueGreen) GatherMetrics(config schemas.Config) error {
 }
 
 // RunAPITest tries to run API Test
-func (b *BlueGreen) RunAPITest(config schemas.Config) error {
+func (b *BlueGreen) RunAPITest(ctx context.Context, config schemas.Config) error {
 	return b.Deployer.RunAPITest(config)
 }
+
+// Rollback removes the new autoscaling group and restores the previous version
+func (b *BlueGreen) Rollback(ctx context.Context, config schemas.Config) error {
+	return b.RollbackDeployment(ctx, config)
+}
@@ -17,11 +17,13 @@ limitations under the license.
 package deployer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	eaws "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -73,7 +75,7 @@ func (c *Canary) GetDeployer() *Deployer {
 }
 
 // CheckPreviousResources checks if there is any previous version of autoscaling group
-func (c *Canary) CheckPreviousResources(config schemas.Config) error {
+func (c *Canary) CheckPreviousResources(ctx context.Context, config schemas.Config) error {
 	err := c.CheckPrevious(config)
 	if err != nil {
 		return err
@@ -83,7 +85,7 @@ func (c *Canary) CheckPreviousResources(config schemas.Config) error {
 }
 
 // Deploy runs deployments with canary approach
-func (c *Canary) Deploy(config schemas.Config) error {
+func (c *Canary) Deploy(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepCheckPrevious] {
 		return nil
 	}
@@ -120,6 +122,15 @@ func (c *Canary) Deploy(config schemas.Config) error {
 			return err
 		}
 
+		if c.Weighted() {
+			changedRegionConfig, err := c.RunWeightedCanaryDeployment(ctx, config, region, tgDetail, canaryVersion, latestASG)
+			if err != nil {
+				return err
+			}
+			c.Stack.Regions[i] = changedRegionConfig
+			continue
+		}
+
 		// Check canary load balancer
 		lbSg, canaryLoadBalancer, err := c.GetLoadBalancerAndSecurityGroupForCanary(region, tgDetail, config.CompleteCanary)
 		if err != nil {
@@ -151,25 +162,26 @@ func (c *Canary) Deploy(config schemas.Config) error {
 }
 
 // HealthChecking does health checking for canary deployment
-func (c *Canary) HealthChecking(config schemas.Config) error {
+func (c *Canary) HealthChecking(ctx context.Context, config schemas.Config) error {
 	healthy := false
 
 	for !healthy {
-		c.Logger.Debugf("Start Timestamp: %d, timeout: %s", config.StartTimestamp, config.Timeout)
-		isTimeout, _ := tool.CheckTimeout(config.StartTimestamp, config.Timeout)
-		if isTimeout {
-			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
+		c.Logger.Debugf("Start Timestamp: %d, timeout: %s, extension: %s", config.StartTimestamp, config.Timeout, c.HealthCheckExtension)
+		if c.HealthCheckTimeoutExceeded(config) {
+			return c.Deployer.HealthCheckTimeout(ctx, config)
 		}
 
-		isDone, err := c.Deployer.HealthChecking(config)
+		isDone, err := c.Deployer.HealthChecking(ctx, config)
 		if err != nil {
-			return errors.New("error happened while health checking")
+			return fmt.Errorf("error happened while health checking: %s", err.Error())
 		}
 
 		if isDone {
 			healthy = true
 		} else {
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -177,26 +189,37 @@ func (c *Canary) HealthChecking(config schemas.Config) error {
 }
 
 // FinishAdditionalWork processes additional work for the new deployment
-func (c *Canary) FinishAdditionalWork(config schemas.Config) error {
+func (c *Canary) FinishAdditionalWork(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepDeploy] {
 		return nil
 	}
 
+	skipped := len(config.Region) > 0 && !CheckRegionExist(config.Region, c.Stack.Regions)
+
 	if config.CompleteCanary {
+		// promote canary target group by forwarding the whole traffic to it
+		if c.Weighted() && !skipped {
+			if err := c.shiftTrafficOfRegions(ctx, config, 100); err != nil {
+				return err
+			}
+		}
+
 		c.StepStatus[constants.StepAdditionalWork] = true
 		return nil
 	}
 
-	skipped := len(config.Region) > 0 && !CheckRegionExist(config.Region, c.Stack.Regions)
-
 	if !skipped {
-		// attach to the previous target group
-		if len(c.PrevTargetGroups) > 0 {
+		if c.Weighted() {
+			if err := c.shiftTrafficOfRegions(ctx, config, c.canaryWeight()); err != nil {
+				return err
+			}
+		} else if len(c.PrevTargetGroups) > 0 {
+			// attach to the previous target group
 			if err := c.AttachToOriginalTargetGroups(config); err != nil {
 				return err
 			}
 
-			if err := c.HealthChecking(config); err != nil {
+			if err := c.HealthChecking(ctx, config); err != nil {
 				return err
 			}
 		}
@@ -212,7 +235,7 @@ func (c *Canary) FinishAdditionalWork(config schemas.Config) error {
 }
 
 // TriggerLifecycleCallbacks runs lifecycle callbacks before cleaning.
-func (c *Canary) TriggerLifecycleCallbacks(config schemas.Config) error {
+func (c *Canary) TriggerLifecycleCallbacks(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepAdditionalWork] {
 		return nil
 	}
@@ -224,7 +247,7 @@ func (c *Canary) TriggerLifecycleCallbacks(config schemas.Config) error {
 }
 
 // CleanPreviousVersion cleans previous version of autoscaling group or canary target group
-func (c *Canary) CleanPreviousVersion(config schemas.Config) error {
+func (c *Canary) CleanPreviousVersion(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepTriggerLifecycleCallback] {
 		return nil
 	}
@@ -237,7 +260,8 @@ func (c *Canary) CleanPreviousVersion(config schemas.Config) error {
 		}
 	}
 
-	if len(c.PrevAsgs) == 0 && !config.CompleteCanary {
+	completed := c.completed(config)
+	if len(c.PrevAsgs) == 0 && !completed {
 		c.Logger.Debug("canary is being used and there is no resources to delete")
 		skipped = true
 	}
@@ -245,7 +269,7 @@ func (c *Canary) CleanPreviousVersion(config schemas.Config) error {
 	if !skipped {
 		c.Logger.Debugf("Start to clean resources from previous canary deployment")
 		for _, region := range c.Stack.Regions {
-			if err := c.CleanPreviousCanaryResources(region, config.CompleteCanary); err != nil {
+			if err := c.CleanPreviousCanaryResources(region, completed); err != nil {
 				return err
 			}
 		}
@@ -262,7 +286,7 @@ func (c *Canary) CleanPreviousVersion(config schemas.Config) error {
 }
 
 // GatherMetrics gathers the whole metrics from deployer
-func (c *Canary) GatherMetrics(config schemas.Config) error {
+func (c *Canary) GatherMetrics(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepCleanChecking] {
 		return nil
 	}
@@ -276,7 +300,7 @@ func (c *Canary) GatherMetrics(config schemas.Config) error {
 		}
 	}
 
-	if !config.CompleteCanary {
+	if !c.completed(config) {
 		c.Logger.Debug("Skip gathering metrics because canary is now applied")
 		return nil
 	}
@@ -290,17 +314,17 @@ func (c *Canary) GatherMetrics(config schemas.Config) error {
 }
 
 // RunAPITest tries to run API Test
-func (c *Canary) RunAPITest(config schemas.Config) error {
+func (c *Canary) RunAPITest(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepGatherMetrics] {
 		return nil
 	}
 
-	if !config.CompleteCanary {
+	if !c.completed(config) {
 		c.Logger.Debug("Skip API test because canary is now applied")
 		return nil
 	}
 
-	err := c.RunAPITest(config)
+	err := c.Deployer.RunAPITest(config)
 	if err != nil {
 		return err
 	}
@@ -309,6 +333,48 @@ func (c *Canary) RunAPITest(config schemas.Config) error {
 	return nil
 }
 
+// Rollback removes the canary autoscaling group
+// Canary load balancer and target group are kept so that the next canary deployment could reuse them.
+func (c *Canary) Rollback(ctx context.Context, config schemas.Config) error {
+	if c.completed(config) {
+		return errors.New("rollback is not supported while completing canary deployment")
+	}
+
+	if c.Weighted() {
+		for _, region := range c.Stack.Regions {
+			if len(c.AsgNames[region.Region]) == 0 || (config.Region != "" && config.Region != region.Region) {
+				continue
+			}
+
+			if err := c.RemoveCanaryTraffic(ctx, region.Region); err != nil {
+				c.Logger.Warnf("failed to remove canary target group from listeners: %s", err.Error())
+			}
+		}
+	}
+
+	return c.RollbackDeployment(ctx, config)
+}
+
+// shiftTrafficOfRegions forwards the percentage of traffic to canary target group in every target region
+func (c *Canary) shiftTrafficOfRegions(ctx context.Context, config schemas.Config, weight int64) error {
+	for _, region := range c.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			continue
+		}
+
+		if err := c.ShiftCanaryTraffic(ctx, region.Region, weight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completed returns true if canary is completed by --complete-canary or promoted by canary steps
+func (c *Canary) completed(config schemas.Config) bool {
+	return config.CompleteCanary || c.promoted()
+}
+
 // ValidateCanaryDeployment validates if configuration is right for canary deployment
 func (c *Canary) ValidateCanaryDeployment(config schemas.Config, region string) error {
 	if c.DeploymentFlag[region] != constants.CanaryDeployment && config.CompleteCanary {
@@ -447,7 +513,7 @@ func (c *Canary) ChangeTargetGroupInfo(newTgName string, region schemas.RegionCo
 }
 
 // CleanChecking checks Termination status
-func (c *Canary) CleanChecking(config schemas.Config) error {
+func (c *Canary) CleanChecking(ctx context.Context, config schemas.Config) error {
 	if !c.StepStatus[constants.StepCleanPreviousVersion] {
 		return nil
 	}
@@ -461,7 +527,7 @@ func (c *Canary) CleanChecking(config schemas.Config) error {
 			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
 		}
 
-		isDone, err = c.Deployer.CleanChecking(config)
+		isDone, err = c.Deployer.CleanChecking(ctx, config)
 		if err != nil {
 			return errors.New("error happened while health checking")
 		}
@@ -470,7 +536,9 @@ func (c *Canary) CleanChecking(config schemas.Config) error {
 			done = true
 		} else {
 			c.Logger.Info("All stacks are not ready to be terminated... Please waiting...")
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -534,10 +602,11 @@ func (c *Canary) CreateCanaryLoadBalancer(region schemas.RegionConfig, groupID *
 		return nil, err
 	}
 
+	scheme := canaryScheme(c.Stack.Canary)
 	subnets := region.SubnetIDs
 	if len(subnets) == 0 {
 		c.Logger.Info("Not Subnet ID Specific")
-		subnets, err = client.EC2Service.GetSubnets(region.VPC, region.UsePublicSubnets, availabilityZones)
+		subnets, err = client.EC2Service.GetSubnets(region.VPC, region.UsePublicSubnets && scheme != constants.InternalScheme, availabilityZones)
 		if err != nil {
 			return nil, err
 		}
@@ -546,7 +615,7 @@ func (c *Canary) CreateCanaryLoadBalancer(region schemas.RegionConfig, groupID *
 		c.Logger.Infof("Subnet ID are Specific : %s", subnetIds)
 	}
 
-	lb, err := client.ELBV2Service.CreateLoadBalancer(newLBName, subnets, groupID)
+	lb, err := client.ELBV2Service.CreateLoadBalancer(newLBName, subnets, groupID, scheme)
 	if err != nil {
 		return nil, err
 	}
@@ -566,11 +635,55 @@ func (c *Canary) AttachCanaryTargetGroup(lbArn, tgArn string, region schemas.Reg
 		return err
 	}
 
+	listener := canaryListener(c.Stack.Canary)
 	if len(existingListeners) == 0 {
-		return client.ELBV2Service.CreateNewListener(lbArn, tgArn)
+		return client.ELBV2Service.CreateNewListener(lbArn, tgArn, listener)
+	}
+
+	return client.ELBV2Service.ModifyListener(existingListeners[0].ListenerArn, tgArn, listener)
+}
+
+// canaryListener returns the listener configuration of canary load balancer
+func canaryListener(canary *schemas.CanaryConfig) aws.ListenerConfig {
+	listener := aws.ListenerConfig{
+		Port:     constants.DefaultHTTPPort,
+		Protocol: constants.HTTPProtocol,
+	}
+
+	if canary == nil {
+		return listener
+	}
+
+	if strings.ToUpper(canary.ListenerProtocol) == constants.HTTPSProtocol {
+		listener.Port = constants.DefaultHTTPSPort
+		listener.Protocol = constants.HTTPSProtocol
+		listener.CertificateArn = canary.CertificateArn
+		listener.SslPolicy = canary.SslPolicy
+	}
+
+	if canary.ListenerPort > 0 {
+		listener.Port = canary.ListenerPort
+	}
+
+	return listener
+}
+
+// canaryScheme returns the scheme of canary load balancer
+func canaryScheme(canary *schemas.CanaryConfig) string {
+	if canary == nil || len(canary.Scheme) == 0 {
+		return constants.InternetFacingScheme
+	}
+
+	return canary.Scheme
+}
+
+// canaryIngressCidrs returns CIDRs which are allowed to access canary load balancer
+func canaryIngressCidrs(canary *schemas.CanaryConfig) []string {
+	if canary == nil || len(canary.IngressCidrs) == 0 {
+		return []string{constants.DefaultCanaryIngressCidr}
 	}
 
-	return client.ELBV2Service.ModifyListener(existingListeners[0].ListenerArn, tgArn)
+	return canary.IngressCidrs
 }
 
 // GetEC2CanarySecurityGroup creates a new security group for canary
@@ -678,8 +791,8 @@ func (c *Canary) CreateCanaryLBSecurityGroup(tg *elbv2.TargetGroup, region schem
 	}
 
 	// inbound
-	if err := client.EC2Service.UpdateInboundRules(*groupID, "tcp", "0.0.0.0/0", "inbound from internet", 80, 80); err != nil {
-		c.Logger.Warn(err.Error())
+	if err := c.allowCanaryIngress(client, *groupID); err != nil {
+		return nil, err
 	}
 
 	// outbound
@@ -690,6 +803,98 @@ func (c *Canary) CreateCanaryLBSecurityGroup(tg *elbv2.TargetGroup, region schem
 	return groupID, nil
 }
 
+// allowCanaryIngress allows ingress CIDRs to access the listener port of canary load balancer
+// Inbound rules which are not configured anymore, like the ones of the previous port or CIDRs, are revoked.
+func (c *Canary) allowCanaryIngress(client aws.Client, groupID string) error {
+	port := canaryListener(c.Stack.Canary).Port
+	cidrs := canaryIngressCidrs(c.Stack.Canary)
+
+	sgDetails, err := client.EC2Service.GetSecurityGroupDetails([]*string{eaws.String(groupID)})
+	if err != nil {
+		return err
+	}
+
+	var existing []*ec2.IpPermission
+	for _, sg := range sgDetails {
+		existing = append(existing, sg.IpPermissions...)
+	}
+
+	if stale := staleCanaryIngress(existing, port, cidrs); len(stale) > 0 {
+		if err := client.EC2Service.RevokeInboundPermissions(groupID, stale); err != nil {
+			return fmt.Errorf("failed to revoke stale inbound rules of canary load balancer, run `goployer canary abort` to create it again: %s", err.Error())
+		}
+		c.Logger.Debugf("Stale inbound rules of canary load balancer are revoked: %s", groupID)
+	}
+
+	for _, cidr := range cidrs {
+		if allowsCanaryIngress(existing, port, cidr) {
+			continue
+		}
+
+		if err := client.EC2Service.UpdateInboundRules(groupID, "tcp", cidr, "inbound to canary load balancer", port, port); err != nil {
+			c.Logger.Warn(err.Error())
+		}
+	}
+
+	return nil
+}
+
+// staleCanaryIngress returns inbound rules which do not allow the CIDRs to access the listener port
+func staleCanaryIngress(permissions []*ec2.IpPermission, port int64, cidrs []string) []*ec2.IpPermission {
+	var stale []*ec2.IpPermission
+	for _, p := range permissions {
+		if !isListenerPermission(p, port) {
+			stale = append(stale, p)
+			continue
+		}
+
+		var ranges []*ec2.IpRange
+		for _, r := range p.IpRanges {
+			if !tool.IsStringInArray(eaws.StringValue(r.CidrIp), cidrs) {
+				ranges = append(ranges, r)
+			}
+		}
+
+		if len(ranges) == 0 && len(p.Ipv6Ranges) == 0 && len(p.PrefixListIds) == 0 && len(p.UserIdGroupPairs) == 0 {
+			continue
+		}
+
+		stale = append(stale, &ec2.IpPermission{
+			IpProtocol:       p.IpProtocol,
+			FromPort:         p.FromPort,
+			ToPort:           p.ToPort,
+			IpRanges:         ranges,
+			Ipv6Ranges:       p.Ipv6Ranges,
+			PrefixListIds:    p.PrefixListIds,
+			UserIdGroupPairs: p.UserIdGroupPairs,
+		})
+	}
+
+	return stale
+}
+
+// allowsCanaryIngress returns true if one of inbound rules allows the CIDR to access the listener port
+func allowsCanaryIngress(permissions []*ec2.IpPermission, port int64, cidr string) bool {
+	for _, p := range permissions {
+		if !isListenerPermission(p, port) {
+			continue
+		}
+
+		for _, r := range p.IpRanges {
+			if eaws.StringValue(r.CidrIp) == cidr {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isListenerPermission returns true if the inbound rule is only for the listener port
+func isListenerPermission(p *ec2.IpPermission, port int64) bool {
+	return eaws.StringValue(p.IpProtocol) == "tcp" && eaws.Int64Value(p.FromPort) == port && eaws.Int64Value(p.ToPort) == port
+}
+
 // ReduceOriginalAutoscalingGroupCount set existing autoscaling group count to -1
 func (c *Canary) ReduceOriginalAutoscalingGroupCount(region schemas.RegionConfig) error {
 	client, err := selectClientFromList(c.AWSClients, region.Region)
@@ -769,30 +974,33 @@ func (c *Canary) CleanPreviousCanaryResources(region schemas.RegionConfig, compl
 		}
 	}
 
-	c.Logger.Debugf("Start to delete load balancer and security group for canary")
 	if completeCanary {
-		if err := c.DeleteLoadBalancer(region); err != nil {
-			return err
-		}
+		return c.DeleteCanaryLoadBalancer(region)
+	}
 
-		if err := c.LoadBalancerDeletionChecking(region); err != nil {
-			return err
-		}
+	return nil
+}
 
-		if err := c.DeleteEC2IngressRules(region); err != nil {
-			return err
-		}
+// DeleteCanaryLoadBalancer deletes canary load balancer and security groups for canary
+func (c *Canary) DeleteCanaryLoadBalancer(region schemas.RegionConfig) error {
+	c.Logger.Debugf("Start to delete load balancer and security group for canary")
+	if err := c.DeleteLoadBalancer(region); err != nil {
+		return err
+	}
 
-		if err := c.DeleteEC2SecurityGroup(region); err != nil {
-			return err
-		}
+	if err := c.LoadBalancerDeletionChecking(region); err != nil {
+		return err
+	}
 
-		if err := c.DeleteLBSecurityGroup(region); err != nil {
-			return err
-		}
+	if err := c.DeleteEC2IngressRules(region); err != nil {
+		return err
 	}
 
-	return nil
+	if err := c.DeleteEC2SecurityGroup(region); err != nil {
+		return err
+	}
+
+	return c.DeleteLBSecurityGroup(region)
 }
 
 // DeleteLoadBalancer deletes load balancer
@@ -1058,22 +1266,25 @@ func (c *Canary) CompleteCanaryDeployment(config schemas.Config, region schemas.
 		return err
 	}
 
-	nis := getNetworkInterfaces(instancesDetail)
-
-	if err := c.DetachSecurityGroup(nis, region, *c.SecurityGroup[region.Region]); err != nil {
-		return err
-	}
-
 	if err := c.RemoveCanaryTag(latestASG, region); err != nil {
 		return err
 	}
 
-	if err := c.DetachCanaryTargetGroup(latestASG, region, asgDetail.TargetGroupARNs); err != nil {
-		return err
-	}
+	// canary target group of weighted canary is kept because it is already in production listeners
+	if !c.Weighted() {
+		nis := getNetworkInterfaces(instancesDetail)
 
-	if err := c.ChangeLaunchTemplateVersion(latestASG, asgDetail.LaunchTemplate, region, *c.SecurityGroup[region.Region]); err != nil {
-		return err
+		if err := c.DetachSecurityGroup(nis, region, *c.SecurityGroup[region.Region]); err != nil {
+			return err
+		}
+
+		if err := c.DetachCanaryTargetGroup(latestASG, region, asgDetail.TargetGroupARNs); err != nil {
+			return err
+		}
+
+		if err := c.ChangeLaunchTemplateVersion(latestASG, asgDetail.LaunchTemplate, region, *c.SecurityGroup[region.Region]); err != nil {
+			return err
+		}
 	}
 
 	appliedCapacity, err := c.DecideCapacity(config.ForceManifestCapacity, config.CompleteCanary, region.Region, len(c.PrevAsgs[region.Region]), c.Stack.RollingUpdateInstanceCount)
@@ -1088,6 +1299,7 @@ func (c *Canary) CompleteCanaryDeployment(config schemas.Config, region schemas.
 
 	// settings for health checking
 	c.Stack.Capacity.Desired = appliedCapacity.Desired
+	c.AppliedCapacity = &appliedCapacity
 	c.AsgNames[region.Region] = latestASG
 
 	return nil
@@ -1117,10 +1329,25 @@ func (c *Canary) GetLoadBalancerAndSecurityGroupForCanary(region schemas.RegionC
 			}
 		} else {
 			c.Logger.Debugf("Found existing load balancer for canary: %s", *canaryLoadBalancer.LoadBalancerName)
+			if scheme := canaryScheme(c.Stack.Canary); eaws.StringValue(canaryLoadBalancer.Scheme) != scheme {
+				c.Logger.Warnf("existing canary load balancer is %s, not %s. Run `goployer canary abort` to create it again with the new scheme", eaws.StringValue(canaryLoadBalancer.Scheme), scheme)
+			}
+
 			lbSg, err = c.GetCanaryLoadBalancerSecurityGroup(region)
 			if err != nil {
 				return nil, nil, err
 			}
+
+			// listener port or ingress CIDRs could be changed after the load balancer is created
+			if lbSg != nil && !completeCanary {
+				client, err := selectClientFromList(c.AWSClients, region.Region)
+				if err != nil {
+					return nil, nil, err
+				}
+				if err := c.allowCanaryIngress(client, *lbSg); err != nil {
+					return nil, nil, err
+				}
+			}
 		}
 
 		if lbSg == nil && !completeCanary {
@@ -0,0 +1,384 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/templates"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// CanaryStatus is the canary in progress of a stack in a region
+type CanaryStatus struct {
+	Stack            string
+	Region           string
+	Mode             string
+	AutoScalingGroup string
+	Baseline         string
+	TargetGroup      string
+	Weight           int64
+	Healthy          int
+	Desired          int64
+	CreatedTime      time.Time
+	Age              string
+}
+
+// GetCanaryStatus returns the canary in progress in the region, or nil if there is no canary
+// In load balancer mode, the weight is the share of canary instances among the instances of canary and baseline
+// because both of them are attached to the original target groups.
+func (c *Canary) GetCanaryStatus(ctx context.Context, region string) (*CanaryStatus, error) {
+	client, err := selectClientFromList(c.AWSClients, region)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := client.EC2Service.GetAllMatchingAutoscalingGroupsWithPrefix(tool.BuildPrefixName(c.AwsConfig.Name, c.Stack.Env, region))
+	if err != nil {
+		return nil, err
+	}
+
+	canary, baseline := findCanaryGroups(groups)
+	if canary == nil {
+		return nil, nil
+	}
+
+	status := CanaryStatus{
+		Stack:            c.Stack.Stack,
+		Region:           region,
+		Mode:             constants.CanaryLoadBalancerMode,
+		AutoScalingGroup: eaws.StringValue(canary.AutoScalingGroupName),
+		Desired:          eaws.Int64Value(canary.DesiredCapacity),
+		CreatedTime:      eaws.TimeValue(canary.CreatedTime),
+		Age:              tool.RoundTime(time.Since(eaws.TimeValue(canary.CreatedTime))),
+	}
+
+	if c.Weighted() {
+		status.Mode = constants.CanaryWeightedMode
+	}
+
+	if baseline != nil {
+		status.Baseline = eaws.StringValue(baseline.AutoScalingGroupName)
+	}
+
+	for _, tg := range eaws.StringValueSlice(canary.TargetGroupARNs) {
+		if tool.IsCanaryTargetGroupArn(tg, region) {
+			status.TargetGroup = tg
+			break
+		}
+	}
+
+	if len(status.TargetGroup) == 0 {
+		status.Healthy = countInServiceInstances(canary)
+	} else {
+		hosts, err := client.ELBV2Service.GetHostInTarget(ctx, canary, eaws.String(status.TargetGroup), false, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, host := range hosts {
+			if host.Valid {
+				status.Healthy++
+			}
+		}
+	}
+
+	if c.Weighted() && len(status.TargetGroup) > 0 {
+		rules, err := client.ELBV2Service.GetForwardRules(ctx, status.TargetGroup)
+		if err != nil {
+			return nil, err
+		}
+		status.Weight = trafficWeight(rules, status.TargetGroup)
+	} else if baseline != nil {
+		status.Weight = instanceShare(countInServiceInstances(canary), countInServiceInstances(baseline))
+	} else {
+		status.Weight = 100
+	}
+
+	return &status, nil
+}
+
+// AbortCanary removes the canary in progress and restores the capacity of the baseline autoscaling group
+// Canary autoscaling group is deleted with its canary target group, and the canary load balancer and security groups
+// are also deleted in load balancer mode.
+func (c *Canary) AbortCanary(ctx context.Context, config schemas.Config) error {
+	canaryTargetGroups := map[string][]*string{}
+	for _, region := range c.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			c.Logger.Debugf("This region is skipped by user : %s", region.Region)
+			continue
+		}
+
+		client, err := selectClientFromList(c.AWSClients, region.Region)
+		if err != nil {
+			return err
+		}
+
+		groups, err := client.EC2Service.GetAllMatchingAutoscalingGroupsWithPrefix(tool.BuildPrefixName(c.AwsConfig.Name, c.Stack.Env, region.Region))
+		if err != nil {
+			return err
+		}
+
+		canary, baseline := findCanaryGroups(groups)
+		if canary == nil {
+			return fmt.Errorf("no canary is in progress: %s(%s)", c.Stack.Stack, region.Region)
+		}
+
+		asg := eaws.StringValue(canary.AutoScalingGroupName)
+		c.AsgNames[region.Region] = asg
+
+		for _, tg := range canary.TargetGroupARNs {
+			if tool.IsCanaryTargetGroupArn(*tg, region.Region) {
+				canaryTargetGroups[region.Region] = append(canaryTargetGroups[region.Region], tg)
+			}
+		}
+
+		// canary target group cannot be deleted while listeners still forward traffic to it
+		if c.Weighted() && len(canaryTargetGroups[region.Region]) > 0 {
+			if err := c.RemoveCanaryTraffic(ctx, region.Region); err != nil {
+				return fmt.Errorf("failed to remove canary target group from listeners: %s", err.Error())
+			}
+		}
+
+		if err := c.RemoveCanaryTag(asg, region); err != nil {
+			return err
+		}
+
+		if baseline != nil {
+			c.LatestAsg[region.Region] = eaws.StringValue(baseline.AutoScalingGroupName)
+			c.PrevInstanceCount[region.Region] = restoredCapacity(groupCapacity(baseline), c.Stack.Capacity)
+		}
+	}
+
+	// the baseline is resized before the canary is deleted
+	if err := c.RollbackDeployment(ctx, config); err != nil {
+		return err
+	}
+
+	for _, region := range c.Stack.Regions {
+		if _, ok := c.AsgNames[region.Region]; !ok {
+			continue
+		}
+
+		client, err := selectClientFromList(c.AWSClients, region.Region)
+		if err != nil {
+			return err
+		}
+
+		if !c.Weighted() {
+			if err := c.findCanaryLoadBalancerResources(region); err != nil {
+				return err
+			}
+
+			if err := c.DeleteCanaryLoadBalancer(region); err != nil {
+				return err
+			}
+		}
+
+		for _, tg := range canaryTargetGroups[region.Region] {
+			if err := client.ELBV2Service.DeleteTargetGroup(tg); err != nil {
+				return err
+			}
+			c.Logger.Debugf("Deleted canary target group: %s", *tg)
+		}
+
+		message := fmt.Sprintf("Canary is aborted : %s / %s", c.AsgNames[region.Region], region.Region)
+		c.Logger.Info(message)
+		c.Slack.SendSimpleMessage(fmt.Sprintf(":x: %s", message))
+	}
+
+	return nil
+}
+
+// findCanaryLoadBalancerResources finds canary load balancer and security groups which are created by canary deployment
+func (c *Canary) findCanaryLoadBalancerResources(region schemas.RegionConfig) error {
+	lb, err := c.FindCanaryLoadBalancer(region)
+	if err != nil {
+		return err
+	}
+
+	if lb != nil {
+		c.LoadBalancer[region.Region] = *lb.LoadBalancerArn
+	}
+
+	lbSg, err := c.GetCanaryLoadBalancerSecurityGroup(region)
+	if err != nil {
+		return err
+	}
+	c.LBSecurityGroup[region.Region] = lbSg
+
+	client, err := selectClientFromList(c.AWSClients, region.Region)
+	if err != nil {
+		return err
+	}
+
+	groupID, err := client.EC2Service.GetSecurityGroup(c.GenerateCanarySecurityGroupName(region.Region))
+	if err != nil {
+		c.Logger.Warn(err.Error())
+		return nil
+	}
+	c.SecurityGroup[region.Region] = groupID
+
+	return nil
+}
+
+// findCanaryGroups returns the latest autoscaling group with canary tag and the latest one without it
+func findCanaryGroups(groups []*autoscaling.Group) (*autoscaling.Group, *autoscaling.Group) {
+	var canary, baseline *autoscaling.Group
+	for _, group := range groups {
+		if isCanaryGroup(group) {
+			if canary == nil || group.CreatedTime.After(*canary.CreatedTime) {
+				canary = group
+			}
+			continue
+		}
+
+		if baseline == nil || group.CreatedTime.After(*baseline.CreatedTime) {
+			baseline = group
+		}
+	}
+
+	return canary, baseline
+}
+
+// isCanaryGroup returns true if the autoscaling group has canary deployment tag
+func isCanaryGroup(group *autoscaling.Group) bool {
+	for _, tag := range group.Tags {
+		if eaws.StringValue(tag.Key) == constants.DeploymentTagKey {
+			return strings.ToLower(eaws.StringValue(tag.Value)) == constants.CanaryDeployment
+		}
+	}
+
+	return false
+}
+
+// countInServiceInstances returns the number of healthy instances in service of the autoscaling group
+func countInServiceInstances(group *autoscaling.Group) int {
+	count := 0
+	for _, instance := range group.Instances {
+		if eaws.StringValue(instance.LifecycleState) == constants.InServiceStatus && eaws.StringValue(instance.HealthStatus) == "Healthy" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// trafficWeight returns the percentage of traffic which the first forward rule sends to the target group
+func trafficWeight(rules []aws.ForwardRule, targetGroupArn string) int64 {
+	for _, rule := range rules {
+		for _, action := range rule.Actions {
+			if !hasTargetGroup(action, targetGroupArn) {
+				continue
+			}
+
+			var total, weight int64
+			for _, tg := range aws.ForwardTargetGroups(action) {
+				w := int64(1)
+				if tg.Weight != nil {
+					w = *tg.Weight
+				}
+				total += w
+				if eaws.StringValue(tg.TargetGroupArn) == targetGroupArn {
+					weight = w
+				}
+			}
+
+			if total == 0 {
+				return 0
+			}
+
+			return int64(math.Round(float64(weight) * 100 / float64(total)))
+		}
+	}
+
+	return 0
+}
+
+// instanceShare returns the percentage of canary instances among the instances of canary and baseline
+func instanceShare(canary, baseline int) int64 {
+	if canary+baseline == 0 {
+		return 0
+	}
+
+	return int64(math.Round(float64(canary) * 100 / float64(canary+baseline)))
+}
+
+// groupCapacity returns the current capacity of the autoscaling group
+func groupCapacity(group *autoscaling.Group) schemas.Capacity {
+	return schemas.Capacity{
+		Min:     eaws.Int64Value(group.MinSize),
+		Max:     eaws.Int64Value(group.MaxSize),
+		Desired: eaws.Int64Value(group.DesiredCapacity),
+	}
+}
+
+// restoredCapacity returns the capacity of baseline after the canary is aborted
+// Baseline which is reduced for the canary goes back to the capacity of the manifest, and is never shrunk.
+func restoredCapacity(current, manifest schemas.Capacity) schemas.Capacity {
+	if current.Desired >= manifest.Desired {
+		return current
+	}
+
+	ret := current
+	ret.Desired = manifest.Desired
+	if ret.Min < manifest.Min {
+		ret.Min = manifest.Min
+	}
+
+	if ret.Max < ret.Desired {
+		ret.Max = ret.Desired
+	}
+
+	return ret
+}
+
+// PrintCanaryStatuses prints canaries in progress in readable format
+func PrintCanaryStatuses(out io.Writer, statuses []CanaryStatus) error {
+	var data = struct {
+		Statuses []CanaryStatus
+	}{
+		Statuses: statuses,
+	}
+
+	funcMap := template.FuncMap{
+		"decorate":    tool.DecorateAttr,
+		"parseTGName": tool.ParseTargetGroupName,
+	}
+
+	w := tabwriter.NewWriter(out, 0, 5, 3, ' ', tabwriter.TabIndent)
+	t := template.Must(template.New("Canary Status").Funcs(funcMap).Parse(templates.CanaryStatusTemplate))
+
+	if err := t.Execute(w, data); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
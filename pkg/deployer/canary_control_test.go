@@ -0,0 +1,179 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestFindCanaryGroups(t *testing.T) {
+	now := time.Now()
+	group := func(name string, created time.Time, canary bool) *autoscaling.Group {
+		g := &autoscaling.Group{AutoScalingGroupName: eaws.String(name), CreatedTime: eaws.Time(created)}
+		if canary {
+			g.Tags = []*autoscaling.TagDescription{{Key: eaws.String(constants.DeploymentTagKey), Value: eaws.String("Canary")}}
+		}
+		return g
+	}
+
+	testData := []struct {
+		groups           []*autoscaling.Group
+		expectedCanary   string
+		expectedBaseline string
+	}{
+		{
+			groups: []*autoscaling.Group{
+				group("hello-dev_apnortheast2-v001", now.Add(-2*time.Hour), false),
+				group("hello-dev_apnortheast2-v003", now, true),
+				group("hello-dev_apnortheast2-v002", now.Add(-time.Hour), false),
+			},
+			expectedCanary:   "hello-dev_apnortheast2-v003",
+			expectedBaseline: "hello-dev_apnortheast2-v002",
+		},
+		{
+			groups: []*autoscaling.Group{
+				group("hello-dev_apnortheast2-v002", now, false),
+			},
+			expectedBaseline: "hello-dev_apnortheast2-v002",
+		},
+		{
+			groups: []*autoscaling.Group{
+				group("hello-dev_apnortheast2-v001", now, true),
+			},
+			expectedCanary: "hello-dev_apnortheast2-v001",
+		},
+	}
+
+	for _, td := range testData {
+		canary, baseline := findCanaryGroups(td.groups)
+
+		var canaryName, baselineName string
+		if canary != nil {
+			canaryName = *canary.AutoScalingGroupName
+		}
+		if baseline != nil {
+			baselineName = *baseline.AutoScalingGroupName
+		}
+
+		if canaryName != td.expectedCanary || baselineName != td.expectedBaseline {
+			t.Errorf("expected %s / %s, got %s / %s", td.expectedCanary, td.expectedBaseline, canaryName, baselineName)
+		}
+	}
+}
+
+func TestTrafficWeight(t *testing.T) {
+	forward := func(weights map[string]int64) *elbv2.Action {
+		var tgs []*elbv2.TargetGroupTuple
+		for _, arn := range []string{testBaselineArn, testCanaryArn} {
+			if w, ok := weights[arn]; ok {
+				tgs = append(tgs, &elbv2.TargetGroupTuple{TargetGroupArn: eaws.String(arn), Weight: eaws.Int64(w)})
+			}
+		}
+		return &elbv2.Action{Type: eaws.String(elbv2.ActionTypeEnumForward), ForwardConfig: &elbv2.ForwardActionConfig{TargetGroups: tgs}}
+	}
+
+	testData := []struct {
+		rules    []aws.ForwardRule
+		expected int64
+	}{
+		{
+			rules:    []aws.ForwardRule{{Actions: []*elbv2.Action{forward(map[string]int64{testBaselineArn: 95, testCanaryArn: 5})}}},
+			expected: 5,
+		},
+		{
+			rules:    []aws.ForwardRule{{Actions: []*elbv2.Action{forward(map[string]int64{testBaselineArn: 1, testCanaryArn: 1})}}},
+			expected: 50,
+		},
+		{
+			rules:    []aws.ForwardRule{{Actions: []*elbv2.Action{forward(map[string]int64{testBaselineArn: 100, testCanaryArn: 0})}}},
+			expected: 0,
+		},
+		{
+			rules:    []aws.ForwardRule{{Actions: []*elbv2.Action{{Type: eaws.String(elbv2.ActionTypeEnumForward), TargetGroupArn: eaws.String(testCanaryArn)}}}},
+			expected: 100,
+		},
+		{
+			rules:    []aws.ForwardRule{{Actions: []*elbv2.Action{forward(map[string]int64{testBaselineArn: 100})}}},
+			expected: 0,
+		},
+	}
+
+	for _, td := range testData {
+		if weight := trafficWeight(td.rules, testCanaryArn); weight != td.expected {
+			t.Errorf("expected %d, got %d", td.expected, weight)
+		}
+	}
+}
+
+func TestInstanceShare(t *testing.T) {
+	testData := []struct {
+		canary   int
+		baseline int
+		expected int64
+	}{
+		{canary: 1, baseline: 9, expected: 10},
+		{canary: 1, baseline: 2, expected: 33},
+		{canary: 2, baseline: 0, expected: 100},
+		{canary: 0, baseline: 0, expected: 0},
+	}
+
+	for _, td := range testData {
+		if share := instanceShare(td.canary, td.baseline); share != td.expected {
+			t.Errorf("expected %d, got %d", td.expected, share)
+		}
+	}
+}
+
+func TestRestoredCapacity(t *testing.T) {
+	testData := []struct {
+		current  schemas.Capacity
+		manifest schemas.Capacity
+		expected schemas.Capacity
+	}{
+		{
+			current:  schemas.Capacity{Min: 1, Desired: 3, Max: 4},
+			manifest: schemas.Capacity{Min: 2, Desired: 4, Max: 4},
+			expected: schemas.Capacity{Min: 2, Desired: 4, Max: 4},
+		},
+		{
+			current:  schemas.Capacity{Min: 2, Desired: 6, Max: 10},
+			manifest: schemas.Capacity{Min: 2, Desired: 4, Max: 4},
+			expected: schemas.Capacity{Min: 2, Desired: 6, Max: 10},
+		},
+		{
+			current:  schemas.Capacity{Min: 0, Desired: 1, Max: 1},
+			manifest: schemas.Capacity{Min: 0, Desired: 2, Max: 2},
+			expected: schemas.Capacity{Min: 0, Desired: 2, Max: 2},
+		},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(restoredCapacity(td.current, td.manifest), td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// RunCanarySteps does nothing because only canary deployment has canary steps
+func (d *Deployer) RunCanarySteps(ctx context.Context, config schemas.Config) error {
+	d.StepStatus[constants.StepCanary] = true
+	return nil
+}
+
+// RunCanarySteps walks through canary steps and promotes the canary when every step passes
+// At each step, the canary autoscaling group is scaled to the weight of the previous capacity and
+// receives the weight of traffic, then it is watched for the pause. If any check fails, the error
+// makes the pipeline roll back the canary so that the baseline takes the whole traffic again.
+func (c *Canary) RunCanarySteps(ctx context.Context, config schemas.Config) error {
+	if !c.hasCanarySteps() || config.CompleteCanary || !c.StepStatus[constants.StepAdditionalWork] {
+		c.StepStatus[constants.StepCanary] = true
+		return nil
+	}
+
+	steps := c.Stack.Canary.Steps
+	for i, step := range steps {
+		for _, region := range c.Stack.Regions {
+			if config.Region != "" && config.Region != region.Region {
+				continue
+			}
+
+			c.Logger.Infof("Canary step %d/%d of %s : %d%% of traffic, pause %s", i+1, len(steps), c.AsgNames[region.Region], step.Weight, tool.RoundTime(step.Pause))
+			c.Slack.SendSimpleMessage(fmt.Sprintf(":hatching_chick: Canary step %d/%d of %s : %d%% of traffic, pause %s", i+1, len(steps), c.AsgNames[region.Region], step.Weight, tool.RoundTime(step.Pause)))
+
+			if err := c.runCanaryStep(ctx, config, region, step, i == len(steps)-1); err != nil {
+				err = fmt.Errorf("canary step %d/%d of %s failed: %s", i+1, len(steps), c.AsgNames[region.Region], err.Error())
+				c.Slack.SendSimpleMessage(fmt.Sprintf(":x: %s", err.Error()))
+				return err
+			}
+		}
+	}
+
+	if err := c.PromoteCanary(ctx, config); err != nil {
+		return err
+	}
+
+	c.StepStatus[constants.StepCanary] = true
+	return nil
+}
+
+// runCanaryStep scales the canary, shifts traffic to it and watches it for the pause of step
+// If canary analysis is configured, it decides whether the canary goes further at the end of the pause.
+// Marginal canary is not promoted at the last step.
+func (c *Canary) runCanaryStep(ctx context.Context, config schemas.Config, region schemas.RegionConfig, step schemas.CanaryStep, last bool) error {
+	asg := c.AsgNames[region.Region]
+
+	baseline := c.PrevInstanceCount[region.Region]
+	if baseline.Desired == 0 {
+		baseline = c.Stack.Capacity
+	}
+
+	capacity := canaryCapacity(baseline, step.Weight)
+	if c.AppliedCapacity == nil || c.AppliedCapacity.Desired != capacity.Desired {
+		if err := c.ResizingAutoScalingGroup(asg, region.Region, capacity); err != nil {
+			return err
+		}
+		c.AppliedCapacity = &capacity
+
+		stepConfig := config
+		stepConfig.StartTimestamp = time.Now().Unix()
+		if err := c.HealthChecking(ctx, stepConfig); err != nil {
+			return err
+		}
+	}
+
+	if c.Weighted() {
+		if err := c.ShiftCanaryTraffic(ctx, region.Region, step.Weight); err != nil {
+			return err
+		}
+	}
+
+	client, err := selectClientFromList(c.AWSClients, region.Region)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for {
+		breaches, err := c.checkCanaryStep(ctx, client, region, start)
+		if err != nil {
+			return err
+		}
+
+		if len(breaches) > 0 {
+			return fmt.Errorf("%s", strings.Join(breaches, ", "))
+		}
+
+		elapsed := time.Since(start)
+		if elapsed >= step.Pause {
+			break
+		}
+
+		interval := config.PollingInterval
+		if remain := step.Pause - elapsed; remain < interval {
+			interval = remain
+		}
+
+		c.Logger.Infof("Watching canary %s : %s left", asg, tool.RoundTime(step.Pause-elapsed))
+		if err := tool.SleepWithContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+
+	if c.Stack.Canary.Analysis != nil {
+		result, err := c.AnalyzeCanary(ctx, client, region.Region, start)
+		if err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("Canary analysis of %s : %s", asg, result.Summary())
+		c.Logger.Info(message)
+		c.Slack.SendSimpleMessage(message)
+
+		if result.Classification == constants.CanaryFail || (result.Classification == constants.CanaryMarginal && last) {
+			return fmt.Errorf("canary analysis is %s with score %.1f", result.Classification, result.Score)
+		}
+	}
+
+	healthy, err := c.Deployer.HealthChecking(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	if !healthy {
+		return fmt.Errorf("canary is not healthy after pause of %s", tool.RoundTime(step.Pause))
+	}
+
+	return nil
+}
+
+// checkCanaryStep returns breached checks of canary since the step started
+func (c *Canary) checkCanaryStep(ctx context.Context, client aws.Client, region schemas.RegionConfig, start time.Time) ([]string, error) {
+	alarms := c.Stack.Canary.Alarms
+	if len(alarms) == 0 {
+		return nil, nil
+	}
+
+	states, err := client.CloudWatchService.GetAlarmStates(ctx, alarms)
+	if err != nil {
+		return nil, err
+	}
+
+	var breaches []string
+	for _, alarm := range alarms {
+		state, ok := states[alarm]
+		if !ok {
+			return nil, fmt.Errorf("alarm does not exist: %s(%s)", alarm, region.Region)
+		}
+
+		if state == cloudwatch.StateValueAlarm {
+			breaches = append(breaches, fmt.Sprintf("alarm %s is in ALARM state", alarm))
+		}
+	}
+
+	return breaches, nil
+}
+
+// PromoteCanary completes canary deployment in the same run after canary steps are passed
+// The canary autoscaling group is scaled to the full capacity and takes the whole traffic, and
+// previous autoscaling groups are cleaned in the following steps.
+func (c *Canary) PromoteCanary(ctx context.Context, config schemas.Config) error {
+	completeConfig := config
+	completeConfig.CompleteCanary = true
+
+	for i, region := range c.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			continue
+		}
+
+		client, err := selectClientFromList(c.AWSClients, region.Region)
+		if err != nil {
+			return err
+		}
+
+		asg := c.AsgNames[region.Region]
+		c.Logger.Infof("Promoting canary : %s", asg)
+		c.Slack.SendSimpleMessage(fmt.Sprintf(":rocket: Promoting canary : %s", asg))
+
+		if err := c.CompleteCanaryDeployment(completeConfig, region, asg); err != nil {
+			return err
+		}
+
+		// canary of separate load balancer is already attached to the original target groups
+		if !c.Weighted() {
+			c.Stack.Regions[i] = c.restoreTargetGroupInfo(region)
+		}
+
+		// every autoscaling group except the canary is cleaned after promotion
+		prefix := tool.BuildPrefixName(c.AwsConfig.Name, c.Stack.Env, region.Region)
+		asgList, err := client.EC2Service.GetAllMatchingAutoscalingGroupsWithPrefix(prefix)
+		if err != nil {
+			return err
+		}
+
+		var prevAsgs []string
+		for _, group := range asgList {
+			if name := eaws.StringValue(group.AutoScalingGroupName); name != asg {
+				prevAsgs = append(prevAsgs, name)
+			}
+		}
+		c.PrevAsgs[region.Region] = prevAsgs
+		c.LatestAsg[region.Region] = asg
+	}
+
+	stepConfig := config
+	stepConfig.StartTimestamp = time.Now().Unix()
+	if err := c.HealthChecking(ctx, stepConfig); err != nil {
+		return err
+	}
+
+	if c.Weighted() {
+		if err := c.shiftTrafficOfRegions(ctx, config, 100); err != nil {
+			return err
+		}
+	}
+
+	c.Logger.Infof("Canary is promoted : %s", c.GetPipelineName())
+	c.Slack.SendSimpleMessage(fmt.Sprintf(":white_check_mark: Canary is promoted : %s", c.GetPipelineName()))
+
+	return nil
+}
+
+// hasCanarySteps returns true if canary steps are configured
+func (c *Canary) hasCanarySteps() bool {
+	return c.Stack.Canary != nil && len(c.Stack.Canary.Steps) > 0
+}
+
+// promoted returns true if the canary is promoted by canary steps in this deployment
+func (c *Canary) promoted() bool {
+	return c.hasCanarySteps() && c.StepStatus[constants.StepCanary]
+}
+
+// restoreTargetGroupInfo changes target group information of region back to the original target groups
+func (c *Canary) restoreTargetGroupInfo(region schemas.RegionConfig) schemas.RegionConfig {
+	if tg, ok := c.PrevHealthCheckTargetGroups[region.Region]; ok {
+		region.HealthcheckTargetGroup = tg
+	}
+
+	if tgs, ok := c.PrevTargetGroups[region.Region]; ok {
+		region.TargetGroups = tgs
+	}
+
+	return region
+}
+
+// canaryCapacity returns capacity of canary for the weight of baseline capacity
+// Canary has at least one instance.
+func canaryCapacity(baseline schemas.Capacity, weight int64) schemas.Capacity {
+	desired := (baseline.Desired*weight + 99) / 100
+	if desired < 1 {
+		desired = 1
+	}
+
+	return schemas.Capacity{
+		Min:     desired,
+		Max:     desired,
+		Desired: desired,
+	}
+}
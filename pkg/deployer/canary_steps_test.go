@@ -0,0 +1,44 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestCanaryCapacity(t *testing.T) {
+	testData := []struct {
+		baseline schemas.Capacity
+		weight   int64
+		expected schemas.Capacity
+	}{
+		{baseline: schemas.Capacity{Min: 10, Max: 20, Desired: 10}, weight: 5, expected: schemas.Capacity{Min: 1, Max: 1, Desired: 1}},
+		{baseline: schemas.Capacity{Min: 10, Max: 20, Desired: 10}, weight: 25, expected: schemas.Capacity{Min: 3, Max: 3, Desired: 3}},
+		{baseline: schemas.Capacity{Min: 10, Max: 20, Desired: 10}, weight: 100, expected: schemas.Capacity{Min: 10, Max: 10, Desired: 10}},
+		{baseline: schemas.Capacity{}, weight: 50, expected: schemas.Capacity{Min: 1, Max: 1, Desired: 1}},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(canaryCapacity(td.baseline, td.weight), td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
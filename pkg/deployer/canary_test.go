@@ -22,8 +22,11 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/go-test/deep"
 
 	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
 )
 
 func TestCheckCanaryVersion(t *testing.T) {
@@ -62,3 +65,122 @@ func TestCheckCanaryVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestCanaryListener(t *testing.T) {
+	certificate := "arn:aws:acm:ap-northeast-2:123456789012:certificate/12345678-1234-1234-1234-123456789012"
+	testData := []struct {
+		canary           *schemas.CanaryConfig
+		expectedPort     int64
+		expectedProtocol string
+		expectedCert     string
+	}{
+		{
+			canary:           nil,
+			expectedPort:     80,
+			expectedProtocol: constants.HTTPProtocol,
+		},
+		{
+			canary:           &schemas.CanaryConfig{ListenerPort: 8080},
+			expectedPort:     8080,
+			expectedProtocol: constants.HTTPProtocol,
+		},
+		{
+			canary:           &schemas.CanaryConfig{ListenerProtocol: "https", CertificateArn: certificate},
+			expectedPort:     443,
+			expectedProtocol: constants.HTTPSProtocol,
+			expectedCert:     certificate,
+		},
+		{
+			canary:           &schemas.CanaryConfig{ListenerProtocol: constants.HTTPSProtocol, ListenerPort: 8443, CertificateArn: certificate},
+			expectedPort:     8443,
+			expectedProtocol: constants.HTTPSProtocol,
+			expectedCert:     certificate,
+		},
+	}
+
+	for _, td := range testData {
+		listener := canaryListener(td.canary)
+		if listener.Port != td.expectedPort || listener.Protocol != td.expectedProtocol || listener.CertificateArn != td.expectedCert {
+			t.Errorf("expected %d/%s/%s, got %d/%s/%s", td.expectedPort, td.expectedProtocol, td.expectedCert, listener.Port, listener.Protocol, listener.CertificateArn)
+		}
+	}
+}
+
+func TestCanaryLoadBalancerDefaults(t *testing.T) {
+	if cidrs := canaryIngressCidrs(nil); len(cidrs) != 1 || cidrs[0] != constants.DefaultCanaryIngressCidr {
+		t.Errorf("expected default ingress CIDR, got %v", cidrs)
+	}
+
+	if scheme := canaryScheme(&schemas.CanaryConfig{}); scheme != constants.InternetFacingScheme {
+		t.Errorf("expected %s, got %s", constants.InternetFacingScheme, scheme)
+	}
+
+	cidrs := canaryIngressCidrs(&schemas.CanaryConfig{IngressCidrs: []string{"10.0.0.0/8"}})
+	if len(cidrs) != 1 || cidrs[0] != "10.0.0.0/8" {
+		t.Errorf("expected configured ingress CIDRs, got %v", cidrs)
+	}
+}
+
+func TestStaleCanaryIngress(t *testing.T) {
+	permission := func(protocol string, port int64, cidrs ...string) *ec2.IpPermission {
+		p := &ec2.IpPermission{IpProtocol: aws.String(protocol), FromPort: aws.Int64(port), ToPort: aws.Int64(port)}
+		for _, cidr := range cidrs {
+			p.IpRanges = append(p.IpRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+		}
+		return p
+	}
+
+	testData := []struct {
+		permissions []*ec2.IpPermission
+		port        int64
+		cidrs       []string
+		stale       []*ec2.IpPermission
+		allowed     []string
+	}{
+		{
+			permissions: []*ec2.IpPermission{permission("tcp", 80, "0.0.0.0/0")},
+			port:        80,
+			cidrs:       []string{"0.0.0.0/0"},
+			stale:       nil,
+			allowed:     []string{"0.0.0.0/0"},
+		},
+		{
+			permissions: []*ec2.IpPermission{permission("tcp", 80, "0.0.0.0/0", "10.0.0.0/8")},
+			port:        80,
+			cidrs:       []string{"10.0.0.0/8"},
+			stale:       []*ec2.IpPermission{permission("tcp", 80, "0.0.0.0/0")},
+			allowed:     []string{"10.0.0.0/8"},
+		},
+		{
+			permissions: []*ec2.IpPermission{permission("tcp", 80, "0.0.0.0/0"), permission("tcp", 443, "10.0.0.0/8")},
+			port:        443,
+			cidrs:       []string{"10.0.0.0/8", "172.16.0.0/12"},
+			stale:       []*ec2.IpPermission{permission("tcp", 80, "0.0.0.0/0")},
+			allowed:     []string{"10.0.0.0/8"},
+		},
+		{
+			permissions: []*ec2.IpPermission{permission("-1", 0, "0.0.0.0/0")},
+			port:        443,
+			cidrs:       []string{"0.0.0.0/0"},
+			stale:       []*ec2.IpPermission{permission("-1", 0, "0.0.0.0/0")},
+			allowed:     nil,
+		},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(staleCanaryIngress(td.permissions, td.port, td.cidrs), td.stale); diff != nil {
+			t.Error(diff)
+		}
+
+		var allowed []string
+		for _, cidr := range td.cidrs {
+			if allowsCanaryIngress(td.permissions, td.port, cidr) {
+				allowed = append(allowed, cidr)
+			}
+		}
+
+		if diff := deep.Equal(allowed, td.allowed); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
@@ -17,18 +17,22 @@ limitations under the license.
 package deployer
 
 import (
+	"context"
+
 	"github.com/DevopsArtFactory/goployer/pkg/schemas"
 )
 
 type DeployManager interface {
 	GetDeployer() *Deployer
-	CheckPreviousResources(config schemas.Config) error
-	Deploy(config schemas.Config) error
-	HealthChecking(config schemas.Config) error
-	FinishAdditionalWork(config schemas.Config) error
-	CleanPreviousVersion(config schemas.Config) error
-	TriggerLifecycleCallbacks(config schemas.Config) error
-	CleanChecking(config schemas.Config) error
-	GatherMetrics(config schemas.Config) error
-	RunAPITest(config schemas.Config) error
+	CheckPreviousResources(ctx context.Context, config schemas.Config) error
+	Deploy(ctx context.Context, config schemas.Config) error
+	HealthChecking(ctx context.Context, config schemas.Config) error
+	FinishAdditionalWork(ctx context.Context, config schemas.Config) error
+	RunCanarySteps(ctx context.Context, config schemas.Config) error
+	CleanPreviousVersion(ctx context.Context, config schemas.Config) error
+	TriggerLifecycleCallbacks(ctx context.Context, config schemas.Config) error
+	CleanChecking(ctx context.Context, config schemas.Config) error
+	GatherMetrics(ctx context.Context, config schemas.Config) error
+	RunAPITest(ctx context.Context, config schemas.Config) error
+	Rollback(ctx context.Context, config schemas.Config) error
 }
@@ -18,6 +18,7 @@ package deployer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
@@ -43,6 +44,7 @@ import (
 	"github.com/DevopsArtFactory/goployer/pkg/helper"
 	"github.com/DevopsArtFactory/goployer/pkg/schemas"
 	"github.com/DevopsArtFactory/goployer/pkg/slack"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
 	"github.com/DevopsArtFactory/goployer/pkg/templates"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 )
@@ -68,6 +70,17 @@ type Deployer struct {
 	Collector         collector.Collector
 	StepStatus        map[int64]bool
 	DeploymentFlag    map[string]string
+	HealthySuccesses  map[string]int
+	HealthCommands    map[string]string
+	UnhealthyHosts    map[string][]aws.HealthcheckHost
+	SpotInterruptions map[string][]SpotInterruption
+	OnDemandFallback  map[string]bool
+
+	// CheckedTerminations is a set of terminated instances whose state reasons are already checked
+	CheckedTerminations map[string]bool
+
+	// HealthCheckExtension is the time added to the health check timeout by spot interruptions
+	HealthCheckExtension time.Duration
 }
 
 type APIAttacker struct {
@@ -94,6 +107,9 @@ func InitDeploymentConfiguration(h *helper.DeployerHelper, awsClients []aws.Clie
 		SecurityGroup:     map[string]*string{},
 		DeploymentFlag:    map[string]string{},
 		LatestAsg:         map[string]string{},
+		HealthySuccesses:  map[string]int{},
+		HealthCommands:    map[string]string{},
+		UnhealthyHosts:    map[string][]aws.HealthcheckHost{},
 		Stack:             h.Stack,
 		Slack:             h.Slack,
 		Collector:         h.Collector,
@@ -103,15 +119,17 @@ func InitDeploymentConfiguration(h *helper.DeployerHelper, awsClients []aws.Clie
 }
 
 // Polling retrieves health information from instances or target groups.
-func (d *Deployer) Polling(region schemas.RegionConfig, asg *autoscaling.Group, client aws.Client, forceManifestCapacity, isUpdate, downsizingUpdate bool) (bool, error) {
+func (d *Deployer) Polling(ctx context.Context, region schemas.RegionConfig, asg *autoscaling.Group, client aws.Client, forceManifestCapacity, isUpdate, downsizingUpdate bool) (bool, error) {
 	if asg.AutoScalingGroupName == nil {
 		return false, fmt.Errorf("no autoscaling found for %s", d.AsgNames[region.Region])
 	}
 
 	threshold := d.AppliedCapacity.Desired
 
-	if region.HealthcheckTargetGroup == "" && region.HealthcheckLB == "" {
-		d.Logger.Info("health check skipped because of neither target group nor classic load balancer specified")
+	targetGroups := healthcheckTargetGroups(region)
+	loadBalancers := healthcheckLoadBalancers(region)
+	if len(targetGroups) == 0 && len(loadBalancers) == 0 && region.HealthCheck == nil {
+		d.Logger.Info("health check skipped because of neither target group, classic load balancer nor health check specified")
 		return true, nil
 	}
 
@@ -119,39 +137,25 @@ func (d *Deployer) Polling(region schemas.RegionConfig, asg *autoscaling.Group,
 	var err error
 	validHostCount := int64(0)
 
-	if len(region.HealthcheckLB) == 0 && len(region.HealthcheckTargetGroup) == 0 {
-		Logger.Infof("No health check target for this autoscaling group: %s / %s", *asg.AutoScalingGroupName, region.Region)
-		return true, nil
-	}
-
 	d.Logger.Debugf("[Checking healthy host count] Autoscaling Group: %s", *asg.AutoScalingGroupName)
-	if len(region.HealthcheckTargetGroup) > 0 {
-		var healthCheckTargetGroupArn *string
-		if tool.IsTargetGroupArn(region.HealthcheckTargetGroup, region.Region) {
-			healthCheckTargetGroupArn = &region.HealthcheckTargetGroup
-		} else {
-			tgs := []string{region.HealthcheckTargetGroup}
-			tgARNs, err := client.ELBV2Service.GetTargetGroupARNs(tgs)
-			if err != nil {
-				return false, err
-			}
-			healthCheckTargetGroupArn = tgARNs[0]
-		}
-		d.Logger.Debugf("[Checking healthy host count] Target Group : %s", *healthCheckTargetGroupArn)
-
-		targetHosts, err = client.ELBV2Service.GetHostInTarget(asg, healthCheckTargetGroupArn, isUpdate, downsizingUpdate)
+	if len(targetGroups) > 0 || len(loadBalancers) > 0 {
+		targetHosts, err = d.GetHostsInHealthcheckTargets(ctx, client, region, asg, isUpdate, downsizingUpdate)
 		if err != nil {
 			return false, err
 		}
-	} else if len(region.HealthcheckLB) > 0 {
-		d.Logger.Debugf("[Checking healthy host count] Load Balancer : %s", region.HealthcheckLB)
-		targetHosts, err = client.ELBService.GetHealthyHostInELB(asg, region.HealthcheckLB)
+	} else if region.HealthCheck != nil {
+		d.Logger.Debugf("[Checking healthy host count] Health Check : %s port %d", region.HealthCheck.Type, region.HealthCheck.Port)
+		targetHosts, err = d.GetHostsByHealthCheck(ctx, client, asg, *region.HealthCheck)
 		if err != nil {
 			return false, err
 		}
 	}
 
 	validHostCount = d.GetValidHostCount(targetHosts)
+	if d.UnhealthyHosts == nil {
+		d.UnhealthyHosts = map[string][]aws.HealthcheckHost{}
+	}
+	d.UnhealthyHosts[region.Region] = unhealthyHosts(targetHosts)
 
 	if isUpdate {
 		if validHostCount == threshold {
@@ -160,21 +164,30 @@ func (d *Deployer) Polling(region schemas.RegionConfig, asg *autoscaling.Group,
 		}
 		d.Logger.Infof("Desired count does not meet the requirement: %d/%d", validHostCount, threshold)
 	} else {
-		if validHostCount >= threshold {
+		required, lackingZones := checkHealthyThreshold(targetHosts, asg, threshold, d.Stack.HealthyThresholdPercent, d.Stack.MinHealthyPerAZ)
+		if validHostCount >= required && len(lackingZones) == 0 {
 			d.Logger.Infof("Healthy Count for %s : %d/%d", d.AsgNames[region.Region], validHostCount, threshold)
-			d.Slack.SendSimpleMessage(fmt.Sprintf("All instances are healthy in %s  :  %d/%d", d.AsgNames[region.Region], validHostCount, threshold))
+			if validHostCount >= threshold {
+				d.Slack.SendSimpleMessage(fmt.Sprintf("All instances are healthy in %s  :  %d/%d", d.AsgNames[region.Region], validHostCount, threshold))
+			} else {
+				d.Slack.SendSimpleMessage(fmt.Sprintf("Healthy instances meet the threshold in %s  :  %d/%d", d.AsgNames[region.Region], validHostCount, threshold))
+				d.HandleUnhealthyHosts(ctx, client, d.AsgNames[region.Region], targetHosts)
+			}
 			return true, nil
 		}
 
-		d.Logger.Infof("Healthy count does not meet the requirement(%s) : %d/%d", d.AsgNames[region.Region], validHostCount, threshold)
-		d.Slack.SendSimpleMessage(fmt.Sprintf("Waiting for healthy instances %s  :  %d/%d", d.AsgNames[region.Region], validHostCount, threshold))
+		if len(lackingZones) > 0 {
+			d.Logger.Infof("Availability zones without %d healthy instances(%s) : %s", d.Stack.MinHealthyPerAZ, d.AsgNames[region.Region], strings.Join(lackingZones, ", "))
+		}
+		d.Logger.Infof("Healthy count does not meet the requirement(%s) : %d/%d", d.AsgNames[region.Region], validHostCount, required)
+		d.Slack.SendSimpleMessage(fmt.Sprintf("Waiting for healthy instances %s  :  %d/%d", d.AsgNames[region.Region], validHostCount, required))
 	}
 	return false, nil
 }
 
 // CheckTerminating verifies whether all instances have been successfully terminated.
-func (d *Deployer) CheckTerminating(client aws.Client, target string, disableMetrics bool) bool {
-	done, err := d.CheckAutoscalingInstanceCount(client, target, 0)
+func (d *Deployer) CheckTerminating(ctx context.Context, client aws.Client, target string, disableMetrics bool) bool {
+	done, err := d.CheckAutoscalingInstanceCount(ctx, client, target, 0)
 	if err != nil {
 		d.Logger.Errorf(err.Error())
 		return true
@@ -190,8 +203,8 @@ func (d *Deployer) CheckTerminating(client aws.Client, target string, disableMet
 }
 
 // CheckAutoscalingInstanceCount checks instance count in the autoscaling group with desired value
-func (d *Deployer) CheckAutoscalingInstanceCount(client aws.Client, asg string, desired int) (bool, error) {
-	asgInfo, err := client.EC2Service.GetMatchingAutoscalingGroup(asg)
+func (d *Deployer) CheckAutoscalingInstanceCount(ctx context.Context, client aws.Client, asg string, desired int) (bool, error) {
+	asgInfo, err := client.EC2Service.GetMatchingAutoscalingGroup(ctx, asg)
 	if err != nil {
 		return false, err
 	}
@@ -445,6 +458,11 @@ func (d *Deployer) GetStackName() string {
 	return d.Stack.Stack
 }
 
+// GetPipelineName returns name of stack with regions which the deployer runs in
+func (d *Deployer) GetPipelineName() string {
+	return state.StackKey(d.Stack)
+}
+
 // SkipDeployStep skips the deployment process.
 func (d *Deployer) SkipDeployStep() {
 	d.StepStatus[constants.StepDeploy] = true
@@ -638,12 +656,7 @@ func (d *Deployer) Deploy(config schemas.Config, region schemas.RegionConfig) er
 		return err
 	}
 
-	healthElb := region.HealthcheckLB
-	loadBalancers := region.LoadBalancers
-	if healthElb != "" && !tool.IsStringInArray(healthElb, loadBalancers) {
-		loadBalancers = append(loadBalancers, healthElb)
-	}
-
+	loadBalancers := d.GetLoadBalancerNames(region)
 	targetGroups := d.GetTargetGroupNames(region)
 
 	healthCheckType := constants.DefaultHealthcheckType
@@ -889,16 +902,28 @@ func (d *Deployer) GenerateTags(asgName, stack string, extraTags, ansibleExtraVa
 
 // GetTargetGroupNames retrieves slice of target group name string
 func (d *Deployer) GetTargetGroupNames(region schemas.RegionConfig) []string {
-	healthCheckTargetGroup := region.HealthcheckTargetGroup
-
 	targetGroups := region.TargetGroups
-	if healthCheckTargetGroup != "" && !tool.IsStringInArray(healthCheckTargetGroup, targetGroups) {
-		targetGroups = append(targetGroups, healthCheckTargetGroup)
+	for _, tg := range healthcheckTargetGroups(region) {
+		if !tool.IsStringInArray(tg, targetGroups) {
+			targetGroups = append(targetGroups, tg)
+		}
 	}
 
 	return targetGroups
 }
 
+// GetLoadBalancerNames retrieves slice of classic load balancer names including ones for healthcheck
+func (d *Deployer) GetLoadBalancerNames(region schemas.RegionConfig) []string {
+	loadBalancers := region.LoadBalancers
+	for _, lb := range healthcheckLoadBalancers(region) {
+		if !tool.IsStringInArray(lb, loadBalancers) {
+			loadBalancers = append(loadBalancers, lb)
+		}
+	}
+
+	return loadBalancers
+}
+
 // DescribeTargetGroups retrieves target group details
 func (d *Deployer) DescribeTargetGroup(targetGroup string, region string) (*elbv2.TargetGroup, error) {
 	client, err := selectClientFromList(d.AWSClients, region)
@@ -920,7 +945,7 @@ func (d *Deployer) DescribeTargetGroup(targetGroup string, region string) (*elbv
 }
 
 // HealthChecking does health check
-func (d *Deployer) HealthChecking(config schemas.Config) (bool, error) {
+func (d *Deployer) HealthChecking(ctx context.Context, config schemas.Config) (bool, error) {
 	var finished []string
 
 	isUpdate := len(config.TargetAutoscalingGroup) > 0
@@ -965,13 +990,23 @@ func (d *Deployer) HealthChecking(config schemas.Config) (bool, error) {
 		}
 		d.Logger.Debugf("Target autoscaling group for health check: %s / %s", region.Region, targetAsgName)
 
-		asg, err := client.EC2Service.GetMatchingAutoscalingGroup(targetAsgName)
+		asg, err := client.EC2Service.GetMatchingAutoscalingGroup(ctx, targetAsgName)
 		if err != nil {
 			return false, err
 		}
 		d.Logger.Debugf("Health check target autoscaling group: %s / %s", region.Region, *asg.AutoScalingGroupName)
 
-		isHealthy, err := d.Polling(region, asg, client, config.ForceManifestCapacity, isUpdate, config.DownSizingUpdate)
+		if err := d.CheckLaunchFailures(ctx, client, *asg.AutoScalingGroupName, config); err != nil {
+			d.Logger.Errorf(err.Error())
+			d.Slack.SendSimpleMessage(fmt.Sprintf(":x: %s", err.Error()))
+			return false, err
+		}
+
+		if err := d.CheckSpotInterruptions(ctx, client, region.Region, *asg.AutoScalingGroupName, config); err != nil {
+			d.Logger.Warnf("failed to check spot interruptions: %s", err.Error())
+		}
+
+		isHealthy, err := d.Polling(ctx, region, asg, client, config.ForceManifestCapacity, isUpdate, config.DownSizingUpdate)
 		if err != nil {
 			return false, err
 		}
@@ -1096,7 +1131,8 @@ func (d *Deployer) TriggerLifecycleCallbacks(config schemas.Config) error {
 }
 
 // CleanPreviousAutoScalingGroup cleans previous version of autoscaling group
-func (d *Deployer) CleanPreviousAutoScalingGroup(config schemas.Config) error {
+// Instances are drained from target groups and classic load balancers before autoscaling group is resized.
+func (d *Deployer) CleanPreviousAutoScalingGroup(ctx context.Context, config schemas.Config) error {
 	for _, region := range d.Stack.Regions {
 		if config.Region != constants.EmptyString && config.Region != region.Region {
 			d.Logger.Debugf("This region is skipped by user: %s", region.Region)
@@ -1127,20 +1163,25 @@ func (d *Deployer) CleanPreviousAutoScalingGroup(config schemas.Config) error {
 					for current > 0 {
 						next = getNextTargetInstanceCount(current, reduceCnt)
 						d.Logger.Debugf("resizing target autoscaling group : %s, total: %d, current: %d, desired: %d", asg, total, current, next)
-						if err := d.ResizingAutoScalingGroupCount(client, asg, next); err != nil {
+						if err := d.drainAndResize(ctx, client, asg, next, config.PollingInterval); err != nil {
+							if ctx.Err() != nil {
+								return err
+							}
 							d.Logger.Errorf(err.Error())
 						}
 
 						done := false
 						for !done {
-							done, err = d.CheckAutoscalingInstanceCount(client, asg, int(next))
+							done, err = d.CheckAutoscalingInstanceCount(ctx, client, asg, int(next))
 							if err != nil {
 								d.Logger.Errorf(err.Error())
 								return nil
 							}
 
 							if !done {
-								time.Sleep(config.PollingInterval)
+								if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+									return err
+								}
 							}
 						}
 
@@ -1148,7 +1189,10 @@ func (d *Deployer) CleanPreviousAutoScalingGroup(config schemas.Config) error {
 					}
 				} else {
 					d.Logger.Debugf("[Resizing to 0] target autoscaling group : %s", asg)
-					if err := d.ResizingAutoScalingGroupCount(client, asg, next); err != nil {
+					if err := d.drainAndResize(ctx, client, asg, next, config.PollingInterval); err != nil {
+						if ctx.Err() != nil {
+							return err
+						}
 						d.Logger.Errorf(err.Error())
 					}
 				}
@@ -1193,7 +1237,7 @@ func (d *Deployer) ReducePreviousAutoScalingGroupCapacity(region string, decreas
 }
 
 // CleanChecking checks if instances in previous autoscaling groups are terminated or not
-func (d *Deployer) CleanChecking(config schemas.Config) (bool, error) {
+func (d *Deployer) CleanChecking(ctx context.Context, config schemas.Config) (bool, error) {
 	var finished []string
 
 	stackName := d.GetStackName()
@@ -1232,7 +1276,7 @@ func (d *Deployer) CleanChecking(config schemas.Config) (bool, error) {
 
 		okCount := 0
 		for _, target := range targets {
-			ok := d.CheckTerminating(client, target, config.DisableMetrics)
+			ok := d.CheckTerminating(ctx, client, target, config.DisableMetrics)
 			if ok {
 				d.Logger.Infof("Termination finished: %s", target)
 				okCount++
@@ -1277,6 +1321,84 @@ func (d *Deployer) ClearResources(client aws.Client, target string, disableMetri
 	return true
 }
 
+// RollbackDeployment restores previous autoscaling groups and removes the new autoscaling group
+func (d *Deployer) RollbackDeployment(ctx context.Context, config schemas.Config) error {
+	for _, region := range d.Stack.Regions {
+		if config.Region != constants.EmptyString && config.Region != region.Region {
+			d.Logger.Debugf("This region is skipped by user: %s", region.Region)
+			continue
+		}
+
+		newAsg, ok := d.AsgNames[region.Region]
+		if !ok || len(newAsg) == 0 {
+			d.Logger.Infof("No new autoscaling group to roll back : %s", region.Region)
+			continue
+		}
+
+		client, err := selectClientFromList(d.AWSClients, region.Region)
+		if err != nil {
+			return err
+		}
+
+		d.Logger.Infof("Start rolling back autoscaling group : %s", newAsg)
+		d.Slack.SendSimpleMessage(fmt.Sprintf(":rewind: Start rolling back autoscaling group : %s", newAsg))
+
+		// Restore the capacity of the autoscaling group which was serving before deployment
+		if latestAsg, ok := d.LatestAsg[region.Region]; ok && latestAsg != newAsg {
+			prevCapacity := d.PrevInstanceCount[region.Region]
+			d.Logger.Infof("Restoring previous autoscaling group %s - Min: %d, Desired: %d, Max: %d", latestAsg, prevCapacity.Min, prevCapacity.Desired, prevCapacity.Max)
+			if err := d.ResizingAutoScalingGroup(latestAsg, region.Region, prevCapacity); err != nil {
+				return err
+			}
+		}
+
+		// Drain the new autoscaling group before deleting it
+		if err := d.ResizingAutoScalingGroupCount(client, newAsg, 0); err != nil {
+			return err
+		}
+
+		start := time.Now().Unix()
+		done := false
+		for !done {
+			isTimeout, _ := tool.CheckTimeout(start, config.Timeout)
+			if isTimeout {
+				return fmt.Errorf("timeout has been exceeded while rolling back %s : %.0f minutes", newAsg, config.Timeout.Minutes())
+			}
+
+			done, err = d.CheckAutoscalingInstanceCount(ctx, client, newAsg, 0)
+			if err != nil {
+				return err
+			}
+
+			if !done {
+				if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := d.CleanAutoscalingSet(client, newAsg); err != nil {
+			return err
+		}
+
+		d.Logger.Debugf("Start deleting launch templates in %s", newAsg)
+		if err := client.EC2Service.DeleteLaunchTemplates(newAsg); err != nil {
+			return err
+		}
+
+		if d.Collector.MetricConfig.Enabled {
+			if err := d.Collector.UpdateStatus(newAsg, constants.StatusRolledBack, nil); err != nil {
+				d.Logger.Errorf("Update status Error, %s : %s", err.Error(), newAsg)
+			}
+		}
+
+		d.Logger.Infof("Autoscaling group is rolled back : %s", newAsg)
+		d.Slack.SendSimpleMessage(fmt.Sprintf(":rewind: Autoscaling group is rolled back : %s", newAsg))
+	}
+
+	return nil
+}
+
 // StartGatheringMetrics starts to gather the whole metrics from deployer
 func (d *Deployer) StartGatheringMetrics(config schemas.Config) error {
 	for _, region := range d.Stack.Regions {
@@ -1363,7 +1485,7 @@ func (d *Deployer) DescribeAutoScalingGroup(asg, region string) (*autoscaling.Gr
 		return nil, err
 	}
 
-	ret, err := client.EC2Service.GetMatchingAutoscalingGroup(asg)
+	ret, err := client.EC2Service.GetMatchingAutoscalingGroup(context.Background(), asg)
 	if err != nil {
 		return nil, err
 	}
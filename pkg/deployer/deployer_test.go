@@ -17,6 +17,8 @@ limitations under the license.
 package deployer
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/DevopsArtFactory/goployer/pkg/constants"
@@ -88,3 +90,38 @@ func TestDeployer_ValidateOption(t *testing.T) {
 		t.Errorf("Invalid Override Spot Types Option: %s", validErr)
 	}
 }
+
+func TestPrintPlans(t *testing.T) {
+	plans := []Plan{
+		{
+			Stack:  "artd",
+			Region: constants.DefaultRegion,
+			Mode:   constants.BlueGreenDeployment,
+			NewAutoScalingGroup: &NewAutoScalingGroupPlan{
+				Name: "hello-artd_apnortheast2-v002",
+				LaunchTemplate: LaunchTemplatePlan{
+					Name: "hello-artd_apnortheast2-v002-20200101",
+				},
+				Capacity: schemas.Capacity{Min: 1, Max: 2, Desired: 1},
+				Tags:     map[string]string{"Name": "hello-artd_apnortheast2-v002"},
+			},
+			PreviousAutoScalingGroups: []PreviousAutoScalingGroupPlan{
+				{
+					Name:   "hello-artd_apnortheast2-v001",
+					Action: "resize to 0 and delete",
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := PrintPlans(&buf, plans); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"hello-artd_apnortheast2-v002", "hello-artd_apnortheast2-v001", "resize to 0 and delete"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("plan output does not contain %s", expected)
+		}
+	}
+}
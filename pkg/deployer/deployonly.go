@@ -17,6 +17,7 @@ limitations under the license.
 package deployer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -59,7 +60,7 @@ func (d *DeployOnly) GetDeployer() *Deployer {
 }
 
 // CheckPreviousResources checks if there is any previous version of autoscaling group
-func (d *DeployOnly) CheckPreviousResources(config schemas.Config) error {
+func (d *DeployOnly) CheckPreviousResources(ctx context.Context, config schemas.Config) error {
 	err := d.CheckPrevious(config)
 	if err != nil {
 		return err
@@ -69,7 +70,7 @@ func (d *DeployOnly) CheckPreviousResources(config schemas.Config) error {
 }
 
 // Deploy function
-func (d *DeployOnly) Deploy(config schemas.Config) error {
+func (d *DeployOnly) Deploy(ctx context.Context, config schemas.Config) error {
 	if !d.StepStatus[constants.StepCheckPrevious] {
 		return nil
 	}
@@ -98,18 +99,16 @@ func (d *DeployOnly) Deploy(config schemas.Config) error {
 }
 
 // HealthChecking does health checking for d.ue-green deployment
-func (d *DeployOnly) HealthChecking(_ schemas.Config) error {
+func (d *DeployOnly) HealthChecking(ctx context.Context, _ schemas.Config) error {
 	// No health checking is needed
 	Logger.Info("Skip health check because this is DeployOnly mode")
 
 	// sleep 30 seconds for a new instance to be ready
-	time.Sleep(30 * time.Second)
-
-	return nil
+	return tool.SleepWithContext(ctx, 30*time.Second)
 }
 
 // FinishAdditionalWork processes additional work for the new deployment
-func (d *DeployOnly) FinishAdditionalWork(config schemas.Config) error {
+func (d *DeployOnly) FinishAdditionalWork(ctx context.Context, config schemas.Config) error {
 	if !d.StepStatus[constants.StepDeploy] {
 		return nil
 	}
@@ -128,7 +127,7 @@ func (d *DeployOnly) FinishAdditionalWork(config schemas.Config) error {
 }
 
 // TriggerLifecycleCallbacks.cks runs lifecycle callbacks d.fore cleaning.
-func (d *DeployOnly) TriggerLifecycleCallbacks(config schemas.Config) error {
+func (d *DeployOnly) TriggerLifecycleCallbacks(ctx context.Context, config schemas.Config) error {
 	if !d.StepStatus[constants.StepAdditionalWork] {
 		return nil
 	}
@@ -136,7 +135,7 @@ func (d *DeployOnly) TriggerLifecycleCallbacks(config schemas.Config) error {
 }
 
 // CleanPreviousVersion cleans previous version of autoscaling group
-func (d *DeployOnly) CleanPreviousVersion(config schemas.Config) error {
+func (d *DeployOnly) CleanPreviousVersion(ctx context.Context, config schemas.Config) error {
 	if !d.StepStatus[constants.StepTriggerLifecycleCallback] {
 		return nil
 	}
@@ -150,7 +149,7 @@ func (d *DeployOnly) CleanPreviousVersion(config schemas.Config) error {
 	}
 
 	if !skipped {
-		if err := d.CleanPreviousAutoScalingGroup(config); err != nil {
+		if err := d.CleanPreviousAutoScalingGroup(ctx, config); err != nil {
 			return err
 		}
 	}
@@ -159,7 +158,7 @@ func (d *DeployOnly) CleanPreviousVersion(config schemas.Config) error {
 }
 
 // CleanChecking checks Termination status
-func (d *DeployOnly) CleanChecking(config schemas.Config) error {
+func (d *DeployOnly) CleanChecking(ctx context.Context, config schemas.Config) error {
 	if !d.StepStatus[constants.StepCleanPreviousVersion] {
 		return nil
 	}
@@ -171,7 +170,7 @@ func (d *DeployOnly) CleanChecking(config schemas.Config) error {
 			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
 		}
 
-		isDone, err := d.Deployer.CleanChecking(config)
+		isDone, err := d.Deployer.CleanChecking(ctx, config)
 		if err != nil {
 			return errors.New("error happened while health checking")
 		}
@@ -180,7 +179,9 @@ func (d *DeployOnly) CleanChecking(config schemas.Config) error {
 			done = true
 		} else {
 			d.Logger.Info("All stacks are not ready to be terminated... Please waiting...")
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -189,7 +190,7 @@ func (d *DeployOnly) CleanChecking(config schemas.Config) error {
 }
 
 // GatherMetrics gathers the whole metrics from deployer
-func (d *DeployOnly) GatherMetrics(config schemas.Config) error {
+func (d *DeployOnly) GatherMetrics(ctx context.Context, config schemas.Config) error {
 	if config.DisableMetrics {
 		return nil
 	}
@@ -208,6 +209,11 @@ func (d *DeployOnly) GatherMetrics(config schemas.Config) error {
 }
 
 // RunAPITest tries to run API Test
-func (d *DeployOnly) RunAPITest(config schemas.Config) error {
+func (d *DeployOnly) RunAPITest(ctx context.Context, config schemas.Config) error {
 	return d.Deployer.RunAPITest(config)
 }
+
+// Rollback removes the new autoscaling group and restores the previous version
+func (d *DeployOnly) Rollback(ctx context.Context, config schemas.Config) error {
+	return d.RollbackDeployment(ctx, config)
+}
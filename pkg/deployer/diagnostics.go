@@ -0,0 +1,319 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// instanceDiagnostics is the summary of an unhealthy instance in diagnostics bundle
+type instanceDiagnostics struct {
+	InstanceID         string
+	LifecycleState     string
+	TargetStatus       string
+	HealthStatus       string
+	TargetHealthReason string `json:",omitempty"`
+	InstanceState      string `json:",omitempty"`
+	SystemStatus       string `json:",omitempty"`
+	InstanceStatus     string `json:",omitempty"`
+	StatusDetails      []string
+}
+
+// HealthCheckTimeout returns the timeout error of health checking after collecting diagnostics of unhealthy instances
+func (d *Deployer) HealthCheckTimeout(ctx context.Context, config schemas.Config) error {
+	err := fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
+	if interruptions := d.countSpotInterruptions(); interruptions > 0 {
+		err = fmt.Errorf("timeout has been exceeded : %.0f minutes extended by %s for %d spot interruptions", config.Timeout.Minutes(), tool.RoundTime(d.HealthCheckExtension), interruptions)
+	}
+
+	bundles := d.CollectDiagnostics(ctx, config)
+	message := fmt.Sprintf(":x: health checking of %s failed: %s", d.GetPipelineName(), err.Error())
+	for _, bundle := range bundles {
+		message += fmt.Sprintf("\ndiagnostics bundle: %s", bundle)
+	}
+	d.Slack.SendSimpleMessage(message)
+
+	return err
+}
+
+// CollectDiagnostics saves diagnostics bundles of unhealthy instances of the last health check and returns paths of tarballs
+// Failures are only logged because diagnostics should not hide the failure of health checking.
+func (d *Deployer) CollectDiagnostics(ctx context.Context, config schemas.Config) []string {
+	if config.DisableDiagnostics || len(config.DiagnosticsDir) == 0 {
+		return nil
+	}
+
+	var bundles []string
+	for _, region := range d.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			continue
+		}
+
+		asg := d.AsgNames[region.Region]
+		if len(config.TargetAutoscalingGroup) > 0 {
+			asg = config.TargetAutoscalingGroup
+		}
+
+		if len(asg) == 0 {
+			continue
+		}
+
+		client, err := selectClientFromList(d.AWSClients, region.Region)
+		if err != nil {
+			d.Logger.Errorf("failed to collect diagnostics of %s: %s", asg, err.Error())
+			continue
+		}
+
+		bundle, err := d.saveDiagnosticsBundle(ctx, client, config, asg, d.UnhealthyHosts[region.Region])
+		if err != nil {
+			d.Logger.Errorf("failed to collect diagnostics of %s: %s", asg, err.Error())
+			continue
+		}
+
+		d.Logger.Infof("diagnostics bundle is saved: %s", bundle)
+		bundles = append(bundles, bundle)
+	}
+
+	return bundles
+}
+
+// saveDiagnosticsBundle writes diagnostics of autoscaling group and its unhealthy instances to a directory and archives it
+func (d *Deployer) saveDiagnosticsBundle(ctx context.Context, client aws.Client, config schemas.Config, asg string, hosts []aws.HealthcheckHost) (string, error) {
+	dir := filepath.Join(config.DiagnosticsDir, fmt.Sprintf("%s-%s", asg, time.Now().Format("20060102150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	activities, err := client.EC2Service.DescribeScalingActivities(ctx, asg)
+	if err != nil {
+		d.Logger.Warnf("failed to get scaling activities of %s: %s", asg, err.Error())
+	} else if err := writeJSONFile(filepath.Join(dir, "activities.json"), activities); err != nil {
+		return "", err
+	}
+
+	instances := make([]instanceDiagnostics, len(hosts))
+	var instanceIds []*string
+	for i, host := range hosts {
+		instances[i] = instanceDiagnostics{
+			InstanceID:         host.InstanceID,
+			LifecycleState:     host.LifecycleState,
+			TargetStatus:       host.TargetStatus,
+			HealthStatus:       host.HealthStatus,
+			TargetHealthReason: host.Reason,
+		}
+		instanceIds = append(instanceIds, eaws.String(host.InstanceID))
+	}
+
+	if len(instanceIds) > 0 {
+		statuses, err := client.EC2Service.DescribeInstanceStatuses(ctx, instanceIds)
+		if err != nil {
+			d.Logger.Warnf("failed to get status checks of instances: %s", err.Error())
+		}
+		applyInstanceStatuses(instances, statuses)
+	}
+
+	if err := writeJSONFile(filepath.Join(dir, "instances.json"), instances); err != nil {
+		return "", err
+	}
+
+	for _, host := range hosts {
+		instanceDir := filepath.Join(dir, host.InstanceID)
+		if err := os.MkdirAll(instanceDir, 0755); err != nil {
+			return "", err
+		}
+
+		output, err := client.EC2Service.GetConsoleOutput(ctx, host.InstanceID)
+		if err != nil {
+			d.Logger.Warnf("failed to get console output of %s: %s", host.InstanceID, err.Error())
+		} else if err := os.WriteFile(filepath.Join(instanceDir, "console-output.log"), []byte(output), 0644); err != nil {
+			return "", err
+		}
+
+		if config.DiagnosticsCloudInit {
+			output, err := d.getCloudInitOutput(ctx, client, host.InstanceID)
+			if err != nil {
+				d.Logger.Warnf("failed to get cloud-init output of %s: %s", host.InstanceID, err.Error())
+			} else if err := os.WriteFile(filepath.Join(instanceDir, "cloud-init-output.log"), []byte(output), 0644); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	tarball := dir + ".tar.gz"
+	if err := archiveDirectory(dir, tarball); err != nil {
+		return "", err
+	}
+
+	return tarball, nil
+}
+
+// getCloudInitOutput fetches the last lines of cloud-init output log from the instance via SSM
+func (d *Deployer) getCloudInitOutput(ctx context.Context, client aws.Client, instanceID string) (string, error) {
+	commands := []string{fmt.Sprintf("tail -n %d /var/log/cloud-init-output.log", constants.DiagnosticsCloudInitLines)}
+	commandID, err := client.SSMService.SendDiagnosticsCommand(ctx, instanceID, commands, int64(constants.DiagnosticsCommandTimeout.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	for time.Since(start) < constants.DiagnosticsCommandTimeout {
+		if err := tool.SleepWithContext(ctx, 2*time.Second); err != nil {
+			return "", err
+		}
+
+		status, output, err := client.SSMService.GetCommandOutput(ctx, commandID, instanceID)
+		if err != nil {
+			return "", err
+		}
+
+		switch status {
+		case ssm.CommandInvocationStatusSuccess:
+			return output, nil
+		case ssm.CommandInvocationStatusPending, ssm.CommandInvocationStatusInProgress, ssm.CommandInvocationStatusDelayed:
+			continue
+		default:
+			return "", fmt.Errorf("command %s is %s", commandID, status)
+		}
+	}
+
+	return "", fmt.Errorf("command %s is not finished in %s", commandID, constants.DiagnosticsCommandTimeout)
+}
+
+// unhealthyHosts returns hosts which are not valid
+func unhealthyHosts(hosts []aws.HealthcheckHost) []aws.HealthcheckHost {
+	var ret []aws.HealthcheckHost
+	for _, host := range hosts {
+		if !host.Valid {
+			ret = append(ret, host)
+		}
+	}
+
+	return ret
+}
+
+// applyInstanceStatuses fills status checks of instances in diagnostics
+func applyInstanceStatuses(instances []instanceDiagnostics, statuses []*ec2.InstanceStatus) {
+	for _, status := range statuses {
+		for i := range instances {
+			if instances[i].InstanceID != eaws.StringValue(status.InstanceId) {
+				continue
+			}
+
+			if status.InstanceState != nil {
+				instances[i].InstanceState = eaws.StringValue(status.InstanceState.Name)
+			}
+
+			if status.SystemStatus != nil {
+				instances[i].SystemStatus = eaws.StringValue(status.SystemStatus.Status)
+				for _, detail := range status.SystemStatus.Details {
+					instances[i].StatusDetails = append(instances[i].StatusDetails, fmt.Sprintf("system %s: %s", eaws.StringValue(detail.Name), eaws.StringValue(detail.Status)))
+				}
+			}
+
+			if status.InstanceStatus != nil {
+				instances[i].InstanceStatus = eaws.StringValue(status.InstanceStatus.Status)
+				for _, detail := range status.InstanceStatus.Details {
+					instances[i].StatusDetails = append(instances[i].StatusDetails, fmt.Sprintf("instance %s: %s", eaws.StringValue(detail.Name), eaws.StringValue(detail.Status)))
+				}
+			}
+		}
+	}
+}
+
+// writeJSONFile writes value to the file in indented JSON format
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
+
+// archiveDirectory creates a gzipped tarball of the directory
+// Paths in the tarball start with the name of the directory.
+func archiveDirectory(dir, target string) error {
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	base := filepath.Dir(dir)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
@@ -0,0 +1,152 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+)
+
+func TestUnhealthyHosts(t *testing.T) {
+	hosts := []aws.HealthcheckHost{
+		{InstanceID: "i-1", TargetStatus: "healthy", Valid: true},
+		{InstanceID: "i-2", TargetStatus: "unhealthy", Reason: "Target.FailedHealthChecks: Health checks failed"},
+		{InstanceID: "i-3", TargetStatus: "Not Found"},
+	}
+
+	expected := []aws.HealthcheckHost{hosts[1], hosts[2]}
+	if diff := deep.Equal(unhealthyHosts(hosts), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if got := unhealthyHosts(hosts[:1]); got != nil {
+		t.Errorf("expected no unhealthy host, got %v", got)
+	}
+}
+
+func TestApplyInstanceStatuses(t *testing.T) {
+	instances := []instanceDiagnostics{
+		{InstanceID: "i-1"},
+		{InstanceID: "i-2"},
+	}
+
+	statuses := []*ec2.InstanceStatus{
+		{
+			InstanceId:    eaws.String("i-2"),
+			InstanceState: &ec2.InstanceState{Name: eaws.String("running")},
+			SystemStatus: &ec2.InstanceStatusSummary{
+				Status:  eaws.String("ok"),
+				Details: []*ec2.InstanceStatusDetails{{Name: eaws.String("reachability"), Status: eaws.String("passed")}},
+			},
+			InstanceStatus: &ec2.InstanceStatusSummary{
+				Status:  eaws.String("impaired"),
+				Details: []*ec2.InstanceStatusDetails{{Name: eaws.String("reachability"), Status: eaws.String("failed")}},
+			},
+		},
+	}
+
+	applyInstanceStatuses(instances, statuses)
+
+	expected := []instanceDiagnostics{
+		{InstanceID: "i-1"},
+		{
+			InstanceID:     "i-2",
+			InstanceState:  "running",
+			SystemStatus:   "ok",
+			InstanceStatus: "impaired",
+			StatusDetails:  []string{"system reachability: passed", "instance reachability: failed"},
+		},
+	}
+
+	if diff := deep.Equal(instances, expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestArchiveDirectory(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "hello-v001")
+	if err := os.MkdirAll(filepath.Join(dir, "i-1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"activities.json":        "[]",
+		"i-1/console-output.log": "boot",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tarball := dir + ".tar.gz"
+	if err := archiveDirectory(dir, tarball); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[header.Name] = string(b)
+	}
+
+	expected := map[string]string{
+		"hello-v001/activities.json":        "[]",
+		"hello-v001/i-1/console-output.log": "boot",
+	}
+	if diff := deep.Equal(got, expected); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// drainTarget is a target group or a classic load balancer which instances are deregistered from
+type drainTarget struct {
+	name     string
+	arn      string
+	delay    time.Duration
+	draining []string
+}
+
+// drainAndResize resizes autoscaling group to the count after instances which are removed are drained
+// The drained instances are terminated by goployer so that autoscaling group does not choose other instances.
+func (d *Deployer) drainAndResize(ctx context.Context, client aws.Client, asg string, count int64, pollingInterval time.Duration) error {
+	group, err := client.EC2Service.GetMatchingAutoscalingGroup(ctx, asg)
+	if err != nil {
+		return err
+	}
+
+	if group == nil {
+		return fmt.Errorf("autoscaling group does not exist: %s", asg)
+	}
+
+	victims := selectDrainInstances(group.Instances, count)
+	if len(victims) == 0 || (len(group.TargetGroupARNs) == 0 && len(group.LoadBalancerNames) == 0) {
+		return d.ResizingAutoScalingGroupCount(client, asg, count)
+	}
+
+	if err := d.DrainInstances(ctx, client, group, victims, pollingInterval); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		d.Logger.Warnf("failed to drain instances of %s, resizing anyway: %s", asg, err.Error())
+	}
+
+	if count > 0 {
+		if _, err := client.EC2Service.UpdateAutoScalingGroupSize(asg, count, *group.MaxSize, *group.DesiredCapacity, 0); err != nil {
+			return err
+		}
+
+		for _, id := range victims {
+			if err := client.EC2Service.TerminateInstanceInAutoScalingGroup(ctx, id, true); err != nil {
+				d.Logger.Warnf("failed to terminate drained instance %s: %s", id, err.Error())
+			}
+		}
+	}
+
+	return d.ResizingAutoScalingGroupCount(client, asg, count)
+}
+
+// DrainInstances deregisters instances of autoscaling group from its target groups and classic load balancers
+// and waits until they are out of draining state, so that in-flight requests are not cut off by termination.
+// Waiting is given up after the longest deregistration delay with a margin.
+func (d *Deployer) DrainInstances(ctx context.Context, client aws.Client, group *autoscaling.Group, instanceIDs []string, pollingInterval time.Duration) error {
+	asg := eaws.StringValue(group.AutoScalingGroupName)
+
+	var targets []*drainTarget
+	for _, arn := range eaws.StringValueSlice(group.TargetGroupARNs) {
+		delay, err := client.ELBV2Service.GetDeregistrationDelay(ctx, arn)
+		if err != nil {
+			return err
+		}
+
+		if err := client.ELBV2Service.DeregisterTargets(ctx, arn, instanceIDs); err != nil {
+			return err
+		}
+		targets = append(targets, &drainTarget{name: tool.ParseTargetGroupName(arn), arn: arn, delay: delay})
+	}
+
+	for _, lb := range eaws.StringValueSlice(group.LoadBalancerNames) {
+		delay, err := client.ELBService.GetConnectionDrainingTimeout(ctx, lb)
+		if err != nil {
+			return err
+		}
+
+		if err := client.ELBService.DeregisterInstances(ctx, lb, instanceIDs); err != nil {
+			return err
+		}
+		targets = append(targets, &drainTarget{name: lb, delay: delay})
+	}
+
+	timeout := constants.DrainingTimeoutMargin
+	for _, t := range targets {
+		if t.delay+constants.DrainingTimeoutMargin > timeout {
+			timeout = t.delay + constants.DrainingTimeoutMargin
+		}
+	}
+
+	d.Logger.Infof("Draining %d instances of %s : %s", len(instanceIDs), asg, strings.Join(instanceIDs, ", "))
+	d.Slack.SendSimpleMessage(fmt.Sprintf("Draining %d instances of %s before resizing", len(instanceIDs), asg))
+
+	interval := pollingInterval
+	if interval <= 0 || interval > constants.DrainingTimeoutMargin {
+		interval = constants.DrainingTimeoutMargin
+	}
+
+	start := time.Now()
+	for {
+		for _, t := range targets {
+			var err error
+			if len(t.arn) > 0 {
+				t.draining, err = client.ELBV2Service.GetDrainingTargets(ctx, t.arn, instanceIDs)
+			} else {
+				t.draining, err = client.ELBService.GetDrainingInstances(ctx, t.name, instanceIDs)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		progress, done := drainProgress(targets, len(instanceIDs))
+		if done {
+			d.Logger.Infof("Instances of %s are drained in %s", asg, tool.RoundTime(time.Since(start)))
+			return nil
+		}
+
+		if time.Since(start) >= timeout {
+			d.Logger.Warnf("Draining of %s is not finished in %s : %s", asg, tool.RoundTime(timeout), progress)
+			return nil
+		}
+
+		d.Logger.Infof("Draining %s : %s, %s elapsed", asg, progress, tool.RoundTime(time.Since(start)))
+		if err := tool.SleepWithContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// selectDrainInstances returns instances which are removed when autoscaling group is resized to the count
+// Instances which are not in service are chosen first, and the rest are chosen in order of instance ID.
+func selectDrainInstances(instances []*autoscaling.Instance, count int64) []string {
+	if int64(len(instances)) <= count {
+		return nil
+	}
+
+	sorted := make([]*autoscaling.Instance, len(instances))
+	copy(sorted, instances)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		iInService := eaws.StringValue(sorted[i].LifecycleState) == constants.InServiceStatus
+		jInService := eaws.StringValue(sorted[j].LifecycleState) == constants.InServiceStatus
+		if iInService != jInService {
+			return !iInService
+		}
+
+		return eaws.StringValue(sorted[i].InstanceId) < eaws.StringValue(sorted[j].InstanceId)
+	})
+
+	var ret []string
+	for _, instance := range sorted[:int64(len(sorted))-count] {
+		ret = append(ret, eaws.StringValue(instance.InstanceId))
+	}
+
+	return ret
+}
+
+// drainProgress returns the progress of draining of each target and whether all targets are drained
+func drainProgress(targets []*drainTarget, total int) (string, bool) {
+	done := true
+	var progress []string
+	for _, t := range targets {
+		if len(t.draining) > 0 {
+			done = false
+		}
+		progress = append(progress, fmt.Sprintf("%s %d/%d drained", t.name, total-len(t.draining), total))
+	}
+
+	return strings.Join(progress, ", "), done
+}
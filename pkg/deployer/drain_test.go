@@ -0,0 +1,81 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+)
+
+func TestSelectDrainInstances(t *testing.T) {
+	instances := []*autoscaling.Instance{
+		{InstanceId: eaws.String("i-3"), LifecycleState: eaws.String(constants.InServiceStatus)},
+		{InstanceId: eaws.String("i-1"), LifecycleState: eaws.String(constants.InServiceStatus)},
+		{InstanceId: eaws.String("i-4"), LifecycleState: eaws.String("Pending")},
+		{InstanceId: eaws.String("i-2"), LifecycleState: eaws.String(constants.InServiceStatus)},
+	}
+
+	testData := []struct {
+		count    int64
+		expected []string
+	}{
+		{
+			count:    4,
+			expected: nil,
+		},
+		{
+			count:    2,
+			expected: []string{"i-4", "i-1"},
+		},
+		{
+			count:    0,
+			expected: []string{"i-4", "i-1", "i-2", "i-3"},
+		},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(selectDrainInstances(instances, td.count), td.expected); diff != nil {
+			t.Errorf("count %d: %v", td.count, diff)
+		}
+	}
+
+	if eaws.StringValue(instances[0].InstanceId) != "i-3" {
+		t.Error("instances should not be reordered")
+	}
+}
+
+func TestDrainProgress(t *testing.T) {
+	targets := []*drainTarget{
+		{name: "hello-tg", draining: []string{"i-1"}},
+		{name: "hello-elb"},
+	}
+
+	progress, done := drainProgress(targets, 2)
+	if done || progress != "hello-tg 1/2 drained, hello-elb 2/2 drained" {
+		t.Errorf("unexpected progress: %s, %t", progress, done)
+	}
+
+	targets[0].draining = nil
+	if _, done := drainProgress(targets, 2); !done {
+		t.Error("expected all targets to be drained")
+	}
+}
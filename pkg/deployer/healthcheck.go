@@ -0,0 +1,490 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ssm"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// healthCheckClient does not follow redirects so that the status code of the instance itself is compared
+// A redirect to a login or maintenance page should not be counted as healthy.
+var healthCheckClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// GetHostsByHealthCheck checks health of each instance of autoscaling group with the health check type
+// An instance is valid after it passes health check consecutively as many times as configured.
+func (d *Deployer) GetHostsByHealthCheck(ctx context.Context, client aws.Client, asg *autoscaling.Group, hc schemas.HealthCheckConfig) ([]aws.HealthcheckHost, error) {
+	if len(asg.Instances) == 0 {
+		return nil, nil
+	}
+
+	var results []healthCheckResult
+	var err error
+	switch hc.Type {
+	case constants.SSMHealthCheck:
+		results, err = d.runHealthCheckCommands(ctx, client, asg.Instances, hc)
+	default:
+		results, err = requestHealthChecks(ctx, client, asg.Instances, hc)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := hc.ConsecutiveSuccesses
+	if threshold == 0 {
+		threshold = constants.DefaultHealthCheckConsecutiveSuccesses
+	}
+
+	return d.countHealthySuccesses(asg.Instances, results, threshold), nil
+}
+
+// requestHealthChecks requests http health check to instances with their private IP addresses at the same time
+func requestHealthChecks(ctx context.Context, client aws.Client, instances []*autoscaling.Instance, hc schemas.HealthCheckConfig) ([]healthCheckResult, error) {
+	var instanceIds []*string
+	for _, instance := range instances {
+		instanceIds = append(instanceIds, instance.InstanceId)
+	}
+
+	ips, err := client.EC2Service.GetPrivateIPAddresses(ctx, instanceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]healthCheckResult, len(instances))
+	wg := sync.WaitGroup{}
+	for i, instance := range instances {
+		ip, ok := ips[*instance.InstanceId]
+		if !ok {
+			results[i] = healthCheckResult{status: constants.InitialStatus}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			results[i] = requestHealthCheck(ctx, ip, hc)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// runHealthCheckCommands checks results of health check commands and sends commands again to instances
+// Commands are not waited for, so results of the commands are checked in the next polling.
+func (d *Deployer) runHealthCheckCommands(ctx context.Context, client aws.Client, instances []*autoscaling.Instance, hc schemas.HealthCheckConfig) ([]healthCheckResult, error) {
+	if d.HealthCommands == nil {
+		d.HealthCommands = map[string]string{}
+	}
+
+	timeout := hc.Timeout
+	if timeout == 0 {
+		timeout = constants.DefaultHealthCheckTimeout
+	}
+	executionTimeout := int64(math.Ceil(timeout.Seconds()))
+
+	results := make([]healthCheckResult, len(instances))
+	for i, instance := range instances {
+		id := *instance.InstanceId
+		if commandID, ok := d.HealthCommands[id]; ok {
+			status, code, err := client.SSMService.GetCommandInvocation(ctx, commandID, id)
+			if err != nil {
+				return nil, err
+			}
+
+			results[i] = commandResult(status, code)
+			if !results[i].pending {
+				delete(d.HealthCommands, id)
+			}
+			continue
+		}
+
+		// commands can only be sent to instances which are running
+		if *instance.LifecycleState != constants.InServiceStatus {
+			results[i] = healthCheckResult{status: constants.InitialStatus, pending: true}
+			continue
+		}
+
+		commandID, err := client.SSMService.SendHealthCheckCommand(ctx, id, hc.Commands, executionTimeout)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeInvalidInstanceId {
+				// ssm agent of the new instance is not registered yet
+				results[i] = healthCheckResult{status: "ssm not ready", pending: true}
+				continue
+			}
+			return nil, err
+		}
+
+		d.HealthCommands[id] = commandID
+		results[i] = healthCheckResult{status: "command sent", pending: true}
+	}
+
+	return results, nil
+}
+
+// commandResult converts status of command invocation to the result of health check
+func commandResult(status string, code int64) healthCheckResult {
+	switch status {
+	case ssm.CommandInvocationStatusSuccess:
+		return healthCheckResult{status: fmt.Sprintf("exit %d", code), healthy: code == 0}
+	case ssm.CommandInvocationStatusFailed:
+		return healthCheckResult{status: fmt.Sprintf("exit %d", code)}
+	case ssm.CommandInvocationStatusPending, ssm.CommandInvocationStatusInProgress, ssm.CommandInvocationStatusDelayed:
+		return healthCheckResult{status: strings.ToLower(status), pending: true}
+	default:
+		return healthCheckResult{status: strings.ToLower(status)}
+	}
+}
+
+// countHealthySuccesses counts consecutive successes of each instance and returns the host status
+func (d *Deployer) countHealthySuccesses(instances []*autoscaling.Instance, results []healthCheckResult, threshold int) []aws.HealthcheckHost {
+	if d.HealthySuccesses == nil {
+		d.HealthySuccesses = map[string]int{}
+	}
+
+	var ret []aws.HealthcheckHost
+	for i, instance := range instances {
+		id := *instance.InstanceId
+		switch {
+		case results[i].pending:
+			// result of previous check is kept until the check is finished
+		case results[i].healthy:
+			d.HealthySuccesses[id]++
+		default:
+			d.HealthySuccesses[id] = 0
+		}
+
+		ret = append(ret, aws.HealthcheckHost{
+			InstanceID:     id,
+			LifecycleState: *instance.LifecycleState,
+			TargetStatus:   results[i].status,
+			HealthStatus:   *instance.HealthStatus,
+			Valid:          *instance.LifecycleState == constants.InServiceStatus && d.HealthySuccesses[id] >= threshold,
+		})
+	}
+
+	return ret
+}
+
+// healthCheckResult is the result of health check request to an instance
+type healthCheckResult struct {
+	status  string
+	healthy bool
+	pending bool
+}
+
+// requestHealthCheck requests health check to the instance and compares the status code with the expected one
+func requestHealthCheck(ctx context.Context, ip string, hc schemas.HealthCheckConfig) healthCheckResult {
+	path := hc.Path
+	if len(path) == 0 {
+		path = constants.DefaultHealthCheckPath
+	}
+
+	expected := hc.ExpectedStatus
+	if expected == 0 {
+		expected = constants.DefaultHealthCheckExpectedStatus
+	}
+
+	timeout := hc.Timeout
+	if timeout == 0 {
+		timeout = constants.DefaultHealthCheckTimeout
+	}
+
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(ip, strconv.FormatInt(hc.Port, 10)), path)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return healthCheckResult{status: "invalid request"}
+	}
+
+	resp, err := healthCheckClient.Do(req)
+	if err != nil {
+		return healthCheckResult{status: "unreachable"}
+	}
+	defer resp.Body.Close()
+
+	return healthCheckResult{
+		status:  fmt.Sprintf("http %d", resp.StatusCode),
+		healthy: resp.StatusCode == expected,
+	}
+}
+
+// CheckLaunchFailures returns error if the latest launches of instances in autoscaling group consecutively failed
+// Instances cannot be healthy in this case, so health checking does not need to wait for the timeout.
+func (d *Deployer) CheckLaunchFailures(ctx context.Context, client aws.Client, asg string, config schemas.Config) error {
+	if config.LaunchFailureThreshold <= 0 {
+		return nil
+	}
+
+	activities, err := client.EC2Service.DescribeScalingActivities(ctx, asg)
+	if err != nil {
+		return err
+	}
+
+	failures, message := countLaunchFailures(activities, time.Unix(config.StartTimestamp, 0))
+	if failures > 0 {
+		d.Logger.Warnf("[%s] %d consecutive launch failures: %s", asg, failures, message)
+	}
+
+	if int64(failures) >= config.LaunchFailureThreshold {
+		return fmt.Errorf("launch of instances failed %d times in a row: %s: %s", failures, asg, message)
+	}
+
+	return nil
+}
+
+// countLaunchFailures counts failed activities from the latest finished one and returns the latest status message
+// Activities which are not finished yet or started before the deployment are not counted.
+func countLaunchFailures(activities []*autoscaling.Activity, since time.Time) (int, string) {
+	failures := 0
+	message := ""
+	for _, activity := range activities {
+		if activity.StartTime != nil && activity.StartTime.Before(since) {
+			break
+		}
+
+		status := eaws.StringValue(activity.StatusCode)
+		if status == autoscaling.ScalingActivityStatusCodeSuccessful {
+			break
+		}
+
+		if status != autoscaling.ScalingActivityStatusCodeFailed && status != autoscaling.ScalingActivityStatusCodeCancelled {
+			continue
+		}
+
+		if failures == 0 {
+			message = eaws.StringValue(activity.StatusMessage)
+		}
+		failures++
+	}
+
+	return failures, message
+}
+
+// checkHealthyThreshold returns the number of healthy instances required by the percentage of desired capacity
+// and availability zones of autoscaling group which do not have enough healthy instances.
+func checkHealthyThreshold(hosts []aws.HealthcheckHost, asg *autoscaling.Group, desired, percent, minPerAZ int64) (int64, []string) {
+	required := desired
+	if percent > 0 {
+		required = int64(math.Ceil(float64(desired) * float64(percent) / 100))
+	}
+
+	if minPerAZ <= 0 {
+		return required, nil
+	}
+
+	zones := map[string]string{}
+	for _, instance := range asg.Instances {
+		zones[*instance.InstanceId] = eaws.StringValue(instance.AvailabilityZone)
+	}
+
+	healthy := map[string]int64{}
+	for _, host := range hosts {
+		if host.Valid {
+			healthy[zones[host.InstanceID]]++
+		}
+	}
+
+	var lacking []string
+	for _, zone := range asg.AvailabilityZones {
+		if healthy[*zone] < minPerAZ {
+			lacking = append(lacking, *zone)
+		}
+	}
+
+	return required, lacking
+}
+
+// HandleUnhealthyHosts reports instances which are not healthy after health checking is finished by the thresholds
+// If terminate_unhealthy is set, the instances are terminated so that autoscaling group replaces them.
+func (d *Deployer) HandleUnhealthyHosts(ctx context.Context, client aws.Client, asg string, hosts []aws.HealthcheckHost) {
+	unhealthy := unhealthyHosts(hosts)
+	if len(unhealthy) == 0 {
+		return
+	}
+
+	var ids []string
+	for _, host := range unhealthy {
+		ids = append(ids, fmt.Sprintf("%s(%s)", host.InstanceID, host.TargetStatus))
+	}
+	d.Logger.Warnf("Instances are not healthy in %s : %s", asg, strings.Join(ids, ", "))
+	d.Slack.SendSimpleMessage(fmt.Sprintf(":warning: Instances are not healthy in %s : %s", asg, strings.Join(ids, ", ")))
+
+	if !d.Stack.TerminateUnhealthy {
+		return
+	}
+
+	for _, host := range unhealthy {
+		if strings.HasPrefix(host.LifecycleState, "Terminat") {
+			continue
+		}
+
+		if err := client.EC2Service.TerminateInstanceInAutoScalingGroup(ctx, host.InstanceID, false); err != nil {
+			d.Logger.Errorf("failed to terminate unhealthy instance %s : %s", host.InstanceID, err.Error())
+			continue
+		}
+		d.Logger.Infof("Unhealthy instance is terminated to be replaced : %s", host.InstanceID)
+	}
+}
+
+// healthcheckTargetGroups returns target groups for healthcheck of region
+func healthcheckTargetGroups(region schemas.RegionConfig) []string {
+	var ret []string
+	if len(region.HealthcheckTargetGroup) > 0 {
+		ret = append(ret, region.HealthcheckTargetGroup)
+	}
+
+	for _, tg := range region.HealthcheckTargetGroups {
+		if !tool.IsStringInArray(tg, ret) {
+			ret = append(ret, tg)
+		}
+	}
+
+	return ret
+}
+
+// healthcheckLoadBalancers returns classic load balancers for healthcheck of region
+func healthcheckLoadBalancers(region schemas.RegionConfig) []string {
+	var ret []string
+	if len(region.HealthcheckLB) > 0 {
+		ret = append(ret, region.HealthcheckLB)
+	}
+
+	for _, lb := range region.HealthcheckLBs {
+		if !tool.IsStringInArray(lb, ret) {
+			ret = append(ret, lb)
+		}
+	}
+
+	return ret
+}
+
+// GetHostsInHealthcheckTargets retrieves health of instances in every target group and load balancer for healthcheck
+// If there are several targets, an instance is valid when it is healthy in as many targets as the quorum.
+func (d *Deployer) GetHostsInHealthcheckTargets(ctx context.Context, client aws.Client, region schemas.RegionConfig, asg *autoscaling.Group, isUpdate, downsizingUpdate bool) ([]aws.HealthcheckHost, error) {
+	var targets []string
+	var hostsByTarget [][]aws.HealthcheckHost
+	for _, tg := range healthcheckTargetGroups(region) {
+		var targetGroupArn *string
+		if tool.IsTargetGroupArn(tg, region.Region) {
+			targetGroupArn = eaws.String(tg)
+		} else {
+			tgARNs, err := client.ELBV2Service.GetTargetGroupARNs([]string{tg})
+			if err != nil {
+				return nil, err
+			}
+			targetGroupArn = tgARNs[0]
+		}
+		d.Logger.Debugf("[Checking healthy host count] Target Group : %s", *targetGroupArn)
+
+		hosts, err := client.ELBV2Service.GetHostInTarget(ctx, asg, targetGroupArn, isUpdate, downsizingUpdate)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, tg)
+		hostsByTarget = append(hostsByTarget, hosts)
+	}
+
+	for _, lb := range healthcheckLoadBalancers(region) {
+		d.Logger.Debugf("[Checking healthy host count] Load Balancer : %s", lb)
+		hosts, err := client.ELBService.GetHealthyHostInELB(ctx, asg, lb)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, lb)
+		hostsByTarget = append(hostsByTarget, hosts)
+	}
+
+	if len(hostsByTarget) == 1 {
+		return hostsByTarget[0], nil
+	}
+
+	return mergeTargetHosts(asg.Instances, targets, hostsByTarget, region.HealthcheckQuorum), nil
+}
+
+// mergeTargetHosts merges health of instances in several targets with status of each target
+func mergeTargetHosts(instances []*autoscaling.Instance, targets []string, hostsByTarget [][]aws.HealthcheckHost, quorum int) []aws.HealthcheckHost {
+	if quorum <= 0 || quorum > len(targets) {
+		quorum = len(targets)
+	}
+
+	var ret []aws.HealthcheckHost
+	for _, instance := range instances {
+		id := *instance.InstanceId
+		healthy := 0
+		var statuses, reasons []string
+		for i, hosts := range hostsByTarget {
+			status := constants.InitialStatus
+			for _, host := range hosts {
+				if host.InstanceID != id {
+					continue
+				}
+
+				// classic load balancer keeps the state of instance as lifecycle state
+				status = host.TargetStatus
+				if len(status) == 0 {
+					status = host.LifecycleState
+				}
+
+				if host.Valid {
+					healthy++
+				}
+
+				if len(host.Reason) > 0 {
+					reasons = append(reasons, fmt.Sprintf("%s %s", targets[i], host.Reason))
+				}
+				break
+			}
+			statuses = append(statuses, fmt.Sprintf("%s=%s", targets[i], status))
+		}
+
+		ret = append(ret, aws.HealthcheckHost{
+			InstanceID:     id,
+			LifecycleState: *instance.LifecycleState,
+			TargetStatus:   strings.Join(statuses, ", "),
+			HealthStatus:   *instance.HealthStatus,
+			Reason:         strings.Join(reasons, ", "),
+			Valid:          healthy >= quorum,
+		})
+	}
+
+	return ret
+}
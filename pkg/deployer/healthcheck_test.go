@@ -0,0 +1,329 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestRequestHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/ready":
+			w.WriteHeader(http.StatusNoContent)
+		case "/maintenance":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.ParseInt(port, 10, 64)
+
+	testData := []struct {
+		hc       schemas.HealthCheckConfig
+		expected healthCheckResult
+	}{
+		{
+			hc:       schemas.HealthCheckConfig{Type: "http", Port: p},
+			expected: healthCheckResult{status: "http 200", healthy: true},
+		},
+		{
+			hc:       schemas.HealthCheckConfig{Type: "http", Port: p, Path: "/ready", ExpectedStatus: http.StatusNoContent},
+			expected: healthCheckResult{status: "http 204", healthy: true},
+		},
+		{
+			hc:       schemas.HealthCheckConfig{Type: "http", Port: p, Path: "/health"},
+			expected: healthCheckResult{status: "http 503", healthy: false},
+		},
+		{
+			hc:       schemas.HealthCheckConfig{Type: "http", Port: p, Path: "/maintenance"},
+			expected: healthCheckResult{status: "http 302", healthy: false},
+		},
+		{
+			hc:       schemas.HealthCheckConfig{Type: "http", Port: p, Path: "/maintenance", ExpectedStatus: http.StatusFound},
+			expected: healthCheckResult{status: "http 302", healthy: true},
+		},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(requestHealthCheck(context.Background(), host, td.hc), td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+
+	server.Close()
+	if result := requestHealthCheck(context.Background(), host, schemas.HealthCheckConfig{Type: "http", Port: p}); result.healthy || result.status != "unreachable" {
+		t.Errorf("expected unreachable, got %v", result)
+	}
+}
+
+func TestCommandResult(t *testing.T) {
+	testData := []struct {
+		status   string
+		code     int64
+		expected healthCheckResult
+	}{
+		{status: "Success", code: 0, expected: healthCheckResult{status: "exit 0", healthy: true}},
+		{status: "Failed", code: 3, expected: healthCheckResult{status: "exit 3"}},
+		{status: "InProgress", code: -1, expected: healthCheckResult{status: "inprogress", pending: true}},
+		{status: "Pending", code: -1, expected: healthCheckResult{status: "pending", pending: true}},
+		{status: "TimedOut", code: -1, expected: healthCheckResult{status: "timedout"}},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(commandResult(td.status, td.code), td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestCountLaunchFailures(t *testing.T) {
+	now := time.Now()
+	activity := func(status, message string, startTime time.Time) *autoscaling.Activity {
+		return &autoscaling.Activity{StatusCode: eaws.String(status), StatusMessage: eaws.String(message), StartTime: eaws.Time(startTime)}
+	}
+
+	testData := []struct {
+		activities []*autoscaling.Activity
+		failures   int
+		message    string
+	}{
+		{
+			activities: nil,
+			failures:   0,
+		},
+		{
+			activities: []*autoscaling.Activity{
+				activity("Failed", "We currently do not have sufficient capacity", now),
+				activity("Failed", "Invalid IAM Instance Profile", now),
+				activity("Successful", "", now),
+				activity("Failed", "old failure", now),
+			},
+			failures: 2,
+			message:  "We currently do not have sufficient capacity",
+		},
+		{
+			activities: []*autoscaling.Activity{
+				activity("InProgress", "", now),
+				activity("Failed", "The image id does not exist", now),
+				activity("Cancelled", "canceled", now),
+			},
+			failures: 2,
+			message:  "The image id does not exist",
+		},
+		{
+			activities: []*autoscaling.Activity{
+				activity("Failed", "No default subnet", now),
+				activity("Failed", "failure of previous deployment", now.Add(-time.Hour)),
+			},
+			failures: 1,
+			message:  "No default subnet",
+		},
+	}
+
+	for _, td := range testData {
+		failures, message := countLaunchFailures(td.activities, now.Add(-time.Minute))
+		if failures != td.failures || message != td.message {
+			t.Errorf("expected %d(%s), got %d(%s)", td.failures, td.message, failures, message)
+		}
+	}
+}
+
+func TestCountHealthySuccesses(t *testing.T) {
+	d := Deployer{}
+	instances := []*autoscaling.Instance{
+		{InstanceId: eaws.String("i-1"), LifecycleState: eaws.String("InService"), HealthStatus: eaws.String("Healthy")},
+		{InstanceId: eaws.String("i-2"), LifecycleState: eaws.String("Pending"), HealthStatus: eaws.String("Healthy")},
+	}
+
+	healthy := []healthCheckResult{{status: "http 200", healthy: true}, {status: "http 200", healthy: true}}
+	unhealthy := []healthCheckResult{{status: "http 503"}, {status: "http 200", healthy: true}}
+	pending := []healthCheckResult{{status: "inprogress", pending: true}, {status: "inprogress", pending: true}}
+
+	testData := []struct {
+		results  []healthCheckResult
+		expected []bool
+	}{
+		{results: healthy, expected: []bool{false, false}},
+		{results: healthy, expected: []bool{true, false}},
+		{results: unhealthy, expected: []bool{false, false}},
+		{results: healthy, expected: []bool{false, false}},
+		{results: pending, expected: []bool{false, false}},
+		{results: healthy, expected: []bool{true, false}},
+		{results: pending, expected: []bool{true, false}},
+	}
+
+	for i, td := range testData {
+		hosts := d.countHealthySuccesses(instances, td.results, 2)
+		for j, host := range hosts {
+			if host.Valid != td.expected[j] {
+				t.Errorf("round %d: expected %t for %s, got %t", i+1, td.expected[j], host.InstanceID, host.Valid)
+			}
+		}
+	}
+}
+
+func TestCheckHealthyThreshold(t *testing.T) {
+	asg := &autoscaling.Group{
+		AvailabilityZones: eaws.StringSlice([]string{"ap-northeast-2a", "ap-northeast-2c"}),
+		Instances: []*autoscaling.Instance{
+			{InstanceId: eaws.String("i-1"), AvailabilityZone: eaws.String("ap-northeast-2a")},
+			{InstanceId: eaws.String("i-2"), AvailabilityZone: eaws.String("ap-northeast-2a")},
+			{InstanceId: eaws.String("i-3"), AvailabilityZone: eaws.String("ap-northeast-2c")},
+		},
+	}
+
+	hosts := []aws.HealthcheckHost{
+		{InstanceID: "i-1", Valid: true},
+		{InstanceID: "i-2", Valid: true},
+		{InstanceID: "i-3", Valid: false},
+	}
+
+	testData := []struct {
+		desired  int64
+		percent  int64
+		minPerAZ int64
+		required int64
+		lacking  []string
+	}{
+		{desired: 3, required: 3},
+		{desired: 3, percent: 100, required: 3},
+		{desired: 3, percent: 60, required: 2},
+		{desired: 60, percent: 95, required: 57},
+		{desired: 3, percent: 60, minPerAZ: 1, required: 2, lacking: []string{"ap-northeast-2c"}},
+		{desired: 3, percent: 60, minPerAZ: 2, required: 2, lacking: []string{"ap-northeast-2c"}},
+	}
+
+	for _, td := range testData {
+		required, lacking := checkHealthyThreshold(hosts, asg, td.desired, td.percent, td.minPerAZ)
+		if required != td.required {
+			t.Errorf("expected %d required, got %d", td.required, required)
+		}
+
+		if diff := deep.Equal(lacking, td.lacking); diff != nil {
+			t.Error(diff)
+		}
+	}
+
+	hosts[2].Valid = true
+	if _, lacking := checkHealthyThreshold(hosts, asg, 3, 60, 1); lacking != nil {
+		t.Errorf("expected every availability zone to have healthy instances, got %v", lacking)
+	}
+}
+
+func TestMergeTargetHosts(t *testing.T) {
+	instances := []*autoscaling.Instance{
+		{InstanceId: eaws.String("i-1"), LifecycleState: eaws.String("InService"), HealthStatus: eaws.String("Healthy")},
+		{InstanceId: eaws.String("i-2"), LifecycleState: eaws.String("InService"), HealthStatus: eaws.String("Healthy")},
+	}
+
+	targets := []string{"public-tg", "grpc-tg", "internal-elb"}
+	hostsByTarget := [][]aws.HealthcheckHost{
+		{
+			{InstanceID: "i-1", TargetStatus: "healthy", Valid: true},
+			{InstanceID: "i-2", TargetStatus: "healthy", Valid: true},
+		},
+		{
+			{InstanceID: "i-1", TargetStatus: "healthy", Valid: true},
+			{InstanceID: "i-2", TargetStatus: "unhealthy", Reason: "Target.FailedHealthChecks: Health checks failed"},
+		},
+		{
+			{InstanceID: "i-1", LifecycleState: "InService", Valid: true},
+		},
+	}
+
+	expected := []aws.HealthcheckHost{
+		{
+			InstanceID:     "i-1",
+			LifecycleState: "InService",
+			TargetStatus:   "public-tg=healthy, grpc-tg=healthy, internal-elb=InService",
+			HealthStatus:   "Healthy",
+			Valid:          true,
+		},
+		{
+			InstanceID:     "i-2",
+			LifecycleState: "InService",
+			TargetStatus:   "public-tg=healthy, grpc-tg=unhealthy, internal-elb=Not Found",
+			HealthStatus:   "Healthy",
+			Reason:         "grpc-tg Target.FailedHealthChecks: Health checks failed",
+			Valid:          false,
+		},
+	}
+
+	if diff := deep.Equal(mergeTargetHosts(instances, targets, hostsByTarget, 0), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	testData := []struct {
+		quorum   int
+		expected []bool
+	}{
+		{quorum: 1, expected: []bool{true, true}},
+		{quorum: 2, expected: []bool{true, false}},
+		{quorum: 3, expected: []bool{true, false}},
+		{quorum: 4, expected: []bool{true, false}},
+	}
+
+	for _, td := range testData {
+		for i, host := range mergeTargetHosts(instances, targets, hostsByTarget, td.quorum) {
+			if host.Valid != td.expected[i] {
+				t.Errorf("quorum %d: expected %t for %s, got %t", td.quorum, td.expected[i], host.InstanceID, host.Valid)
+			}
+		}
+	}
+}
+
+func TestHealthcheckTargetGroups(t *testing.T) {
+	region := schemas.RegionConfig{
+		HealthcheckTargetGroup:  "public-tg",
+		HealthcheckTargetGroups: []string{"public-tg", "grpc-tg"},
+		HealthcheckLBs:          []string{"internal-elb"},
+	}
+
+	if diff := deep.Equal(healthcheckTargetGroups(region), []string{"public-tg", "grpc-tg"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if diff := deep.Equal(healthcheckLoadBalancers(region), []string{"internal-elb"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if got := healthcheckTargetGroups(schemas.RegionConfig{}); got != nil {
+		t.Errorf("expected no target group, got %v", got)
+	}
+}
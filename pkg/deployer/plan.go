@@ -0,0 +1,366 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"text/template"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/templates"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// Plan is the list of changes which deployment would make in a region
+type Plan struct {
+	Stack                     string
+	Region                    string
+	Mode                      string
+	NewAutoScalingGroup       *NewAutoScalingGroupPlan `json:",omitempty"`
+	PreviousAutoScalingGroups []PreviousAutoScalingGroupPlan
+	LifecycleCallbacks        []string `json:",omitempty"`
+	Notes                     []string `json:",omitempty"`
+}
+
+// NewAutoScalingGroupPlan is the resolved configuration of autoscaling group to create
+type NewAutoScalingGroupPlan struct {
+	Name              string
+	LaunchTemplate    LaunchTemplatePlan
+	Capacity          schemas.Capacity
+	AvailabilityZones []string
+	Subnets           []string
+	LoadBalancers     []string
+	TargetGroups      []string
+	TargetGroupARNs   []string
+	Tags              map[string]string
+	LifecycleHooks    []string
+	ScalingPolicies   []string
+	Alarms            []string
+	ScheduledActions  []string
+}
+
+// LaunchTemplatePlan is the resolved configuration of launch template to create
+type LaunchTemplatePlan struct {
+	Name               string
+	Ami                string
+	InstanceType       string
+	SSHKey             string
+	IamInstanceProfile string
+	EbsOptimized       bool
+	SecurityGroupIDs   []string
+	BlockDevices       []string
+	UserdataSHA256     string
+}
+
+// PreviousAutoScalingGroupPlan is the change which would be applied to the previous autoscaling group
+type PreviousAutoScalingGroupPlan struct {
+	Name            string
+	Action          string
+	Capacity        schemas.Capacity
+	InstanceCount   int
+	ScalingPolicies []string
+	LifecycleHooks  []string
+}
+
+// PlanDeployment resolves what Deploy would create and remove in each region without changing anything
+func (d *Deployer) PlanDeployment(config schemas.Config) ([]Plan, error) {
+	var plans []Plan
+	provider := builder.SetUserdataProvider(d.Stack.Userdata, d.AwsConfig.Userdata)
+	userdata, err := provider.Provide()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, region := range d.Stack.Regions {
+		if config.Region != constants.EmptyString && config.Region != region.Region {
+			d.Logger.Debugf("This region is skipped by user: %s", region.Region)
+			continue
+		}
+
+		newAsg, err := d.planNewAutoScalingGroup(config, region, userdata)
+		if err != nil {
+			return nil, err
+		}
+
+		prevAsgs, err := d.planPreviousAutoScalingGroups(region.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		plan := Plan{
+			Stack:                     d.Stack.Stack,
+			Region:                    region.Region,
+			Mode:                      d.Mode,
+			NewAutoScalingGroup:       newAsg,
+			PreviousAutoScalingGroups: prevAsgs,
+			LifecycleCallbacks:        d.planLifecycleCallbacks(region.Region),
+		}
+
+		switch d.Mode {
+		case constants.CanaryDeployment:
+			if config.CompleteCanary {
+				plan.Notes = append(plan.Notes, "canary load balancer, target group and security groups will be deleted after the new version is healthy")
+			} else {
+				plan.Notes = append(plan.Notes, "canary load balancer, target group and security groups will be created or reused")
+				plan.Notes = append(plan.Notes, fmt.Sprintf("capacity of %s will be reduced by one", d.LatestAsg[region.Region]))
+			}
+		case constants.RollingUpdateDeployment:
+			plan.Notes = append(plan.Notes, fmt.Sprintf("instances will be replaced by %d at once", d.Stack.RollingUpdateInstanceCount))
+		case constants.BlueGreenDeployment:
+			if d.Stack.TerminationDelayRate > 0 {
+				plan.Notes = append(plan.Notes, fmt.Sprintf("previous versions will be reduced by %d%% at once", d.Stack.TerminationDelayRate))
+			}
+		}
+
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+// PlanDeletion resolves what Delete would remove in each region without changing anything
+func (d *Deployer) PlanDeletion(config schemas.Config) ([]Plan, error) {
+	var plans []Plan
+	for _, region := range d.Stack.Regions {
+		if config.Region != constants.EmptyString && config.Region != region.Region {
+			d.Logger.Debugf("This region is skipped by user: %s", region.Region)
+			continue
+		}
+
+		prevAsgs, err := d.planPreviousAutoScalingGroups(region.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		plans = append(plans, Plan{
+			Stack:                     d.Stack.Stack,
+			Region:                    region.Region,
+			Mode:                      d.Mode,
+			PreviousAutoScalingGroups: prevAsgs,
+			LifecycleCallbacks:        d.planLifecycleCallbacks(region.Region),
+		})
+	}
+
+	return plans, nil
+}
+
+// planNewAutoScalingGroup resolves the same values as Deploy does before creating resources
+func (d *Deployer) planNewAutoScalingGroup(config schemas.Config, region schemas.RegionConfig, userdata string) (*NewAutoScalingGroupPlan, error) {
+	client, err := selectClientFromList(d.AWSClients, region.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := tool.BuildPrefixName(d.AwsConfig.Name, d.Stack.Env, region.Region)
+	newAsgName := tool.GenerateAsgName(prefix, getCurrentVersion(d.PrevVersions[region.Region]))
+
+	ami := region.AmiID
+	if len(config.Ami) > 0 {
+		ami = config.Ami
+	}
+
+	instanceType := region.InstanceType
+	if len(config.OverrideInstanceType) > 0 {
+		instanceType = config.OverrideInstanceType
+	}
+
+	securityGroups, err := client.EC2Service.GetSecurityGroupList(region.VPC, region.SecurityGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockDevices []string
+	for _, block := range d.Stack.BlockDevices {
+		blockDevices = append(blockDevices, fmt.Sprintf("%s(%s, %dGB)", block.DeviceName, block.VolumeType, block.VolumeSize))
+	}
+
+	loadBalancers := d.GetLoadBalancerNames(region)
+
+	targetGroups := d.GetTargetGroupNames(region)
+	targetGroupARNs, err := client.ELBV2Service.GetTargetGroupARNs(targetGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	availabilityZones, err := client.EC2Service.GetAvailabilityZones(region.VPC, region.AvailabilityZones)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := region.SubnetIDs
+	if len(subnets) == 0 {
+		subnets, err = client.EC2Service.GetSubnets(region.VPC, region.UsePublicSubnets, availabilityZones)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	capacity, err := d.DecideCapacity(config.ForceManifestCapacity, config.CompleteCanary, region.Region, len(d.PrevAsgs[region.Region]), d.Stack.RollingUpdateInstanceCount)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for _, tag := range d.GenerateTags(newAsgName, d.Stack.Stack, config.ExtraTags, config.AnsibleExtraVars, region.Region) {
+		tags[*tag.Key] = *tag.Value
+	}
+
+	var lifecycleHooks []string
+	if d.Stack.LifecycleHooks != nil {
+		for _, hook := range client.EC2Service.GenerateLifecycleHooks(*d.Stack.LifecycleHooks) {
+			lifecycleHooks = append(lifecycleHooks, fmt.Sprintf("%s(%s)", *hook.LifecycleHookName, *hook.LifecycleTransition))
+		}
+	}
+
+	var scalingPolicies []string
+	var alarms []string
+	if len(d.Stack.Autoscaling) > 0 {
+		for _, policy := range d.Stack.Autoscaling {
+			scalingPolicies = append(scalingPolicies, policy.Name)
+		}
+		alarms = aws.GenerateAlarmNames(newAsgName, d.Stack.Alarms)
+	}
+
+	var scheduledActions []string
+	for _, sa := range d.AwsConfig.ScheduledActions {
+		if tool.IsStringInArray(sa.Name, region.ScheduledActions) {
+			scheduledActions = append(scheduledActions, fmt.Sprintf("%s(%s)", sa.Name, sa.Recurrence))
+		}
+	}
+
+	return &NewAutoScalingGroupPlan{
+		Name: newAsgName,
+		LaunchTemplate: LaunchTemplatePlan{
+			Name:               tool.GenerateLcName(newAsgName),
+			Ami:                ami,
+			InstanceType:       instanceType,
+			SSHKey:             region.SSHKey,
+			IamInstanceProfile: d.Stack.IamInstanceProfile,
+			EbsOptimized:       d.Stack.EbsOptimized,
+			SecurityGroupIDs:   eaws.StringValueSlice(securityGroups),
+			BlockDevices:       blockDevices,
+			UserdataSHA256:     fmt.Sprintf("%x", sha256.Sum256([]byte(userdata))),
+		},
+		Capacity:          capacity,
+		AvailabilityZones: availabilityZones,
+		Subnets:           subnets,
+		LoadBalancers:     loadBalancers,
+		TargetGroups:      targetGroups,
+		TargetGroupARNs:   eaws.StringValueSlice(targetGroupARNs),
+		Tags:              tags,
+		LifecycleHooks:    lifecycleHooks,
+		ScalingPolicies:   scalingPolicies,
+		Alarms:            alarms,
+		ScheduledActions:  scheduledActions,
+	}, nil
+}
+
+// planPreviousAutoScalingGroups resolves previous autoscaling groups which would be resized and deleted
+func (d *Deployer) planPreviousAutoScalingGroups(region string) ([]PreviousAutoScalingGroupPlan, error) {
+	client, err := selectClientFromList(d.AWSClients, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []PreviousAutoScalingGroupPlan
+	for _, asg := range d.PrevAsgs[region] {
+		// the latest autoscaling group is kept during canary deployment
+		if asg == d.LatestAsg[region] && d.Mode == constants.CanaryDeployment {
+			continue
+		}
+
+		group, err := client.EC2Service.GetMatchingAutoscalingGroup(context.Background(), asg)
+		if err != nil {
+			return nil, err
+		}
+
+		policies, err := client.EC2Service.GetScalingPolicyNames(asg)
+		if err != nil {
+			return nil, err
+		}
+
+		hooks, err := client.EC2Service.GetLifecycleHookNames(asg)
+		if err != nil {
+			return nil, err
+		}
+
+		p := PreviousAutoScalingGroupPlan{
+			Name:            asg,
+			Action:          "resize to 0 and delete",
+			ScalingPolicies: policies,
+			LifecycleHooks:  hooks,
+		}
+
+		if group != nil {
+			p.Capacity = schemas.Capacity{
+				Min:     *group.MinSize,
+				Max:     *group.MaxSize,
+				Desired: *group.DesiredCapacity,
+			}
+			p.InstanceCount = len(group.Instances)
+		}
+
+		ret = append(ret, p)
+	}
+
+	return ret, nil
+}
+
+// planLifecycleCallbacks returns commands which would run on previous instances before termination
+func (d *Deployer) planLifecycleCallbacks(region string) []string {
+	if d.Stack.LifecycleCallbacks == nil || len(d.PrevInstances[region]) == 0 {
+		return nil
+	}
+
+	if d.Mode == constants.BlueGreenDeployment && d.Stack.TerminationDelayRate > 0 {
+		return nil
+	}
+
+	return d.Stack.LifecycleCallbacks.PreTerminatePastClusters
+}
+
+// PrintPlans prints deployment plans in readable format
+func PrintPlans(out io.Writer, plans []Plan) error {
+	var data = struct {
+		Plans []Plan
+	}{
+		Plans: plans,
+	}
+
+	funcMap := template.FuncMap{
+		"decorate":   tool.DecorateAttr,
+		"joinString": tool.JoinString,
+	}
+
+	w := tabwriter.NewWriter(out, 0, 5, 3, ' ', tabwriter.TabIndent)
+	t := template.Must(template.New("Deployment Plan").Funcs(funcMap).Parse(templates.DeploymentPlanTemplate))
+
+	if err := t.Execute(w, data); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,95 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"time"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+// Result is the result of deployment in a region
+type Result struct {
+	Stack                    string
+	Region                   string
+	Status                   string
+	NewAutoScalingGroup      string            `json:",omitempty"`
+	Version                  int               `json:",omitempty"`
+	Ami                      string            `json:",omitempty"`
+	Capacity                 *schemas.Capacity `json:",omitempty"`
+	Steps                    []StepResult
+	Errors                   []string `json:",omitempty"`
+	RemovedAutoScalingGroups []string `json:",omitempty"`
+	SpotInterruptions        []string `json:",omitempty"`
+}
+
+// StepResult is the timing and error of a deployment step
+type StepResult struct {
+	Name            string
+	StartedAt       time.Time
+	DurationSeconds float64
+	Error           string `json:",omitempty"`
+}
+
+// ExportResults returns resources of deployment in each region
+// Status, steps and errors are filled by the runner which runs the steps.
+func (d *Deployer) ExportResults(config schemas.Config) []Result {
+	var results []Result
+	for _, region := range d.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			continue
+		}
+
+		result := Result{
+			Stack:  d.Stack.Stack,
+			Region: region.Region,
+		}
+
+		if d.StepStatus[constants.StepDeploy] {
+			result.NewAutoScalingGroup = d.AsgNames[region.Region]
+			result.Version = getCurrentVersion(d.PrevVersions[region.Region])
+			result.Ami = region.AmiID
+			if len(config.Ami) > 0 {
+				result.Ami = config.Ami
+			}
+
+			if d.AppliedCapacity != nil {
+				capacity := *d.AppliedCapacity
+				result.Capacity = &capacity
+			}
+		}
+
+		if d.StepStatus[constants.StepCleanPreviousVersion] {
+			for _, asg := range d.PrevAsgs[region.Region] {
+				// the latest version is not removed in canary deployment
+				if d.Mode == constants.CanaryDeployment && asg == d.LatestAsg[region.Region] {
+					continue
+				}
+				result.RemovedAutoScalingGroups = append(result.RemovedAutoScalingGroups, asg)
+			}
+		}
+
+		for _, event := range d.SpotInterruptions[region.Region] {
+			result.SpotInterruptions = append(result.SpotInterruptions, event.String())
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
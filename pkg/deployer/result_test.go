@@ -0,0 +1,74 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/helper"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestExportResults(t *testing.T) {
+	h := helper.DeployerHelper{
+		Stack: schemas.Stack{
+			Stack:           "artd",
+			ReplacementType: constants.BlueGreenDeployment,
+			Regions:         []schemas.RegionConfig{{Region: "ap-northeast-2", AmiID: "ami-1234"}},
+		},
+	}
+
+	d := InitDeploymentConfiguration(&h, nil)
+	d.Stack = h.Stack
+
+	// nothing is created before deploy step
+	if diff := deep.Equal(d.ExportResults(schemas.Config{}), []Result{{Stack: "artd", Region: "ap-northeast-2"}}); diff != nil {
+		t.Error(diff)
+	}
+
+	d.StepStatus[constants.StepCheckPrevious] = true
+	d.StepStatus[constants.StepDeploy] = true
+	d.StepStatus[constants.StepCleanPreviousVersion] = true
+	d.AsgNames["ap-northeast-2"] = "hello-artd_apnortheast2-v002"
+	d.PrevAsgs["ap-northeast-2"] = []string{"hello-artd_apnortheast2-v001"}
+	d.PrevVersions["ap-northeast-2"] = []int{1}
+	d.AppliedCapacity = &schemas.Capacity{Min: 1, Max: 2, Desired: 1}
+
+	expected := []Result{
+		{
+			Stack:                    "artd",
+			Region:                   "ap-northeast-2",
+			NewAutoScalingGroup:      "hello-artd_apnortheast2-v002",
+			Version:                  2,
+			Ami:                      "ami-5678",
+			Capacity:                 &schemas.Capacity{Min: 1, Max: 2, Desired: 1},
+			RemovedAutoScalingGroups: []string{"hello-artd_apnortheast2-v001"},
+		},
+	}
+
+	if diff := deep.Equal(d.ExportResults(schemas.Config{Ami: "ami-5678"}), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	// regions skipped by user are not included
+	if results := d.ExportResults(schemas.Config{Region: "us-east-1"}); len(results) != 0 {
+		t.Errorf("expected no result, got %v", results)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/collector"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// RollbackCandidate is a previous version which can be restored by rollback
+type RollbackCandidate struct {
+	Name     string
+	Version  int
+	Retained bool
+	Status   string
+}
+
+// String returns the description of rollback candidate
+func (r RollbackCandidate) String() string {
+	if r.Retained {
+		return fmt.Sprintf("%s (retained autoscaling group)", r.Name)
+	}
+	return fmt.Sprintf("%s (recreate from record, %s)", r.Name, r.Status)
+}
+
+// GetRollbackCandidates returns previous versions which the current deployment can be rolled back to
+func (d *Deployer) GetRollbackCandidates(region string) ([]RollbackCandidate, error) {
+	client, err := selectClientFromList(d.AWSClients, region)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := tool.BuildPrefixName(d.AwsConfig.Name, d.Stack.Env, region)
+	asgGroups, err := client.EC2Service.GetAllMatchingAutoscalingGroupsWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var retained []string
+	for _, asgGroup := range asgGroups {
+		retained = append(retained, *asgGroup.AutoScalingGroupName)
+	}
+
+	statuses := map[string]string{}
+	if d.Collector.MetricConfig.Enabled {
+		statuses, err = d.Collector.GetDeploymentStatuses(prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return MakeRollbackCandidates(d.LatestAsg[region], retained, statuses), nil
+}
+
+// MakeRollbackCandidates merges retained autoscaling groups and deployment records into rollback candidates
+// Records which have never been deployed or are already rolled back are excluded.
+func MakeRollbackCandidates(current string, retained []string, statuses map[string]string) []RollbackCandidate {
+	var candidates []RollbackCandidate
+	for _, asg := range retained {
+		if asg == current {
+			continue
+		}
+		candidates = append(candidates, RollbackCandidate{
+			Name:     asg,
+			Version:  tool.ParseAutoScalingVersion(asg),
+			Retained: true,
+			Status:   statuses[asg],
+		})
+	}
+
+	for asg, status := range statuses {
+		if asg == current || tool.IsStringInArray(asg, retained) {
+			continue
+		}
+
+		if status != "deployed" && status != "terminated" {
+			continue
+		}
+
+		candidates = append(candidates, RollbackCandidate{
+			Name:    asg,
+			Version: tool.ParseAutoScalingVersion(asg),
+			Status:  status,
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Version > candidates[j].Version
+	})
+
+	return candidates
+}
+
+// FindRollbackCandidate finds the candidate with version like v012 or 12
+func FindRollbackCandidate(candidates []RollbackCandidate, target string) (*RollbackCandidate, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(target), "v"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version format: %s", target)
+	}
+
+	for i := range candidates {
+		if candidates[i].Version == version {
+			return &candidates[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("version %s cannot be restored", target)
+}
+
+// RestoreVersion makes the target version serve as the new autoscaling group of the region
+// Retained autoscaling group is scaled up again, otherwise a new version is created from the deployment record.
+func (d *Deployer) RestoreVersion(config schemas.Config, region string, target RollbackCandidate) error {
+	current := d.LatestAsg[region]
+	if len(current) == 0 {
+		return fmt.Errorf("no autoscaling group exists to roll back in %s", region)
+	}
+
+	currentAsg, err := d.DescribeAutoScalingGroup(current, region)
+	if err != nil {
+		return err
+	}
+
+	capacity := schemas.Capacity{
+		Min:     *currentAsg.MinSize,
+		Max:     *currentAsg.MaxSize,
+		Desired: *currentAsg.DesiredCapacity,
+	}
+	if capacity.Desired == 0 {
+		capacity = d.Stack.Capacity
+	}
+
+	d.Logger.Infof("Start rolling back from %s to %s", current, target.Name)
+	d.Slack.SendSimpleMessage(fmt.Sprintf(":rewind: Start rolling back from %s to %s", current, target.Name))
+
+	if target.Retained {
+		if err := d.ResizingAutoScalingGroup(target.Name, region, capacity); err != nil {
+			return err
+		}
+		d.AsgNames[region] = target.Name
+		d.AppliedCapacity = &capacity
+	} else {
+		if !d.Collector.MetricConfig.Enabled {
+			return fmt.Errorf("deployment record of %s is needed to recreate it, but metrics are disabled", target.Name)
+		}
+
+		record, err := d.Collector.GetDeploymentRecord(target.Name)
+		if err != nil {
+			return err
+		}
+
+		regionConfig, err := d.applyDeploymentRecord(record, region)
+		if err != nil {
+			return err
+		}
+
+		config.Ami = record.Config.Ami
+		config.OverrideInstanceType = record.Config.OverrideInstanceType
+		config.ForceManifestCapacity = false
+		d.PrevInstanceCount[region] = capacity
+
+		if err := d.Deploy(config, *regionConfig); err != nil {
+			return err
+		}
+	}
+
+	// every other version including the current one is drained after health check
+	var prevAsgs []string
+	for _, asg := range d.PrevAsgs[region] {
+		if asg != d.AsgNames[region] {
+			prevAsgs = append(prevAsgs, asg)
+		}
+	}
+	d.PrevAsgs[region] = prevAsgs
+
+	var prevInstances []string
+	for _, instance := range currentAsg.Instances {
+		prevInstances = append(prevInstances, *instance.InstanceId)
+	}
+	d.PrevInstances[region] = prevInstances
+
+	return nil
+}
+
+// applyDeploymentRecord replaces stack configuration with the one stored in deployment record
+func (d *Deployer) applyDeploymentRecord(record *collector.DeploymentRecord, region string) (*schemas.RegionConfig, error) {
+	if len(record.Userdata) == 0 {
+		return nil, fmt.Errorf("no userdata is stored in the deployment record of %s", record.Name)
+	}
+
+	var regionConfig *schemas.RegionConfig
+	for i := range record.Stack.Regions {
+		if record.Stack.Regions[i].Region == region {
+			regionConfig = &record.Stack.Regions[i]
+			break
+		}
+	}
+
+	if regionConfig == nil {
+		return nil, fmt.Errorf("no region configuration of %s exists in the deployment record of %s", region, record.Name)
+	}
+
+	stack := record.Stack
+	stack.ReplacementType = d.Mode
+	stack.AssumeRole = d.Stack.AssumeRole
+	stack.Regions = d.Stack.Regions
+	d.Stack = stack
+	d.LocalProvider = builder.StaticProvider{Userdata: record.Userdata}
+
+	return regionConfig, nil
+}
+
+// FinishRollback marks the version which was rolled back from in the metric table
+func (d *Deployer) FinishRollback(config schemas.Config) {
+	if !d.Collector.MetricConfig.Enabled {
+		return
+	}
+
+	for _, region := range d.Stack.Regions {
+		if config.Region != constants.EmptyString && config.Region != region.Region {
+			continue
+		}
+
+		if err := d.Collector.UpdateStatus(d.LatestAsg[region.Region], constants.StatusRolledBack, nil); err != nil {
+			d.Logger.Errorf(err.Error())
+		}
+	}
+}
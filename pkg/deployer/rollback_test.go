@@ -0,0 +1,88 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestMakeRollbackCandidates(t *testing.T) {
+	current := "hello-dev_apnortheast2-v003"
+	retained := []string{
+		"hello-dev_apnortheast2-v002",
+		"hello-dev_apnortheast2-v003",
+	}
+	statuses := map[string]string{
+		"hello-dev_apnortheast2-v000": "terminated",
+		"hello-dev_apnortheast2-v001": "rolled_back",
+		"hello-dev_apnortheast2-v002": "deployed",
+		"hello-dev_apnortheast2-v003": "deployed",
+		"hello-dev_apnortheast2-v004": "creating",
+	}
+
+	expected := []RollbackCandidate{
+		{
+			Name:     "hello-dev_apnortheast2-v002",
+			Version:  2,
+			Retained: true,
+			Status:   "deployed",
+		},
+		{
+			Name:    "hello-dev_apnortheast2-v000",
+			Version: 0,
+			Status:  "terminated",
+		},
+	}
+
+	if diff := deep.Equal(MakeRollbackCandidates(current, retained, statuses), expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestFindRollbackCandidate(t *testing.T) {
+	candidates := []RollbackCandidate{
+		{Name: "hello-dev_apnortheast2-v012", Version: 12, Retained: true},
+		{Name: "hello-dev_apnortheast2-v010", Version: 10},
+	}
+
+	testData := []struct {
+		Input    string
+		Expected string
+		HasError bool
+	}{
+		{Input: "v012", Expected: "hello-dev_apnortheast2-v012"},
+		{Input: "10", Expected: "hello-dev_apnortheast2-v010"},
+		{Input: "v011", HasError: true},
+		{Input: "latest", HasError: true},
+	}
+
+	for _, td := range testData {
+		output, err := FindRollbackCandidate(candidates, td.Input)
+		if td.HasError {
+			if err == nil {
+				t.Errorf("expected error for %s", td.Input)
+			}
+			continue
+		}
+
+		if err != nil || output.Name != td.Expected {
+			t.Errorf("expected: %s, output: %v, err: %v", td.Expected, output, err)
+		}
+	}
+}
@@ -17,9 +17,9 @@ limitations under the license.
 package deployer
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -69,7 +69,7 @@ func (r *RollingUpdate) GetDeployer() *Deployer {
 }
 
 // CheckPreviousResources checks if there is any previous version of autoscaling group
-func (r *RollingUpdate) CheckPreviousResources(config schemas.Config) error {
+func (r *RollingUpdate) CheckPreviousResources(ctx context.Context, config schemas.Config) error {
 	err := r.CheckPrevious(config)
 	if err != nil {
 		return err
@@ -79,7 +79,7 @@ func (r *RollingUpdate) CheckPreviousResources(config schemas.Config) error {
 }
 
 // Deploy runs deployments with rolling update approach
-func (r *RollingUpdate) Deploy(config schemas.Config) error {
+func (r *RollingUpdate) Deploy(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepCheckPrevious] {
 		return nil
 	}
@@ -103,25 +103,26 @@ func (r *RollingUpdate) Deploy(config schemas.Config) error {
 }
 
 // HealthChecking does health checking for canary deployment
-func (r *RollingUpdate) HealthChecking(config schemas.Config) error {
+func (r *RollingUpdate) HealthChecking(ctx context.Context, config schemas.Config) error {
 	healthy := false
 
 	for !healthy {
-		r.Logger.Debugf("Start Timestamp: %d, timeout: %s", config.StartTimestamp, config.Timeout)
-		isTimeout, _ := tool.CheckTimeout(config.StartTimestamp, config.Timeout)
-		if isTimeout {
-			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
+		r.Logger.Debugf("Start Timestamp: %d, timeout: %s, extension: %s", config.StartTimestamp, config.Timeout, r.HealthCheckExtension)
+		if r.HealthCheckTimeoutExceeded(config) {
+			return r.Deployer.HealthCheckTimeout(ctx, config)
 		}
 
-		isDone, err := r.Deployer.HealthChecking(config)
+		isDone, err := r.Deployer.HealthChecking(ctx, config)
 		if err != nil {
-			return errors.New("error happened while health checking")
+			return fmt.Errorf("error happened while health checking: %s", err.Error())
 		}
 
 		if isDone {
 			healthy = true
 		} else {
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -129,7 +130,7 @@ func (r *RollingUpdate) HealthChecking(config schemas.Config) error {
 }
 
 // FinishAdditionalWork processes additional work for the new deployment
-func (r *RollingUpdate) FinishAdditionalWork(config schemas.Config) error {
+func (r *RollingUpdate) FinishAdditionalWork(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepDeploy] {
 		return nil
 	}
@@ -143,7 +144,7 @@ func (r *RollingUpdate) FinishAdditionalWork(config schemas.Config) error {
 				continue
 			}
 
-			if err := r.CompleteRollingUpdate(config, region); err != nil {
+			if err := r.CompleteRollingUpdate(ctx, config, region); err != nil {
 				return err
 			}
 		}
@@ -159,7 +160,7 @@ func (r *RollingUpdate) FinishAdditionalWork(config schemas.Config) error {
 }
 
 // TriggerLifecycleCallbacks runs lifecycle callbacks before cleaning.
-func (r *RollingUpdate) TriggerLifecycleCallbacks(config schemas.Config) error {
+func (r *RollingUpdate) TriggerLifecycleCallbacks(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepAdditionalWork] {
 		return nil
 	}
@@ -171,7 +172,7 @@ func (r *RollingUpdate) TriggerLifecycleCallbacks(config schemas.Config) error {
 }
 
 // CleanPreviousVersion cleans previous version of autoscaling group or canary target group
-func (r *RollingUpdate) CleanPreviousVersion(config schemas.Config) error {
+func (r *RollingUpdate) CleanPreviousVersion(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepTriggerLifecycleCallback] {
 		return nil
 	}
@@ -182,7 +183,7 @@ func (r *RollingUpdate) CleanPreviousVersion(config schemas.Config) error {
 }
 
 // GatherMetrics gathers the whole metrics from deployer
-func (r *RollingUpdate) GatherMetrics(config schemas.Config) error {
+func (r *RollingUpdate) GatherMetrics(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepCleanChecking] {
 		return nil
 	}
@@ -210,7 +211,7 @@ func (r *RollingUpdate) GatherMetrics(config schemas.Config) error {
 }
 
 // RunAPITest tries to run API Test
-func (r *RollingUpdate) RunAPITest(config schemas.Config) error {
+func (r *RollingUpdate) RunAPITest(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepGatherMetrics] {
 		return nil
 	}
@@ -230,7 +231,7 @@ func (r *RollingUpdate) RunAPITest(config schemas.Config) error {
 }
 
 // CleanChecking checks Termination status
-func (r *RollingUpdate) CleanChecking(config schemas.Config) error {
+func (r *RollingUpdate) CleanChecking(ctx context.Context, config schemas.Config) error {
 	if !r.StepStatus[constants.StepCleanPreviousVersion] {
 		return nil
 	}
@@ -242,7 +243,7 @@ func (r *RollingUpdate) CleanChecking(config schemas.Config) error {
 			return fmt.Errorf("timeout has been exceeded : %.0f minutes", config.Timeout.Minutes())
 		}
 
-		isDone, err := r.Deployer.CleanChecking(config)
+		isDone, err := r.Deployer.CleanChecking(ctx, config)
 		if err != nil {
 			return errors.New("error happened while health checking")
 		}
@@ -251,7 +252,9 @@ func (r *RollingUpdate) CleanChecking(config schemas.Config) error {
 			done = true
 		} else {
 			r.Logger.Info("All stacks are not ready to be terminated... Please waiting...")
-			time.Sleep(config.PollingInterval)
+			if err := tool.SleepWithContext(ctx, config.PollingInterval); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -259,8 +262,13 @@ func (r *RollingUpdate) CleanChecking(config schemas.Config) error {
 	return nil
 }
 
+// Rollback removes the new autoscaling group and restores the capacity of the previous version
+func (r *RollingUpdate) Rollback(ctx context.Context, config schemas.Config) error {
+	return r.RollbackDeployment(ctx, config)
+}
+
 // CompleteRollingUpdate processes the whole process of rolling update
-func (r *RollingUpdate) CompleteRollingUpdate(config schemas.Config, region schemas.RegionConfig) error {
+func (r *RollingUpdate) CompleteRollingUpdate(ctx context.Context, config schemas.Config, region schemas.RegionConfig) error {
 	latestASG, ok := r.LatestAsg[region.Region]
 	if !ok {
 		return nil
@@ -304,7 +312,7 @@ func (r *RollingUpdate) CompleteRollingUpdate(config schemas.Config, region sche
 		// settings for health checking
 		r.AppliedCapacity = &appliedCapacity
 
-		if err := r.HealthChecking(config); err != nil {
+		if err := r.HealthChecking(ctx, config); err != nil {
 			return err
 		}
 	}
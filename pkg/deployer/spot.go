@@ -0,0 +1,252 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+var (
+	// instanceIDRegex finds instance ID in the description of scaling activity
+	instanceIDRegex = regexp.MustCompile(`i-[0-9a-f]+`)
+
+	// spotStateReasons is a list of state reason codes of instances which are reclaimed by EC2
+	spotStateReasons = []string{"Server.SpotInstanceTermination", "Server.SpotInstanceShutdown"}
+)
+
+// SpotInterruption is a spot instance of the new autoscaling group which is interrupted or recommended to be rebalanced
+type SpotInterruption struct {
+	InstanceID string
+	Event      string
+	Time       time.Time
+	Cause      string
+}
+
+// String returns the summary of spot interruption
+func (s SpotInterruption) String() string {
+	return fmt.Sprintf("%s %s at %s", s.InstanceID, strings.ReplaceAll(s.Event, "_", " "), s.Time.Format(time.RFC3339))
+}
+
+// usesSpot returns true if the stack launches spot instances
+func usesSpot(stack schemas.Stack) bool {
+	if stack.MixedInstancesPolicy.Enabled && stack.MixedInstancesPolicy.OnDemandPercentage < 100 {
+		return true
+	}
+
+	return stack.InstanceMarketOptions != nil && stack.InstanceMarketOptions.MarketType == "spot"
+}
+
+// CheckSpotInterruptions finds spot interruptions and rebalance recommendations of instances in the autoscaling group
+// They are reported separately from failures of the application, and each of them extends the health check timeout
+// so that the autoscaling group can replace the instance.
+func (d *Deployer) CheckSpotInterruptions(ctx context.Context, client aws.Client, region, asg string, config schemas.Config) error {
+	if !usesSpot(d.Stack) {
+		return nil
+	}
+
+	activities, err := client.EC2Service.DescribeScalingActivities(ctx, asg)
+	if err != nil {
+		return err
+	}
+
+	events, terminated := findSpotEvents(activities, time.Unix(config.StartTimestamp, 0))
+
+	// instances terminated without spot related cause are checked with the state reason only once
+	terminated = d.uncheckedTerminations(region, terminated)
+
+	var instanceIds []*string
+	for _, t := range terminated {
+		instanceIds = append(instanceIds, eaws.String(t.InstanceID))
+	}
+
+	if len(instanceIds) > 0 {
+		reasons, err := client.EC2Service.GetInstanceStateReasons(ctx, instanceIds)
+		if err != nil {
+			d.Logger.Warnf("failed to get state reasons of terminated instances: %s", err.Error())
+		}
+
+		if d.CheckedTerminations == nil {
+			d.CheckedTerminations = map[string]bool{}
+		}
+
+		for _, t := range terminated {
+			code, ok := reasons[t.InstanceID]
+			if !ok {
+				continue
+			}
+			d.CheckedTerminations[t.InstanceID] = true
+
+			if tool.IsStringInArray(code, spotStateReasons) {
+				t.Event = constants.SpotInterruptionEvent
+				t.Cause = code
+				events = append(events, t)
+			}
+		}
+	}
+
+	for _, event := range events {
+		if d.hasSpotEvent(region, event) {
+			continue
+		}
+		d.reportSpotEvent(ctx, client, region, asg, event, config)
+	}
+
+	return nil
+}
+
+// uncheckedTerminations returns terminated instances which are neither reported nor checked with the state reason yet
+func (d *Deployer) uncheckedTerminations(region string, terminated []SpotInterruption) []SpotInterruption {
+	var ret []SpotInterruption
+	for _, t := range terminated {
+		if d.CheckedTerminations[t.InstanceID] || d.hasSpotEvent(region, SpotInterruption{InstanceID: t.InstanceID, Event: constants.SpotInterruptionEvent}) {
+			continue
+		}
+		ret = append(ret, t)
+	}
+
+	return ret
+}
+
+// hasSpotEvent returns true if the spot event is already reported
+func (d *Deployer) hasSpotEvent(region string, event SpotInterruption) bool {
+	for _, e := range d.SpotInterruptions[region] {
+		if e.InstanceID == event.InstanceID && e.Event == event.Event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportSpotEvent records the spot event, extends the health check timeout and falls back to on-demand if it is enabled
+func (d *Deployer) reportSpotEvent(ctx context.Context, client aws.Client, region, asg string, event SpotInterruption, config schemas.Config) {
+	if d.SpotInterruptions == nil {
+		d.SpotInterruptions = map[string][]SpotInterruption{}
+	}
+	d.SpotInterruptions[region] = append(d.SpotInterruptions[region], event)
+
+	grace := constants.DefaultSpotInterruptionGracePeriod
+	maxExtension := config.Timeout
+	if sc := d.Stack.SpotInterruption; sc != nil {
+		if sc.GracePeriod > 0 {
+			grace = sc.GracePeriod
+		}
+
+		if sc.MaxExtension > 0 {
+			maxExtension = sc.MaxExtension
+		}
+	}
+
+	d.HealthCheckExtension = extendHealthCheck(d.HealthCheckExtension, grace, maxExtension)
+
+	message := fmt.Sprintf("spot %s of %s : %s, health check timeout is extended by %s", strings.ReplaceAll(event.Event, "_", " "), asg, event.InstanceID, tool.RoundTime(d.HealthCheckExtension))
+	d.Logger.Warnf("%s (%s)", message, event.Cause)
+	d.Slack.SendSimpleMessage(fmt.Sprintf(":zap: %s", message))
+
+	if d.Stack.SpotInterruption == nil || !d.Stack.SpotInterruption.FallbackToOnDemand || d.OnDemandFallback[region] {
+		return
+	}
+
+	if err := client.EC2Service.SetOnDemandPercentage(ctx, asg, 100); err != nil {
+		d.Logger.Errorf("failed to fall back to on-demand instances: %s", err.Error())
+		return
+	}
+
+	if d.OnDemandFallback == nil {
+		d.OnDemandFallback = map[string]bool{}
+	}
+	d.OnDemandFallback[region] = true
+
+	d.Logger.Warnf("%s launches only on-demand instances for the rest of the deployment", asg)
+	d.Slack.SendSimpleMessage(fmt.Sprintf(":zap: %s launches only on-demand instances for the rest of the deployment", asg))
+}
+
+// HealthCheckTimeoutExceeded returns true if health checking exceeds the timeout with extension by spot interruptions
+func (d *Deployer) HealthCheckTimeoutExceeded(config schemas.Config) bool {
+	isTimeout, _ := tool.CheckTimeout(config.StartTimestamp, config.Timeout+d.HealthCheckExtension)
+	return isTimeout
+}
+
+// countSpotInterruptions returns the number of spot events in all regions
+func (d *Deployer) countSpotInterruptions() int {
+	count := 0
+	for _, events := range d.SpotInterruptions {
+		count += len(events)
+	}
+
+	return count
+}
+
+// extendHealthCheck returns the extension of health check timeout with the grace period up to the maximum
+func extendHealthCheck(extension, grace, maxExtension time.Duration) time.Duration {
+	extension += grace
+	if extension > maxExtension {
+		return maxExtension
+	}
+
+	return extension
+}
+
+// findSpotEvents returns spot events in scaling activities since the deployment started
+// Instances which are terminated without spot related cause are returned separately to be checked with their state reason.
+func findSpotEvents(activities []*autoscaling.Activity, since time.Time) ([]SpotInterruption, []SpotInterruption) {
+	var events, terminated []SpotInterruption
+	for _, activity := range activities {
+		if activity.StartTime != nil && activity.StartTime.Before(since) {
+			break
+		}
+
+		// launches in response to rebalance recommendations are reported with the terminated instance
+		description := eaws.StringValue(activity.Description)
+		instanceID := instanceIDRegex.FindString(description)
+		if !strings.HasPrefix(description, "Terminating EC2 instance") || len(instanceID) == 0 {
+			continue
+		}
+
+		event := SpotInterruption{
+			InstanceID: instanceID,
+			Time:       eaws.TimeValue(activity.StartTime),
+			Cause:      eaws.StringValue(activity.Cause),
+		}
+
+		cause := strings.ToLower(event.Cause)
+		switch {
+		case strings.Contains(cause, "rebalance recommendation"):
+			event.Event = constants.SpotRebalanceEvent
+			events = append(events, event)
+		case strings.Contains(cause, "spot instance interruption") || strings.Contains(cause, "interruption notice"):
+			event.Event = constants.SpotInterruptionEvent
+			events = append(events, event)
+		default:
+			terminated = append(terminated, event)
+		}
+	}
+
+	return events, terminated
+}
@@ -0,0 +1,129 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+	"time"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestFindSpotEvents(t *testing.T) {
+	since := time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)
+	activities := []*autoscaling.Activity{
+		{
+			Description: eaws.String("Terminating EC2 instance: i-0a1b"),
+			Cause:       eaws.String("At 2020-07-01T12:10:00Z an instance was taken out of service in response to an EC2 instance rebalance recommendation."),
+			StartTime:   eaws.Time(since.Add(10 * time.Minute)),
+		},
+		{
+			Description: eaws.String("Launching a new EC2 instance: i-0c2d"),
+			Cause:       eaws.String("At 2020-07-01T12:10:00Z an instance was launched in response to an EC2 instance rebalance recommendation."),
+			StartTime:   eaws.Time(since.Add(10 * time.Minute)),
+		},
+		{
+			Description: eaws.String("Terminating EC2 instance: i-0e3f"),
+			Cause:       eaws.String("At 2020-07-01T12:05:00Z an instance was taken out of service in response to an EC2 health check indicating it has been terminated or stopped."),
+			StartTime:   eaws.Time(since.Add(5 * time.Minute)),
+		},
+		{
+			Description: eaws.String("Terminating EC2 instance: i-0a4b"),
+			Cause:       eaws.String("At 2020-07-01T12:03:00Z an instance was taken out of service in response to a Spot Instance interruption notice."),
+			StartTime:   eaws.Time(since.Add(3 * time.Minute)),
+		},
+		{
+			Description: eaws.String("Terminating EC2 instance: i-0c5d"),
+			Cause:       eaws.String("At 2020-07-01T11:50:00Z an instance was taken out of service in response to a Spot Instance interruption notice."),
+			StartTime:   eaws.Time(since.Add(-10 * time.Minute)),
+		},
+	}
+
+	events, terminated := findSpotEvents(activities, since)
+
+	expectedEvents := []SpotInterruption{
+		{InstanceID: "i-0a1b", Event: constants.SpotRebalanceEvent, Time: since.Add(10 * time.Minute), Cause: eaws.StringValue(activities[0].Cause)},
+		{InstanceID: "i-0a4b", Event: constants.SpotInterruptionEvent, Time: since.Add(3 * time.Minute), Cause: eaws.StringValue(activities[3].Cause)},
+	}
+	if diff := deep.Equal(events, expectedEvents); diff != nil {
+		t.Error(diff)
+	}
+
+	expectedTerminated := []SpotInterruption{
+		{InstanceID: "i-0e3f", Time: since.Add(5 * time.Minute), Cause: eaws.StringValue(activities[2].Cause)},
+	}
+	if diff := deep.Equal(terminated, expectedTerminated); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestUncheckedTerminations(t *testing.T) {
+	d := Deployer{
+		SpotInterruptions: map[string][]SpotInterruption{
+			"ap-northeast-2": {{InstanceID: "i-0a1", Event: constants.SpotInterruptionEvent}},
+		},
+		CheckedTerminations: map[string]bool{"i-0b2": true},
+	}
+
+	terminated := []SpotInterruption{{InstanceID: "i-0a1"}, {InstanceID: "i-0b2"}, {InstanceID: "i-0c3"}}
+	expected := []SpotInterruption{{InstanceID: "i-0c3"}}
+	if diff := deep.Equal(d.uncheckedTerminations("ap-northeast-2", terminated), expected); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestExtendHealthCheck(t *testing.T) {
+	testData := []struct {
+		extension    time.Duration
+		grace        time.Duration
+		maxExtension time.Duration
+		expected     time.Duration
+	}{
+		{extension: 0, grace: 5 * time.Minute, maxExtension: 20 * time.Minute, expected: 5 * time.Minute},
+		{extension: 10 * time.Minute, grace: 5 * time.Minute, maxExtension: 20 * time.Minute, expected: 15 * time.Minute},
+		{extension: 18 * time.Minute, grace: 5 * time.Minute, maxExtension: 20 * time.Minute, expected: 20 * time.Minute},
+	}
+
+	for _, td := range testData {
+		if got := extendHealthCheck(td.extension, td.grace, td.maxExtension); got != td.expected {
+			t.Errorf("expected %s, got %s", td.expected, got)
+		}
+	}
+}
+
+func TestUsesSpot(t *testing.T) {
+	testData := []struct {
+		stack    schemas.Stack
+		expected bool
+	}{
+		{stack: schemas.Stack{}, expected: false},
+		{stack: schemas.Stack{MixedInstancesPolicy: schemas.MixedInstancesPolicy{Enabled: true, OnDemandPercentage: 20}}, expected: true},
+		{stack: schemas.Stack{MixedInstancesPolicy: schemas.MixedInstancesPolicy{Enabled: true, OnDemandPercentage: 100}}, expected: false},
+		{stack: schemas.Stack{InstanceMarketOptions: &schemas.InstanceMarketOptions{MarketType: "spot"}}, expected: true},
+	}
+
+	for _, td := range testData {
+		if got := usesSpot(td.stack); got != td.expected {
+			t.Errorf("expected %t, got %t", td.expected, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// ExportState returns a copy of step status and resources of deployer
+// Maps are copied because the state is saved while other deployers are running.
+func (d *Deployer) ExportState() state.StackState {
+	ss := state.StackState{
+		Stack:             d.Stack,
+		StepStatus:        map[int64]bool{},
+		AsgNames:          copyStringMap(d.AsgNames),
+		PrevAsgs:          copyStringSliceMap(d.PrevAsgs),
+		PrevInstances:     copyStringSliceMap(d.PrevInstances),
+		PrevVersions:      map[string][]int{},
+		PrevInstanceCount: map[string]schemas.Capacity{},
+		SecurityGroup:     map[string]*string{},
+		LatestAsg:         copyStringMap(d.LatestAsg),
+		DeploymentFlag:    copyStringMap(d.DeploymentFlag),
+	}
+
+	for step, done := range d.StepStatus {
+		ss.StepStatus[step] = done
+	}
+
+	for region, versions := range d.PrevVersions {
+		ss.PrevVersions[region] = append([]int{}, versions...)
+	}
+
+	for region, capacity := range d.PrevInstanceCount {
+		ss.PrevInstanceCount[region] = capacity
+	}
+
+	for region, sg := range d.SecurityGroup {
+		ss.SecurityGroup[region] = sg
+	}
+
+	if d.AppliedCapacity != nil {
+		capacity := *d.AppliedCapacity
+		ss.AppliedCapacity = &capacity
+	}
+
+	return ss
+}
+
+// ImportState restores step status and resources of deployer from saved state
+func (d *Deployer) ImportState(ss state.StackState) {
+	for step, done := range ss.StepStatus {
+		d.StepStatus[step] = done
+	}
+
+	for region, name := range ss.AsgNames {
+		d.AsgNames[region] = name
+	}
+
+	for region, asgs := range ss.PrevAsgs {
+		d.PrevAsgs[region] = asgs
+	}
+
+	for region, instances := range ss.PrevInstances {
+		d.PrevInstances[region] = instances
+	}
+
+	for region, versions := range ss.PrevVersions {
+		d.PrevVersions[region] = versions
+	}
+
+	for region, capacity := range ss.PrevInstanceCount {
+		d.PrevInstanceCount[region] = capacity
+	}
+
+	for region, sg := range ss.SecurityGroup {
+		d.SecurityGroup[region] = sg
+	}
+
+	for region, asg := range ss.LatestAsg {
+		d.LatestAsg[region] = asg
+	}
+
+	for region, flag := range ss.DeploymentFlag {
+		d.DeploymentFlag[region] = flag
+	}
+
+	d.AppliedCapacity = ss.AppliedCapacity
+}
+
+// CleanIncompleteDeployment removes the new autoscaling group and launch template of deploy step which was not finished
+func (d *Deployer) CleanIncompleteDeployment(ctx context.Context, config schemas.Config) error {
+	// nothing is created before previous versions are checked
+	if !d.StepStatus[constants.StepCheckPrevious] {
+		return nil
+	}
+
+	for _, region := range d.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			d.Logger.Debug("This region is skipped by user : " + region.Region)
+			continue
+		}
+
+		client, err := selectClientFromList(d.AWSClients, region.Region)
+		if err != nil {
+			return err
+		}
+
+		prefix := tool.BuildPrefixName(d.AwsConfig.Name, d.Stack.Env, region.Region)
+		newAsg := d.AsgNames[region.Region]
+		if len(newAsg) == 0 {
+			newAsg = tool.GenerateAsgName(prefix, getCurrentVersion(d.PrevVersions[region.Region]))
+		}
+
+		asgGroups, err := client.EC2Service.GetAllMatchingAutoscalingGroupsWithPrefix(prefix)
+		if err != nil {
+			return err
+		}
+
+		created := false
+		for _, group := range asgGroups {
+			if *group.AutoScalingGroupName == newAsg {
+				created = true
+				break
+			}
+		}
+
+		if created {
+			// autoscaling group is removed by rollback below
+			d.AsgNames[region.Region] = newAsg
+			continue
+		}
+
+		delete(d.AsgNames, region.Region)
+		d.Logger.Infof("Start deleting launch templates of incomplete deployment : %s", newAsg)
+		if err := client.EC2Service.DeleteLaunchTemplates(newAsg); err != nil {
+			return err
+		}
+	}
+
+	return d.RollbackDeployment(ctx, config)
+}
+
+// copyStringMap returns a copy of map
+func copyStringMap(src map[string]string) map[string]string {
+	dst := map[string]string{}
+	for k, v := range src {
+		dst[k] = v
+	}
+
+	return dst
+}
+
+// copyStringSliceMap returns a copy of map with slice values
+func copyStringSliceMap(src map[string][]string) map[string][]string {
+	dst := map[string][]string{}
+	for k, v := range src {
+		dst[k] = append([]string{}, v...)
+	}
+
+	return dst
+}
@@ -0,0 +1,59 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/helper"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestExportImportState(t *testing.T) {
+	h := helper.DeployerHelper{
+		Stack: schemas.Stack{Stack: "artd", ReplacementType: constants.BlueGreenDeployment},
+	}
+
+	src := InitDeploymentConfiguration(&h, nil)
+	src.StepStatus[constants.StepCheckPrevious] = true
+	src.StepStatus[constants.StepDeploy] = true
+	src.AsgNames["ap-northeast-2"] = "hello-dev_apnortheast2-v002"
+	src.LatestAsg["ap-northeast-2"] = "hello-dev_apnortheast2-v001"
+	src.PrevAsgs["ap-northeast-2"] = []string{"hello-dev_apnortheast2-v001"}
+	src.PrevVersions["ap-northeast-2"] = []int{1}
+	src.PrevInstanceCount["ap-northeast-2"] = schemas.Capacity{Min: 1, Max: 2, Desired: 1}
+	src.AppliedCapacity = &schemas.Capacity{Min: 1, Max: 2, Desired: 1}
+
+	ss := src.ExportState()
+
+	// exported state must not be changed by the running deployer
+	src.StepStatus[constants.StepAdditionalWork] = true
+	src.PrevAsgs["ap-northeast-2"][0] = "changed"
+	if ss.StepStatus[constants.StepAdditionalWork] || ss.PrevAsgs["ap-northeast-2"][0] != "hello-dev_apnortheast2-v001" {
+		t.Error("exported state shares maps with deployer")
+	}
+
+	dst := InitDeploymentConfiguration(&h, nil)
+	dst.ImportState(ss)
+
+	if diff := deep.Equal(dst.ExportState(), ss); diff != nil {
+		t.Error(diff)
+	}
+}
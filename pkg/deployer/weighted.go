@@ -0,0 +1,307 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"context"
+	"fmt"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// Weighted returns true if canary target group is added to production listeners with weights
+// instead of a separate canary load balancer
+func (c *Canary) Weighted() bool {
+	return c.Stack.Canary != nil && c.Stack.Canary.Mode == constants.CanaryWeightedMode
+}
+
+// canaryWeight returns the percentage of traffic which is forwarded to canary target group
+func (c *Canary) canaryWeight() int64 {
+	if c.Stack.Canary == nil || c.Stack.Canary.Weight == 0 {
+		return constants.DefaultCanaryWeight
+	}
+
+	return c.Stack.Canary.Weight
+}
+
+// RunWeightedCanaryDeployment runs or completes canary deployment with weighted target groups of production listeners
+// The canary target group is copied from the target group which serves production traffic now and added to its listeners
+// without traffic. Traffic is shifted to the canary after it becomes healthy.
+func (c *Canary) RunWeightedCanaryDeployment(ctx context.Context, config schemas.Config, region schemas.RegionConfig, tgDetail *elbv2.TargetGroup, canaryVersion int, latestASG string) (schemas.RegionConfig, error) {
+	if config.CompleteCanary {
+		if canaryVersion == 0 {
+			return region, fmt.Errorf("there is no canary target group of %s", latestASG)
+		}
+
+		if err := c.CompleteCanaryDeployment(config, region, latestASG); err != nil {
+			return region, err
+		}
+
+		// the canary target group keeps serving after it is promoted
+		return c.ChangeTargetGroupInfo(*tgDetail.TargetGroupName, region), nil
+	}
+
+	if c.DeploymentFlag[region.Region] == constants.CanaryDeployment {
+		return region, fmt.Errorf("canary of %s is still in progress, complete it with --complete-canary before deploying a new canary", latestASG)
+	}
+
+	client, err := selectClientFromList(c.AWSClients, region.Region)
+	if err != nil {
+		return region, err
+	}
+
+	newTgName := c.GenerateCanaryTargetGroupName(canaryVersion)
+	c.Logger.Debugf("New target group will be created for canary deployment: %s", newTgName)
+
+	tg, err := c.CopyTargetGroups(tgDetail, newTgName, region.Region)
+	if err != nil {
+		return region, err
+	}
+	c.Logger.Debugf("New target group is created: %s", *tg.TargetGroupName)
+
+	canaryArn := *tg.TargetGroupArn
+	err = c.updateForwardRules(ctx, client, *tgDetail.TargetGroupArn, func(action *elbv2.Action) (*elbv2.Action, error) {
+		return weightedForwardAction(action, canaryArn, 0), nil
+	})
+	if err != nil {
+		return region, err
+	}
+	c.Logger.Infof("Canary target group is added to listeners of %s without traffic: %s", *tgDetail.TargetGroupName, newTgName)
+
+	region = c.ChangeTargetGroupInfo(newTgName, region)
+	c.Logger.Debugf("Changed information: %s / %s", region.HealthcheckTargetGroup, region.TargetGroups)
+
+	if err := c.Deployer.Deploy(config, region); err != nil {
+		return region, err
+	}
+
+	return region, nil
+}
+
+// ShiftCanaryTraffic forwards the percentage of traffic of production listeners to the canary target group
+// If the weight is 100, the other target groups are dropped from the listeners.
+func (c *Canary) ShiftCanaryTraffic(ctx context.Context, region string, weight int64) error {
+	client, err := selectClientFromList(c.AWSClients, region)
+	if err != nil {
+		return err
+	}
+
+	canaryArn, err := c.findCanaryTargetGroup(ctx, client, region)
+	if err != nil {
+		return err
+	}
+
+	err = c.updateForwardRules(ctx, client, canaryArn, func(action *elbv2.Action) (*elbv2.Action, error) {
+		return weightedForwardAction(action, canaryArn, weight), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%d%% of traffic is forwarded to canary target group : %s", weight, tool.ParseTargetGroupName(canaryArn))
+	c.Logger.Info(message)
+	c.Slack.SendSimpleMessage(message)
+
+	return nil
+}
+
+// RemoveCanaryTraffic drops the canary target group from production listeners so that the others take the whole traffic
+func (c *Canary) RemoveCanaryTraffic(ctx context.Context, region string) error {
+	client, err := selectClientFromList(c.AWSClients, region)
+	if err != nil {
+		return err
+	}
+
+	canaryArn, err := c.findCanaryTargetGroup(ctx, client, region)
+	if err != nil {
+		return err
+	}
+
+	err = c.updateForwardRules(ctx, client, canaryArn, func(action *elbv2.Action) (*elbv2.Action, error) {
+		return withoutTargetGroup(action, canaryArn)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Logger.Infof("Canary target group is removed from listeners : %s", tool.ParseTargetGroupName(canaryArn))
+
+	return nil
+}
+
+// findCanaryTargetGroup returns canary target group of the new autoscaling group
+func (c *Canary) findCanaryTargetGroup(ctx context.Context, client aws.Client, region string) (string, error) {
+	asg := c.AsgNames[region]
+	group, err := client.EC2Service.GetMatchingAutoscalingGroup(ctx, asg)
+	if err != nil {
+		return constants.EmptyString, err
+	}
+
+	if group == nil {
+		return constants.EmptyString, fmt.Errorf("autoscaling group does not exist: %s", asg)
+	}
+
+	for _, tg := range eaws.StringValueSlice(group.TargetGroupARNs) {
+		if tool.IsCanaryTargetGroupArn(tg, region) {
+			return tg, nil
+		}
+	}
+
+	return constants.EmptyString, fmt.Errorf("there is no canary target group of %s", asg)
+}
+
+// updateForwardRules changes forward actions of listeners and listener rules which forward requests to the target group
+func (c *Canary) updateForwardRules(ctx context.Context, client aws.Client, targetGroupArn string, update func(*elbv2.Action) (*elbv2.Action, error)) error {
+	rules, err := client.ELBV2Service.GetForwardRules(ctx, targetGroupArn)
+	if err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		return fmt.Errorf("no listener forwards requests to the target group: %s", tool.ParseTargetGroupName(targetGroupArn))
+	}
+
+	for _, rule := range rules {
+		var actions []*elbv2.Action
+		for _, action := range rule.Actions {
+			if hasTargetGroup(action, targetGroupArn) {
+				action, err = update(action)
+				if err != nil {
+					return fmt.Errorf("%s: %s", err.Error(), rule.Name())
+				}
+			}
+			actions = append(actions, action)
+		}
+
+		if err := client.ELBV2Service.ModifyForwardRule(ctx, rule, actions); err != nil {
+			return err
+		}
+		c.Logger.Debugf("Forward action is changed: %s", rule.Name())
+	}
+
+	return nil
+}
+
+// hasTargetGroup returns true if the action forwards requests to the target group
+func hasTargetGroup(action *elbv2.Action, targetGroupArn string) bool {
+	for _, tg := range aws.ForwardTargetGroups(action) {
+		if eaws.StringValue(tg.TargetGroupArn) == targetGroupArn {
+			return true
+		}
+	}
+
+	return false
+}
+
+// weightedForwardAction returns a copy of forward action which forwards the weight of traffic to the canary target group
+// and the rest to the other target groups. Weights of the other target groups are scaled to the rest with their ratio kept,
+// and they are dropped if the weight is 100.
+func weightedForwardAction(action *elbv2.Action, canaryArn string, weight int64) *elbv2.Action {
+	var tgs []*elbv2.TargetGroupTuple
+	for _, tg := range aws.ForwardTargetGroups(action) {
+		if eaws.StringValue(tg.TargetGroupArn) == canaryArn || weight == 100 {
+			continue
+		}
+		tgs = append(tgs, &elbv2.TargetGroupTuple{TargetGroupArn: tg.TargetGroupArn, Weight: tg.Weight})
+	}
+
+	weights := scaleWeights(tgs, 100-weight)
+	for i := range tgs {
+		tgs[i].Weight = eaws.Int64(weights[i])
+	}
+	tgs = append(tgs, &elbv2.TargetGroupTuple{TargetGroupArn: eaws.String(canaryArn), Weight: eaws.Int64(weight)})
+
+	return withTargetGroups(action, tgs)
+}
+
+// scaleWeights returns weights of target groups which sum to the total with their ratio kept
+// Remainders of rounding go to the target groups with the largest fractions. If every weight is 0, the total is split evenly.
+func scaleWeights(tgs []*elbv2.TargetGroupTuple, total int64) []int64 {
+	weights := make([]int64, len(tgs))
+	if len(tgs) == 0 {
+		return weights
+	}
+
+	var sum int64
+	for _, tg := range tgs {
+		sum += eaws.Int64Value(tg.Weight)
+	}
+
+	even := sum == 0
+	if even {
+		sum = int64(len(tgs))
+	}
+
+	fractions := make([]int64, len(tgs))
+	var assigned int64
+	for i, tg := range tgs {
+		w := eaws.Int64Value(tg.Weight)
+		if even {
+			w = 1
+		}
+		weights[i] = total * w / sum
+		fractions[i] = total * w % sum
+		assigned += weights[i]
+	}
+
+	for ; assigned < total; assigned++ {
+		largest := 0
+		for i := range fractions {
+			if fractions[i] > fractions[largest] {
+				largest = i
+			}
+		}
+		weights[largest]++
+		fractions[largest] = -1
+	}
+
+	return weights
+}
+
+// withoutTargetGroup returns a copy of forward action without the target group
+func withoutTargetGroup(action *elbv2.Action, targetGroupArn string) (*elbv2.Action, error) {
+	var tgs []*elbv2.TargetGroupTuple
+	for _, tg := range aws.ForwardTargetGroups(action) {
+		if eaws.StringValue(tg.TargetGroupArn) != targetGroupArn {
+			tgs = append(tgs, tg)
+		}
+	}
+
+	if len(tgs) == 0 {
+		return nil, fmt.Errorf("cannot remove the only target group of forward action: %s", tool.ParseTargetGroupName(targetGroupArn))
+	}
+
+	return withTargetGroups(action, tgs), nil
+}
+
+// withTargetGroups returns a copy of forward action with the target groups
+func withTargetGroups(action *elbv2.Action, tgs []*elbv2.TargetGroupTuple) *elbv2.Action {
+	ret := *action
+	ret.TargetGroupArn = nil
+	ret.ForwardConfig = &elbv2.ForwardActionConfig{TargetGroups: tgs}
+	if action.ForwardConfig != nil {
+		ret.ForwardConfig.TargetGroupStickinessConfig = action.ForwardConfig.TargetGroupStickinessConfig
+	}
+
+	return &ret
+}
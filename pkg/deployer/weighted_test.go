@@ -0,0 +1,183 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package deployer
+
+import (
+	"fmt"
+	"testing"
+
+	eaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/go-test/deep"
+)
+
+const (
+	testBaselineArn = "arn:aws:elasticloadbalancing:ap-northeast-2:123456789012:targetgroup/hello-dev-ap2/0123456789abcdef"
+	testGrpcArn     = "arn:aws:elasticloadbalancing:ap-northeast-2:123456789012:targetgroup/hello-grpc-dev-ap2/abcdef0123456789"
+	testCanaryArn   = "arn:aws:elasticloadbalancing:ap-northeast-2:123456789012:targetgroup/hello-dev-canary-v001/fedcba9876543210"
+)
+
+func TestWeightedForwardAction(t *testing.T) {
+	stickiness := &elbv2.TargetGroupStickinessConfig{Enabled: eaws.Bool(false)}
+	testData := []struct {
+		action   *elbv2.Action
+		weight   int64
+		expected *elbv2.Action
+	}{
+		{
+			action: &elbv2.Action{Type: eaws.String("forward"), Order: eaws.Int64(1), TargetGroupArn: eaws.String(testBaselineArn)},
+			weight: 0,
+			expected: &elbv2.Action{Type: eaws.String("forward"), Order: eaws.Int64(1), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(100)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(0)},
+				},
+			}},
+		},
+		{
+			action: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroupStickinessConfig: stickiness,
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(100)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(0)},
+				},
+			}},
+			weight: 5,
+			expected: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroupStickinessConfig: stickiness,
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(95)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(5)},
+				},
+			}},
+		},
+		{
+			action: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(95)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(5)},
+				},
+			}},
+			weight: 100,
+			expected: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(100)},
+				},
+			}},
+		},
+		{
+			action: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(3)},
+					{TargetGroupArn: eaws.String(testGrpcArn), Weight: eaws.Int64(1)},
+				},
+			}},
+			weight: 0,
+			expected: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(75)},
+					{TargetGroupArn: eaws.String(testGrpcArn), Weight: eaws.Int64(25)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(0)},
+				},
+			}},
+		},
+		{
+			action: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(75)},
+					{TargetGroupArn: eaws.String(testGrpcArn), Weight: eaws.Int64(25)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(0)},
+				},
+			}},
+			weight: 5,
+			expected: &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+				TargetGroups: []*elbv2.TargetGroupTuple{
+					{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(71)},
+					{TargetGroupArn: eaws.String(testGrpcArn), Weight: eaws.Int64(24)},
+					{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(5)},
+				},
+			}},
+		},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(weightedForwardAction(td.action, testCanaryArn, td.weight), td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestScaleWeights(t *testing.T) {
+	tuples := func(weights ...int64) []*elbv2.TargetGroupTuple {
+		var tgs []*elbv2.TargetGroupTuple
+		for _, w := range weights {
+			tgs = append(tgs, &elbv2.TargetGroupTuple{Weight: eaws.Int64(w)})
+		}
+		return tgs
+	}
+
+	testData := []struct {
+		tgs      []*elbv2.TargetGroupTuple
+		total    int64
+		expected []int64
+	}{
+		{tgs: tuples(1), total: 95, expected: []int64{95}},
+		{tgs: tuples(50, 50), total: 95, expected: []int64{48, 47}},
+		{tgs: tuples(1, 1, 1), total: 100, expected: []int64{34, 33, 33}},
+		{tgs: tuples(0, 0), total: 90, expected: []int64{45, 45}},
+		{tgs: tuples(80, 20, 0), total: 50, expected: []int64{40, 10, 0}},
+		{tgs: nil, total: 100, expected: []int64{}},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(scaleWeights(td.tgs, td.total), td.expected); diff != nil {
+			t.Errorf("total %d: %v", td.total, diff)
+		}
+	}
+}
+
+func TestWithoutTargetGroup(t *testing.T) {
+	action := &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+		TargetGroups: []*elbv2.TargetGroupTuple{
+			{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(95)},
+			{TargetGroupArn: eaws.String(testCanaryArn), Weight: eaws.Int64(5)},
+		},
+	}}
+
+	got, err := withoutTargetGroup(action, testCanaryArn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &elbv2.Action{Type: eaws.String("forward"), ForwardConfig: &elbv2.ForwardActionConfig{
+		TargetGroups: []*elbv2.TargetGroupTuple{
+			{TargetGroupArn: eaws.String(testBaselineArn), Weight: eaws.Int64(95)},
+		},
+	}}
+	if diff := deep.Equal(got, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	_, err = withoutTargetGroup(got, testBaselineArn)
+	if diff := deep.Equal(err, fmt.Errorf("cannot remove the only target group of forward action: hello-dev-ap2")); diff != nil {
+		t.Error(diff)
+	}
+
+	if hasTargetGroup(got, testCanaryArn) || !hasTargetGroup(got, testBaselineArn) {
+		t.Errorf("unexpected target groups of forward action: %v", got)
+	}
+}
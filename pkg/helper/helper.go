@@ -47,5 +47,7 @@ func InitStartStatus() map[int64]bool {
 		constants.StepCleanChecking:            false,
 		constants.StepGatherMetrics:            false,
 		constants.StepRunAPI:                   false,
+		constants.StepBake:                     false,
+		constants.StepCanary:                   false,
 	}
 }
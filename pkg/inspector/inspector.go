@@ -17,6 +17,7 @@ limitations under the license.
 package inspector
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -118,7 +119,7 @@ func (i Inspector) GetStacks(application string) ([]string, error) {
 }
 
 func (i Inspector) GetStackInformation(asgName string) (*autoscaling.Group, error) {
-	asg, err := i.AWSClient.EC2Service.GetMatchingAutoscalingGroup(asgName)
+	asg, err := i.AWSClient.EC2Service.GetMatchingAutoscalingGroup(context.Background(), asgName)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,162 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package lock
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+)
+
+// DynamoDBLocker stores locks in a dynamodb table with conditional writes
+type DynamoDBLocker struct {
+	Client    aws.DynamoDBClient
+	TableName string
+}
+
+// NewDynamoDBLocker creates dynamodb lock backend
+func NewDynamoDBLocker(region, tableName string) DynamoDBLocker {
+	return DynamoDBLocker{
+		Client:    aws.BootstrapMetricService(region, constants.EmptyString).DynamoDBService,
+		TableName: tableName,
+	}
+}
+
+// EnsureTable creates lock table if it does not exist
+func (d DynamoDBLocker) EnsureTable() error {
+	isExist, err := d.Client.CheckTableExists(d.TableName)
+	if err != nil {
+		return err
+	}
+
+	if !isExist {
+		if err := d.Client.CreateTable(d.TableName); err != nil {
+			return err
+		}
+	}
+
+	return d.Client.WaitTableExists(d.TableName)
+}
+
+// Acquire puts lock item only if there is no valid lock
+func (d DynamoDBLocker) Acquire(info Info) error {
+	fields := map[string]string{
+		"owner":       info.Owner,
+		"operation":   info.Operation,
+		"acquired_at": info.AcquiredAt.Format(time.RFC3339),
+	}
+
+	err := d.Client.PutLockItem(itemKey(info.Key), d.TableName, fields, info.ExpiresAt.Unix(), time.Now().Unix())
+	if err == nil {
+		return nil
+	}
+
+	if !isConditionalCheckFailed(err) {
+		return err
+	}
+
+	holder, err := d.Get(info.Key)
+	if err != nil {
+		return err
+	}
+
+	if holder == nil {
+		// lock is released right after the conditional write
+		return d.Acquire(info)
+	}
+
+	return LockedError{Holder: *holder}
+}
+
+// Renew extends expiration time of lock item held by the owner
+func (d DynamoDBLocker) Renew(info Info) error {
+	err := d.Client.RenewLockItem(itemKey(info.Key), d.TableName, info.Owner, info.ExpiresAt.Unix())
+	if isConditionalCheckFailed(err) {
+		return ErrLockNotHeld
+	}
+
+	return err
+}
+
+// Release deletes lock item held by the owner
+func (d DynamoDBLocker) Release(info Info) error {
+	err := d.Client.DeleteLockItem(itemKey(info.Key), d.TableName, info.Owner)
+	if isConditionalCheckFailed(err) {
+		return ErrLockNotHeld
+	}
+
+	return err
+}
+
+// ForceRelease deletes lock item regardless of the owner
+func (d DynamoDBLocker) ForceRelease(key string) error {
+	return d.Client.DeleteLockItem(itemKey(key), d.TableName, constants.EmptyString)
+}
+
+// Get retrieves lock item
+func (d DynamoDBLocker) Get(key string) (*Info, error) {
+	item, err := d.Client.GetSingleItem(itemKey(key), d.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		return nil, nil
+	}
+
+	info := Info{Key: key}
+	if v, ok := item["owner"]; ok && v.S != nil {
+		info.Owner = *v.S
+	}
+
+	if v, ok := item["operation"]; ok && v.S != nil {
+		info.Operation = *v.S
+	}
+
+	if v, ok := item["acquired_at"]; ok && v.S != nil {
+		info.AcquiredAt, _ = time.Parse(time.RFC3339, *v.S)
+	}
+
+	if v, ok := item["expires_at"]; ok && v.N != nil {
+		expiresAt, err := strconv.ParseInt(*v.N, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		info.ExpiresAt = time.Unix(expiresAt, 0)
+	}
+
+	return &info, nil
+}
+
+// itemKey returns identifier of lock item
+func itemKey(key string) string {
+	return constants.LockKeyPrefix + key
+}
+
+// isConditionalCheckFailed checks if the error is caused by the condition of write
+func isConditionalCheckFailed(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileLocker stores locks as files in a local directory
+type FileLocker struct {
+	Dir string
+}
+
+// NewFileLocker creates file lock backend
+func NewFileLocker(dir string) (FileLocker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return FileLocker{}, err
+	}
+
+	return FileLocker{Dir: dir}, nil
+}
+
+// Acquire creates lock file exclusively
+func (f FileLocker) Acquire(info Info) error {
+	for {
+		err := f.create(info)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		holder, err := f.Get(info.Key)
+		if err != nil {
+			return err
+		}
+
+		if holder != nil && !holder.IsExpired(time.Now()) {
+			return LockedError{Holder: *holder}
+		}
+
+		// previous lease is expired
+		if err := os.Remove(f.path(info.Key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+}
+
+// Renew updates expiration time of lock file
+func (f FileLocker) Renew(info Info) error {
+	holder, err := f.Get(info.Key)
+	if err != nil {
+		return err
+	}
+
+	if holder == nil || holder.Owner != info.Owner {
+		return ErrLockNotHeld
+	}
+
+	holder.ExpiresAt = info.ExpiresAt
+	return f.write(*holder)
+}
+
+// Release removes lock file held by the owner
+func (f FileLocker) Release(info Info) error {
+	holder, err := f.Get(info.Key)
+	if err != nil {
+		return err
+	}
+
+	if holder == nil || holder.Owner != info.Owner {
+		return ErrLockNotHeld
+	}
+
+	return os.Remove(f.path(info.Key))
+}
+
+// ForceRelease removes lock file regardless of the owner
+func (f FileLocker) ForceRelease(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Get reads lock file
+func (f FileLocker) Get(key string) (*Info, error) {
+	b, err := os.ReadFile(f.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var info Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// create writes new lock file and fails if the file already exists
+func (f FileLocker) create(info Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path(info.Key), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(b)
+	return err
+}
+
+// write overwrites lock file
+func (f FileLocker) write(info Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f.path(info.Key), b, 0644)
+}
+
+// path returns file path of lock
+func (f FileLocker) path(key string) string {
+	return filepath.Join(f.Dir, strings.ReplaceAll(key, "/", "_")+".lock")
+}
@@ -0,0 +1,169 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFileLocker(t *testing.T) {
+	locker, err := NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	first := Info{
+		Key:        "hello-dev_apnortheast2",
+		Owner:      "first",
+		Operation:  "deploy",
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(time.Minute),
+	}
+	second := first
+	second.Owner = "second"
+
+	if err := locker.Acquire(first); err != nil {
+		t.Fatalf("first acquire failed: %s", err)
+	}
+
+	err = locker.Acquire(second)
+	var lockedErr LockedError
+	if !errors.As(err, &lockedErr) || lockedErr.Holder.Owner != "first" {
+		t.Errorf("expected lock held by first, got %v", err)
+	}
+
+	if err := locker.Renew(second); err != ErrLockNotHeld {
+		t.Errorf("expected ErrLockNotHeld when renewing by another owner, got %v", err)
+	}
+
+	if err := locker.Release(second); err != ErrLockNotHeld {
+		t.Errorf("expected ErrLockNotHeld when releasing by another owner, got %v", err)
+	}
+
+	if err := locker.Release(first); err != nil {
+		t.Errorf("release failed: %s", err)
+	}
+
+	if err := locker.Acquire(second); err != nil {
+		t.Errorf("acquire after release failed: %s", err)
+	}
+
+	if err := locker.ForceRelease(second.Key); err != nil {
+		t.Errorf("force release failed: %s", err)
+	}
+
+	holder, err := locker.Get(second.Key)
+	if err != nil || holder != nil {
+		t.Errorf("expected no holder after force release, got %v, %v", holder, err)
+	}
+}
+
+func TestFileLocker_ExpiredLease(t *testing.T) {
+	locker, err := NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	expired := Info{
+		Key:        "hello-dev_apnortheast2",
+		Owner:      "crashed",
+		AcquiredAt: now.Add(-time.Hour),
+		ExpiresAt:  now.Add(-time.Minute),
+	}
+
+	if err := locker.Acquire(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	next := Info{
+		Key:        expired.Key,
+		Owner:      "next",
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(time.Minute),
+	}
+
+	if err := locker.Acquire(next); err != nil {
+		t.Errorf("expected to take over expired lock, got %s", err)
+	}
+}
+
+func TestAcquireLease(t *testing.T) {
+	locker, err := NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lease, err := AcquireLease(locker, []string{"a", "b"}, "deploy", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AcquireLease(locker, []string{"c", "b"}, "deploy", time.Minute); err == nil {
+		t.Error("expected lease to fail while b is locked")
+	}
+
+	// c should be released because b could not be taken
+	if holder, _ := locker.Get("c"); holder != nil {
+		t.Errorf("expected c to be released, held by %s", holder.Owner)
+	}
+
+	lease.Release()
+	for _, key := range []string{"a", "b"} {
+		if holder, _ := locker.Get(key); holder != nil {
+			t.Errorf("expected %s to be released", key)
+		}
+	}
+}
+
+func TestLease_Lost(t *testing.T) {
+	locker, err := NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lease, err := AcquireLease(locker, []string{"a"}, "deploy", 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	ctx, cancel := lease.WithContext(context.Background())
+	defer cancel()
+
+	if err := lease.Err(); err != nil {
+		t.Fatalf("expected lease to be held, got %s", err)
+	}
+
+	if err := locker.ForceRelease("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected context to be cancelled after the lock is lost")
+	}
+
+	if err := lease.Err(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("expected ErrLockNotHeld, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	Logger "github.com/sirupsen/logrus"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+var (
+	// ErrLockNotHeld is returned when the lock is not held by the owner
+	ErrLockNotHeld = errors.New("lock is not held by this owner")
+)
+
+// Locker is a backend storage of deployment lock
+type Locker interface {
+	// Acquire takes the lock if nobody holds it or the lease of holder is expired
+	Acquire(info Info) error
+
+	// Renew extends the lease of the lock held by info.Owner
+	Renew(info Info) error
+
+	// Release removes the lock held by info.Owner
+	Release(info Info) error
+
+	// ForceRelease removes the lock regardless of the owner
+	ForceRelease(key string) error
+
+	// Get returns the current holder of the lock, nil if nobody holds it
+	Get(key string) (*Info, error)
+}
+
+// Info is the metadata of lock holder
+type Info struct {
+	Key        string
+	Owner      string
+	Operation  string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// LockedError is returned when another owner holds the lock
+type LockedError struct {
+	Holder Info
+}
+
+func (e LockedError) Error() string {
+	return fmt.Sprintf("%s is locked by %s for %s since %s (expires at %s), run `goployer unlock %s --force` if the lock is stale",
+		e.Holder.Key, e.Holder.Owner, e.Holder.Operation, e.Holder.AcquiredAt.Format(time.RFC3339), e.Holder.ExpiresAt.Format(time.RFC3339), e.Holder.Key)
+}
+
+// New creates lock backend with configuration
+// If backend is not specified, dynamodb is used with the lock table or metric table, otherwise local files are used.
+func New(config schemas.Config, mc schemas.MetricConfig) (Locker, error) {
+	backend := config.LockBackend
+	if len(backend) == 0 {
+		backend = constants.FileLockBackend
+		if len(config.LockTable) > 0 || mc.Enabled {
+			backend = constants.DynamoDBLockBackend
+		}
+	}
+
+	switch backend {
+	case constants.NoLockBackend:
+		return nil, nil
+	case constants.FileLockBackend:
+		return NewFileLocker(constants.DefaultLockDirectory)
+	case constants.DynamoDBLockBackend:
+		table := config.LockTable
+		if len(table) == 0 {
+			table = mc.Storage.Name
+		}
+
+		if len(table) == 0 {
+			return nil, errors.New("you have to specify --lock-table or enable metrics to use dynamodb lock")
+		}
+
+		region := mc.Region
+		if len(config.LockTable) > 0 || len(region) == 0 {
+			region = config.Region
+		}
+
+		if len(region) == 0 {
+			region = constants.DefaultRegion
+		}
+
+		locker := NewDynamoDBLocker(region, table)
+		if err := locker.EnsureTable(); err != nil {
+			return nil, err
+		}
+
+		return locker, nil
+	}
+
+	return nil, fmt.Errorf("lock backend is not supported: %s", backend)
+}
+
+// IsExpired checks if the lease of the lock is expired
+func (i Info) IsExpired(now time.Time) bool {
+	return !i.ExpiresAt.After(now)
+}
+
+// NewOwner returns the identity of the current process like user@host:pid
+func NewOwner() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s@%s:%d", username, hostname, os.Getpid())
+}
+
+// Lease holds locks and renews them until released
+// If one of locks is lost, renewal stops and Done is closed.
+type Lease struct {
+	Locker   Locker
+	Infos    []Info
+	Duration time.Duration
+	stop     chan struct{}
+	lost     chan struct{}
+	err      error
+	wg       sync.WaitGroup
+}
+
+// AcquireLease takes all locks with keys and starts renewing them
+// If one of keys is already locked, locks taken so far are released.
+func AcquireLease(locker Locker, keys []string, operation string, duration time.Duration) (*Lease, error) {
+	owner := NewOwner()
+	lease := &Lease{
+		Locker:   locker,
+		Duration: duration,
+		stop:     make(chan struct{}),
+		lost:     make(chan struct{}),
+	}
+
+	for _, key := range keys {
+		now := time.Now()
+		info := Info{
+			Key:        key,
+			Owner:      owner,
+			Operation:  operation,
+			AcquiredAt: now,
+			ExpiresAt:  now.Add(duration),
+		}
+
+		if err := locker.Acquire(info); err != nil {
+			lease.releaseAll()
+			return nil, err
+		}
+		Logger.Debugf("lock is acquired: %s", key)
+		lease.Infos = append(lease.Infos, info)
+	}
+
+	lease.wg.Add(1)
+	go lease.renew()
+
+	return lease, nil
+}
+
+// renew extends leases periodically until Release is called
+// Renewal stops when the lock is taken by others or the lease expires while renewal keeps failing.
+func (l *Lease) renew() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.Duration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			for i := range l.Infos {
+				if err := l.renewLock(i); err != nil {
+					Logger.Errorf("deployment lock is lost: %s", err.Error())
+					l.err = err
+					close(l.lost)
+					return
+				}
+			}
+		}
+	}
+}
+
+// renewLock extends the lease of a lock
+// Errors except for ErrLockNotHeld are retried on the next tick until the lease expires.
+func (l *Lease) renewLock(i int) error {
+	expiresAt := l.Infos[i].ExpiresAt
+	l.Infos[i].ExpiresAt = time.Now().Add(l.Duration)
+
+	err := l.Locker.Renew(l.Infos[i])
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, ErrLockNotHeld) {
+		return fmt.Errorf("%s: %w", l.Infos[i].Key, err)
+	}
+
+	l.Infos[i].ExpiresAt = expiresAt
+	if time.Now().After(expiresAt) {
+		return fmt.Errorf("lease of %s is expired: %s", l.Infos[i].Key, err.Error())
+	}
+
+	Logger.Errorf("failed to renew lock %s: %s", l.Infos[i].Key, err.Error())
+	return nil
+}
+
+// Done returns a channel which is closed when one of locks is lost
+func (l *Lease) Done() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+
+	return l.lost
+}
+
+// Err returns the reason why the lease is lost, nil if all locks are held
+func (l *Lease) Err() error {
+	if l == nil {
+		return nil
+	}
+
+	select {
+	case <-l.lost:
+		return l.err
+	default:
+		return nil
+	}
+}
+
+// WithContext returns a context which is cancelled when one of locks is lost
+func (l *Lease) WithContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if l == nil {
+		return ctx, cancel
+	}
+
+	go func() {
+		select {
+		case <-l.lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Release stops renewing and removes all locks
+func (l *Lease) Release() {
+	if l == nil {
+		return
+	}
+
+	close(l.stop)
+	l.wg.Wait()
+	l.releaseAll()
+}
+
+// releaseAll removes all locks of lease
+func (l *Lease) releaseAll() {
+	for _, info := range l.Infos {
+		if err := l.Locker.Release(info); err != nil {
+			Logger.Errorf("failed to release lock %s: %s", info.Key, err.Error())
+			continue
+		}
+		Logger.Debugf("lock is released: %s", info.Key)
+	}
+}
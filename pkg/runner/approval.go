@@ -0,0 +1,358 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// approvalGate is the name of gate step which is tracked in the result
+const approvalGate = "StepApproval"
+
+// Approve is the main function of `goployer approve`
+func Approve(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: goployer approve <deployment id>")
+	}
+
+	builderSt, err := builder.NewBuilder(nil)
+	if err != nil {
+		return err
+	}
+
+	config := builderSt.Config
+	approver := config.Approver
+	if len(approver) == 0 {
+		approver = defaultApprover()
+	}
+
+	decision := state.Decision{
+		Stack:    config.Stack,
+		Region:   config.Region,
+		Approved: !config.Reject,
+		Approver: approver,
+		Source:   constants.ApprovalSourceCommand,
+		Comment:  config.Comment,
+	}
+
+	if err := ApproveDeployment(config, args[0], decision); err != nil {
+		return err
+	}
+
+	if decision.Approved {
+		fmt.Printf("deployment %s is approved by %s\n", args[0], approver)
+	} else {
+		fmt.Printf("deployment %s is rejected by %s\n", args[0], approver)
+	}
+
+	return nil
+}
+
+// ApproveDeployment saves the decision on pending approval gates of the deployment to the state backend of configuration
+func ApproveDeployment(config schemas.Config, id string, decision state.Decision) error {
+	m, err := builder.ParseMetricConfig(config.DisableMetrics, constants.MetricYamlPath)
+	if err != nil {
+		return err
+	}
+
+	store, err := state.New(config, m)
+	if err != nil {
+		return err
+	}
+
+	if store == nil {
+		return errors.New("state backend is disabled")
+	}
+
+	return decide(store, id, decision)
+}
+
+// decide appends the decision if the deployment has a pending approval gate which matches it
+func decide(store state.Store, id string, decision state.Decision) error {
+	st, err := store.Load(id)
+	if err != nil {
+		return fmt.Errorf("%s: %s", err.Error(), id)
+	}
+
+	if len(pendingApprovals(*st, decision)) == 0 {
+		return fmt.Errorf("no approval gate is pending: %s", id)
+	}
+
+	if decision.DecidedAt.IsZero() {
+		decision.DecidedAt = time.Now()
+	}
+
+	return store.AddDecision(id, decision)
+}
+
+// pendingApprovals returns pending approval gates of the state which match the decision
+func pendingApprovals(st state.State, decision state.Decision) []state.ApprovalEvent {
+	var ret []state.ApprovalEvent
+	for _, ev := range st.Approvals {
+		if ev.Status != constants.ApprovalPending {
+			continue
+		}
+
+		ss, ok := st.GetStack(ev.Pipeline)
+		if !ok || !decision.Matches(ss.Stack) {
+			continue
+		}
+
+		ret = append(ret, ev)
+	}
+
+	return ret
+}
+
+// findDecision returns the newest decision which matches the stack and is made after the gate is requested
+// Decisions on earlier gates of the same deployment are ignored.
+func findDecision(decisions []state.Decision, stack schemas.Stack, requestedAt time.Time) (state.Decision, bool) {
+	var ret state.Decision
+	found := false
+	for _, decision := range decisions {
+		if !decision.Matches(stack) || decision.DecidedAt.Before(requestedAt) {
+			continue
+		}
+
+		if !found || !decision.DecidedAt.Before(ret.DecidedAt) {
+			ret, found = decision, true
+		}
+	}
+
+	return ret, found
+}
+
+// hasApproval returns true if any deployer has approval gate
+func hasApproval(deployers []deployer.DeployManager) bool {
+	for _, d := range deployers {
+		if d.GetDeployer().Stack.Approval != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// approvalBefore returns true if the step of deployer is gated by approval
+func approvalBefore(d deployer.DeployManager, step int64) bool {
+	approval := d.GetDeployer().Stack.Approval
+	if approval == nil {
+		return false
+	}
+
+	return constants.ApprovalSteps[approval.Before] == step
+}
+
+// waitForApproval pauses the pipeline until the approval gate is decided
+// Decisions are taken from the terminal if goployer runs interactively, and from the state backend
+// where `goployer approve` and the goployer server save them. Timeout is regarded as rejection.
+func (r Runner) waitForApproval(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder) error {
+	dp := d.GetDeployer()
+	approval := dp.Stack.Approval
+	pipeline := dp.GetPipelineName()
+
+	if ev, ok := recorder.GetApproval(pipeline, approval.Before); ok && ev.Status == constants.ApprovalApproved {
+		r.Logger.Infof("approval gate before %s is already approved by %s: %s", approval.Before, ev.Approver, pipeline)
+		return nil
+	}
+
+	if recorder == nil && r.terminal == nil {
+		return fmt.Errorf("approval gate before %s needs state backend or interactive terminal: %s", approval.Before, pipeline)
+	}
+
+	timeout := approval.Timeout
+	if timeout == 0 {
+		timeout = constants.DefaultApprovalTimeout
+	}
+
+	ev := state.ApprovalEvent{
+		Pipeline:    pipeline,
+		Gate:        approval.Before,
+		Status:      constants.ApprovalPending,
+		RequestedAt: time.Now(),
+	}
+	recorder.RecordApproval(ev)
+
+	message := fmt.Sprintf("approval is required before %s of %s in %s", approval.Before, pipeline, tool.RoundTime(timeout))
+	if recorder != nil {
+		message += fmt.Sprintf(", run `goployer approve %s --stack %s --region %s` to approve", recorder.State.ID, dp.Stack.Stack, dp.Stack.Regions[0].Region)
+	}
+	r.Logger.Warn(message)
+	r.Slacker.SendSimpleMessage(fmt.Sprintf(":raised_hand: %s", message))
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	var answers <-chan bool
+	if r.terminal != nil {
+		answers = r.terminal.ask(fmt.Sprintf("Do you approve %s of %s? (yes/no) ", approval.Before, pipeline), stop)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var poll <-chan time.Time
+	if recorder != nil {
+		ticker := time.NewTicker(constants.ApprovalPollingInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	var decision state.Decision
+	for decided := false; !decided; {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			decision, decided = state.Decision{DecidedAt: time.Now()}, true
+			ev.Status = constants.ApprovalTimedOut
+		case approved := <-answers:
+			decision = state.Decision{Approved: approved, Approver: defaultApprover(), Source: constants.ApprovalSourceTerminal, DecidedAt: time.Now()}
+			decided = true
+		case <-poll:
+			decisions, err := recorder.Store.LoadDecisions(recorder.State.ID)
+			if err != nil {
+				r.Logger.Warnf("failed to load approval decisions: %s", err.Error())
+				continue
+			}
+			decision, decided = findDecision(decisions, dp.Stack, ev.RequestedAt)
+		}
+	}
+
+	if ev.Status != constants.ApprovalTimedOut {
+		ev.Status = constants.ApprovalRejected
+		if decision.Approved {
+			ev.Status = constants.ApprovalApproved
+		}
+		ev.Approver = decision.Approver
+		ev.Source = decision.Source
+		ev.Comment = decision.Comment
+	}
+	ev.DecidedAt = decision.DecidedAt
+	recorder.RecordApproval(ev)
+
+	switch ev.Status {
+	case constants.ApprovalApproved:
+		r.Logger.Infof("approval gate before %s is approved by %s(%s): %s", approval.Before, ev.Approver, ev.Source, pipeline)
+		r.Slacker.SendSimpleMessage(fmt.Sprintf(":white_check_mark: %s of %s is approved by %s", approval.Before, pipeline, ev.Approver))
+		return nil
+	case constants.ApprovalTimedOut:
+		r.Slacker.SendSimpleMessage(fmt.Sprintf(":x: approval of %s of %s is timed out", approval.Before, pipeline))
+		return fmt.Errorf("approval gate before %s is timed out after %s", approval.Before, tool.RoundTime(timeout))
+	}
+
+	r.Slacker.SendSimpleMessage(fmt.Sprintf(":x: %s of %s is rejected by %s", approval.Before, pipeline, ev.Approver))
+	return fmt.Errorf("approval gate before %s is rejected by %s(%s)", approval.Before, ev.Approver, ev.Source)
+}
+
+// terminalApprover asks decisions of approval gates on the terminal one by one
+type terminalApprover struct {
+	turn  chan struct{}
+	lines chan string
+}
+
+// newTerminalApprover creates terminal approver if the standard input is a terminal
+func newTerminalApprover() *terminalApprover {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	t := &terminalApprover{
+		turn:  make(chan struct{}, 1),
+		lines: make(chan string),
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			t.lines <- scanner.Text()
+		}
+		close(t.lines)
+	}()
+
+	return t
+}
+
+// ask prints the message when other questions are answered and sends the answer to the channel
+// The question is dropped when stop is closed.
+func (t *terminalApprover) ask(message string, stop <-chan struct{}) <-chan bool {
+	answers := make(chan bool, 1)
+
+	go func() {
+		select {
+		case t.turn <- struct{}{}:
+			defer func() { <-t.turn }()
+		case <-stop:
+			return
+		}
+
+		for {
+			fmt.Print(message)
+			select {
+			case line, ok := <-t.lines:
+				if !ok {
+					return
+				}
+
+				answer := strings.ToLower(strings.TrimSpace(line))
+				if tool.IsStringInArray(answer, constants.AllowedAnswerYes) {
+					answers <- true
+					return
+				}
+
+				if answer == "n" || answer == "no" {
+					answers <- false
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return answers
+}
+
+// defaultApprover returns the identity of the current user like user@host
+func defaultApprover() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s@%s", username, hostname)
+}
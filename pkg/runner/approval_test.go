@@ -0,0 +1,102 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+)
+
+func TestDecide(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artd := schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}}
+	st := state.State{
+		ID:     "hello-20201001120000",
+		Stacks: []state.StackState{{Stack: artd}},
+	}
+	if err := store.Save(st); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := decide(store, st.ID, state.Decision{Approved: true, Approver: "jack"}); err == nil {
+		t.Error("expected error without pending approval gate")
+	}
+
+	st.Approvals = []state.ApprovalEvent{{Pipeline: "artd/ap-northeast-2", Gate: "bake", Status: constants.ApprovalPending}}
+	if err := store.Save(st); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := decide(store, st.ID, state.Decision{Stack: "batch", Approved: true, Approver: "jack"}); err == nil {
+		t.Error("expected error with decision of other stack")
+	}
+
+	if err := decide(store, st.ID, state.Decision{Stack: "artd", Approved: false, Approver: "jack", Source: constants.ApprovalSourceCommand}); err != nil {
+		t.Fatal(err)
+	}
+
+	decisions, err := store.LoadDecisions(st.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decision, ok := findDecision(decisions, artd, time.Time{})
+	if !ok || decision.Approved || decision.Approver != "jack" || decision.DecidedAt.IsZero() {
+		t.Errorf("expected rejection of jack, got %v", decision)
+	}
+
+	if _, ok := findDecision(decisions, schemas.Stack{Stack: "batch"}, time.Time{}); ok {
+		t.Error("expected no decision for batch")
+	}
+}
+
+func TestFindDecision(t *testing.T) {
+	artd := schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}}
+	requestedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	decisions := []state.Decision{
+		{Stack: "artd", Approved: false, Approver: "jack", DecidedAt: requestedAt.Add(-time.Hour)},
+		{Stack: "artd", Approved: true, Approver: "jill", DecidedAt: requestedAt.Add(2 * time.Minute)},
+		{Stack: "artd", Approved: false, Approver: "john", DecidedAt: requestedAt.Add(time.Minute)},
+		{Stack: "batch", Approved: false, Approver: "jane", DecidedAt: requestedAt.Add(3 * time.Minute)},
+	}
+
+	testData := []struct {
+		requestedAt time.Time
+		found       bool
+		approver    string
+	}{
+		{requestedAt: time.Time{}, found: true, approver: "jill"},
+		{requestedAt: requestedAt, found: true, approver: "jill"},
+		{requestedAt: requestedAt.Add(90 * time.Second), found: true, approver: "jill"},
+		{requestedAt: requestedAt.Add(3 * time.Minute), found: false},
+	}
+
+	for _, td := range testData {
+		decision, ok := findDecision(decisions, artd, td.requestedAt)
+		if ok != td.found || decision.Approver != td.approver {
+			t.Errorf("requested at %s: expected %s, got %v", td.requestedAt, td.approver, decision)
+		}
+	}
+}
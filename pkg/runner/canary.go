@@ -0,0 +1,220 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// canaryActions are actions of `goployer canary`
+var canaryActions = []string{"status", "promote", "abort"}
+
+// canaryPipeline is a canary deployer of a stack in a region with the canary in progress
+type canaryPipeline struct {
+	deployer *deployer.Canary
+	status   deployer.CanaryStatus
+}
+
+// Canary is the main function of `goployer canary`
+func Canary(ctx context.Context, args []string) error {
+	if len(args) != 1 || !tool.IsStringInArray(args[0], canaryActions) {
+		return errors.New("usage: goployer canary <status|promote|abort>")
+	}
+
+	builderSt, err := SetupBuilder("canary")
+	if err != nil {
+		return err
+	}
+
+	if err := builderSt.CheckValidation(); err != nil {
+		return err
+	}
+
+	r, err := NewRunner(builderSt, "canary")
+	if err != nil {
+		return err
+	}
+	r.LogFormatting(builderSt.Config.LogLevel)
+
+	switch args[0] {
+	case "promote":
+		return r.PromoteCanary(ctx)
+	case "abort":
+		return r.AbortCanary(ctx)
+	}
+
+	return r.CanaryStatus(ctx)
+}
+
+// CanaryStatus prints canaries in progress of canary stacks
+func (r Runner) CanaryStatus(ctx context.Context) error {
+	canaries, err := r.findCanaries(ctx)
+	if err != nil {
+		return err
+	}
+
+	var statuses []deployer.CanaryStatus
+	for _, c := range canaries {
+		statuses = append(statuses, c.status)
+	}
+
+	return deployer.PrintCanaryStatuses(os.Stdout, statuses)
+}
+
+// PromoteCanary completes canaries in progress with the same pipeline as `goployer deploy --complete-canary`
+func (r Runner) PromoteCanary(ctx context.Context) error {
+	canaries, err := r.findCanaries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(canaries) == 0 {
+		return errors.New("no canary is in progress")
+	}
+
+	if err := tool.LocalCheck(fmt.Sprintf("Do you really want to promote %d canaries? ", len(canaries)), r.Builder.Config.AutoApply); err != nil {
+		return err
+	}
+
+	r.Builder.Stacks = canaryStacks(r.Builder.Stacks, canaries)
+	r.Builder.Config.CompleteCanary = true
+	r.Builder.Config.AutoApply = true
+
+	if err := r.Deploy(ctx); err != nil {
+		return err
+	}
+
+	r.Slacker.SendSimpleMessage(fmt.Sprintf(":100: Canary is promoted: %s", r.Builder.AwsConfig.Name))
+	return nil
+}
+
+// AbortCanary deletes canaries in progress and restores baseline autoscaling groups
+func (r Runner) AbortCanary(ctx context.Context) error {
+	canaries, err := r.findCanaries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(canaries) == 0 {
+		return errors.New("no canary is in progress")
+	}
+
+	if err := tool.LocalCheck(fmt.Sprintf("Do you really want to abort %d canaries? ", len(canaries)), r.Builder.Config.AutoApply); err != nil {
+		return err
+	}
+
+	lease, err := r.acquireLock("canary", r.lockKeys())
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	ctx, cancel := lease.WithContext(ctx)
+	defer cancel()
+
+	for _, c := range canaries {
+		config := r.Builder.Config
+		config.Region = c.status.Region
+
+		r.Logger.Infof("Aborting canary: %s / %s", c.status.AutoScalingGroup, c.status.Region)
+		if err := c.deployer.AbortCanary(ctx, config); err != nil {
+			return checkLease(lease, fmt.Errorf("failed to abort canary of %s(%s): %s", c.status.Stack, c.status.Region, err.Error()))
+		}
+
+		if err := checkLease(lease, nil); err != nil {
+			return err
+		}
+	}
+
+	r.Logger.Infof("abort operation is finished")
+	return nil
+}
+
+// findCanaries returns canary deployers of stacks and regions which have a canary in progress
+func (r Runner) findCanaries(ctx context.Context) ([]canaryPipeline, error) {
+	var ret []canaryPipeline
+	for _, stack := range r.Builder.Stacks {
+		if r.Builder.Config.Stack != "" && stack.Stack != r.Builder.Config.Stack {
+			continue
+		}
+
+		if stack.ReplacementType != constants.CanaryDeployment {
+			r.Logger.Debugf("Skipping this stack which is not canary, stack=%s", stack.Stack)
+			continue
+		}
+
+		for _, region := range stack.Regions {
+			if r.Builder.Config.Region != "" && region.Region != r.Builder.Config.Region {
+				continue
+			}
+
+			s := stack
+			s.Regions = []schemas.RegionConfig{region}
+
+			c, ok := getDeployer(r.Logger, s, r.Builder.AwsConfig, r.Builder.APITestTemplates, region.Region, r.Slacker, r.Collector).(*deployer.Canary)
+			if !ok {
+				continue
+			}
+
+			status, err := c.GetCanaryStatus(ctx, region.Region)
+			if err != nil {
+				return nil, err
+			}
+
+			if status == nil {
+				r.Logger.Debugf("No canary is in progress, stack=%s, region=%s", stack.Stack, region.Region)
+				continue
+			}
+
+			ret = append(ret, canaryPipeline{deployer: c, status: *status})
+		}
+	}
+
+	return ret, nil
+}
+
+// canaryStacks returns stacks with only regions which have a canary in progress
+func canaryStacks(stacks []schemas.Stack, canaries []canaryPipeline) []schemas.Stack {
+	var ret []schemas.Stack
+	for _, stack := range stacks {
+		var regions []schemas.RegionConfig
+		for _, region := range stack.Regions {
+			for _, c := range canaries {
+				if c.status.Stack == stack.Stack && c.status.Region == region.Region {
+					regions = append(regions, region)
+					break
+				}
+			}
+		}
+
+		if len(regions) > 0 {
+			s := stack
+			s.Regions = regions
+			ret = append(ret, s)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,49 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestCanaryStacks(t *testing.T) {
+	stacks := []schemas.Stack{
+		{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}, {Region: "us-east-1"}}},
+		{Stack: "artp", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}},
+	}
+
+	canaries := []canaryPipeline{
+		{status: deployer.CanaryStatus{Stack: "artd", Region: "us-east-1"}},
+	}
+
+	expected := []schemas.Stack{
+		{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "us-east-1"}}},
+	}
+
+	if diff := deep.Equal(canaryStacks(stacks, canaries), expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if len(stacks[0].Regions) != 2 {
+		t.Errorf("stacks of manifest should not be changed: %v", stacks[0].Regions)
+	}
+}
@@ -0,0 +1,469 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	Logger "github.com/sirupsen/logrus"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// deployStep is a step of deployment pipeline after the new version is ready
+type deployStep struct {
+	step     int64
+	name     string
+	message  string
+	exitCode int
+	run      func(context.Context, schemas.Config) error
+}
+
+// deploySteps returns steps after health checking in order
+func deploySteps(d deployer.DeployManager) []deployStep {
+	return []deployStep{
+		{step: constants.StepAdditionalWork, name: "StepFinishAdditionalWork", message: "finish additional work", exitCode: constants.ExitCodeAdditionalWorkFailure, run: d.FinishAdditionalWork},
+		{step: constants.StepCanary, name: "StepCanary", message: "canary steps", exitCode: constants.ExitCodeCanaryFailure, run: d.RunCanarySteps},
+		{step: constants.StepBake, name: "StepBake", message: "bake", exitCode: constants.ExitCodeBakeFailure, run: d.GetDeployer().Bake},
+		{step: constants.StepTriggerLifecycleCallback, name: "StepTriggerLifecycleCallbacks", message: "trigger lifecycle callbacks", exitCode: constants.ExitCodeLifecycleCallbackFailure, run: d.TriggerLifecycleCallbacks},
+		{step: constants.StepCleanPreviousVersion, name: "StepCleanPreviousVersion", message: "clean previous version", exitCode: constants.ExitCodeCleanFailure, run: d.CleanPreviousVersion},
+		{step: constants.StepCleanChecking, name: "StepCleanChecking", message: "clean checking", exitCode: constants.ExitCodeCleanFailure, run: d.CleanChecking},
+		{step: constants.StepGatherMetrics, name: "StepGatherMetrics", message: "gather metrics", exitCode: constants.ExitCodeMetricsFailure, run: d.GatherMetrics},
+		{step: constants.StepRunAPI, name: "StepRunAPITest", message: "API test", exitCode: constants.ExitCodeAPITestFailure, run: d.RunAPITest},
+	}
+}
+
+// newDeployers creates a deployer for every pair of stack and region so that each of them runs its own pipeline
+func (r Runner) newDeployers() []deployer.DeployManager {
+	var deployers []deployer.DeployManager
+	for _, stack := range r.Builder.Stacks {
+		if r.Builder.Config.Stack != "" && stack.Stack != r.Builder.Config.Stack {
+			r.Logger.Debugf("Skipping this stack, stack=%s", stack.Stack)
+			continue
+		}
+
+		for _, region := range stack.Regions {
+			if r.Builder.Config.Region != "" && region.Region != r.Builder.Config.Region {
+				r.Logger.Debugf("Skipping this region, stack=%s, region=%s", stack.Stack, region.Region)
+				continue
+			}
+
+			s := stack
+			s.Regions = []schemas.RegionConfig{region}
+
+			r.Logger.Debugf("add deployer setup function : %s", state.StackKey(s))
+			deployers = append(deployers, getDeployer(r.Logger, s, r.Builder.AwsConfig, r.Builder.APITestTemplates, r.Builder.Config.Region, r.Slacker, r.Collector))
+		}
+	}
+
+	return deployers
+}
+
+// rollout is a group of deployers of a stack which are deployed wave by wave
+type rollout struct {
+	stack     string
+	dependsOn []string
+	waves     [][]deployer.DeployManager
+	bakeTime  time.Duration
+}
+
+// newRollouts groups deployers by stack and splits them into waves with rollout strategy of the stack
+func newRollouts(deployers []deployer.DeployManager) []rollout {
+	var rollouts []rollout
+	index := map[string]int{}
+	for _, d := range deployers {
+		stack := d.GetDeployer().Stack
+		i, ok := index[stack.Stack]
+		if !ok {
+			ro := rollout{stack: stack.Stack, dependsOn: stack.DependsOn, waves: make([][]deployer.DeployManager, 1)}
+			if stack.Rollout != nil && stack.Rollout.Strategy == constants.WavesRollout && len(stack.Rollout.Waves) > 0 {
+				ro.waves = make([][]deployer.DeployManager, len(stack.Rollout.Waves))
+				ro.bakeTime = stack.Rollout.BakeTime
+			}
+			rollouts = append(rollouts, ro)
+			i = len(rollouts) - 1
+			index[stack.Stack] = i
+		}
+
+		w := waveIndex(stack)
+		rollouts[i].waves[w] = append(rollouts[i].waves[w], d)
+	}
+
+	// waves can be empty if regions are skipped by user
+	for i := range rollouts {
+		var waves [][]deployer.DeployManager
+		for _, wave := range rollouts[i].waves {
+			if len(wave) > 0 {
+				waves = append(waves, wave)
+			}
+		}
+		rollouts[i].waves = waves
+	}
+
+	return rollouts
+}
+
+// waveIndex returns the index of wave which the region of stack belongs to
+func waveIndex(stack schemas.Stack) int {
+	if stack.Rollout == nil || stack.Rollout.Strategy != constants.WavesRollout || len(stack.Regions) == 0 {
+		return 0
+	}
+
+	for i, wave := range stack.Rollout.Waves {
+		if tool.IsStringInArray(stack.Regions[0].Region, wave) {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// pipelines returns pipeline names of waves from the index
+func (ro rollout) pipelines(from int) []string {
+	var names []string
+	for _, wave := range ro.waves[from:] {
+		names = append(names, pipelineNames(wave)...)
+	}
+
+	return names
+}
+
+// scheduler runs pipelines of deployers with maxParallel limit and aggregates errors of all pipelines
+type scheduler struct {
+	ctx      context.Context
+	logger   *Logger.Logger
+	sem      chan struct{}
+	mu       sync.Mutex
+	errs     []error
+	finished []deployer.DeployManager
+}
+
+// newScheduler creates a scheduler
+// If maxParallel is 0, the number of pipelines running at the same time is not limited.
+func newScheduler(ctx context.Context, logger *Logger.Logger, maxParallel int64) *scheduler {
+	s := &scheduler{
+		ctx:    ctx,
+		logger: logger,
+	}
+
+	if maxParallel > 0 {
+		s.sem = make(chan struct{}, maxParallel)
+	}
+
+	return s
+}
+
+// run runs pipeline of each deployer concurrently and returns true if all pipelines are finished without error
+// Pipelines which are not started yet are skipped when the context is cancelled.
+func (s *scheduler) run(deployers []deployer.DeployManager, pipeline func(deployer.DeployManager) error) bool {
+	wg := sync.WaitGroup{}
+	succeeded := true
+
+	for _, d := range deployers {
+		wg.Add(1)
+		go func(d deployer.DeployManager) {
+			defer wg.Done()
+			if s.sem != nil {
+				select {
+				case s.sem <- struct{}{}:
+					defer func() { <-s.sem }()
+				case <-s.ctx.Done():
+				}
+			}
+
+			s.mu.Lock()
+			cancelled := s.ctx.Err() != nil
+			if cancelled {
+				succeeded = false
+			}
+			s.mu.Unlock()
+			if cancelled {
+				return
+			}
+
+			err := pipeline(d)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.ctx.Err() == nil || !errors.Is(err, s.ctx.Err()) {
+				s.finished = append(s.finished, d)
+			}
+
+			if err != nil {
+				succeeded = false
+				s.errs = append(s.errs, fmt.Errorf("[%s] %w", d.GetDeployer().GetPipelineName(), err))
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	return succeeded
+}
+
+// runRollouts runs rollouts of stacks in order of dependencies and waves of each rollout in order
+// Independent rollouts run at the same time, and a rollout starts after all rollouts of its dependencies succeed.
+// Dependencies which are not deployed together, for example with --stack, are not waited for.
+func (s *scheduler) runRollouts(rollouts []rollout, pipeline func(d deployer.DeployManager, gated bool) error) {
+	done := map[string]chan struct{}{}
+	for _, ro := range rollouts {
+		done[ro.stack] = make(chan struct{})
+	}
+
+	dependents := map[string]bool{}
+	for _, ro := range rollouts {
+		for _, dep := range ro.dependsOn {
+			dependents[dep] = true
+		}
+	}
+
+	succeeded := map[string]bool{}
+	wg := sync.WaitGroup{}
+	for _, ro := range rollouts {
+		wg.Add(1)
+		go func(ro rollout) {
+			defer wg.Done()
+			defer close(done[ro.stack])
+
+			for _, dep := range ro.dependsOn {
+				ch, ok := done[dep]
+				if !ok {
+					continue
+				}
+
+				s.logger.Infof("[%s] wait for the dependency to be deployed: %s", ro.stack, dep)
+				<-ch
+
+				s.mu.Lock()
+				ok = succeeded[dep]
+				s.mu.Unlock()
+				if !ok {
+					if s.ctx.Err() == nil {
+						s.fail(fmt.Errorf("[%s] stack is not deployed because the dependency is not deployed: %s", ro.stack, dep))
+					}
+					return
+				}
+			}
+
+			ok := s.runWaves(ro, dependents[ro.stack], pipeline)
+
+			s.mu.Lock()
+			succeeded[ro.stack] = ok
+			s.mu.Unlock()
+		}(ro)
+	}
+	wg.Wait()
+}
+
+// runWaves runs waves of a rollout in order and returns true if all waves are finished without error
+// Deployers are gated if they are followed by another wave or stacks depending on them,
+// so that the unhealthy version stops the rollout.
+func (s *scheduler) runWaves(ro rollout, gated bool, pipeline func(d deployer.DeployManager, gated bool) error) bool {
+	for i, wave := range ro.waves {
+		last := i == len(ro.waves)-1
+		if !s.run(wave, func(d deployer.DeployManager) error { return pipeline(d, gated || !last) }) {
+			if !last && s.ctx.Err() == nil {
+				s.fail(fmt.Errorf("[%s] rollout is stopped at wave %d, following pipelines are not deployed: %s", ro.stack, i+1, strings.Join(ro.pipelines(i+1), ", ")))
+			}
+			return false
+		}
+
+		if last {
+			break
+		}
+
+		if ro.bakeTime > 0 {
+			s.logger.Infof("[%s] wave %d is finished, wait %s before the next wave", ro.stack, i+1, ro.bakeTime)
+			if err := tool.SleepWithContext(s.ctx, ro.bakeTime); err != nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// fail adds error of pipeline
+func (s *scheduler) fail(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.errs = append(s.errs, err)
+	s.mu.Unlock()
+}
+
+// err returns aggregated errors of all pipelines
+func (s *scheduler) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return errors.Join(s.errs...)
+}
+
+// interrupted returns deployers which are not finished because the context is cancelled
+func (s *scheduler) interrupted(deployers []deployer.DeployManager) []deployer.DeployManager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return excludeDeployers(deployers, s.finished)
+}
+
+// runPipelines runs pipeline of each deployer concurrently with maxParallel limit and aggregates errors of all pipelines
+func runPipelines(ctx context.Context, deployers []deployer.DeployManager, maxParallel int64, pipeline func(deployer.DeployManager) error) error {
+	s := newScheduler(ctx, Logger.StandardLogger(), maxParallel)
+	s.run(deployers, pipeline)
+
+	return s.err()
+}
+
+// runDeployRollouts runs deployment pipelines of deployers with rollout strategy of each stack
+// It returns deployers which are not finished because the context is cancelled.
+func runDeployRollouts(ctx context.Context, logger *Logger.Logger, deployers []deployer.DeployManager, maxParallel int64, pipeline func(d deployer.DeployManager, gated bool) error) ([]deployer.DeployManager, error) {
+	s := newScheduler(ctx, logger, maxParallel)
+	s.runRollouts(newRollouts(deployers), pipeline)
+
+	return s.interrupted(deployers), s.err()
+}
+
+// deployPipeline runs all deployment steps of a deployer
+// Only errors before the new version becomes healthy stop the pipeline unless auto rollback or strict mode is enabled.
+// A breach during bake or canary steps always rolls back the new version because the previous version is still serving.
+// Rejection or timeout of approval gate also rolls back the new version.
+// If the pipeline is gated, the unhealthy version also stops the pipeline so that the rollout does not go further.
+func (r Runner) deployPipeline(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder, gated bool) error {
+	config := r.Builder.Config
+
+	if err := r.tracker.track(d, "StepCheckPrevious", func() error { return d.CheckPreviousResources(ctx, config) }); err != nil {
+		r.Logger.Errorf("[StepCheckPrevious] check previous deployer error occurred: %s", err.Error())
+		return stepError(ctx, "StepCheckPrevious", constants.ExitCodeDeployFailure, err)
+	}
+	recorder.Record(d.GetDeployer().ExportState())
+
+	if err := r.tracker.track(d, "StepDeploy", func() error { return d.Deploy(ctx, config) }); err != nil {
+		r.Logger.Errorf("[StepDeploy] deploy step error occurred: %s", err.Error())
+		return stepError(ctx, "StepDeploy", constants.ExitCodeDeployFailure, err)
+	}
+	recorder.Record(d.GetDeployer().ExportState())
+
+	if err := r.tracker.track(d, "StepHealthCheck", func() error { return d.HealthChecking(ctx, config) }); err != nil {
+		if err := r.failHealthCheck(ctx, d, recorder, gated, err); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range deploySteps(d) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if approvalBefore(d, s.step) && d.GetDeployer().StepStatus[constants.StepDeploy] {
+			if err := r.tracker.track(d, approvalGate, func() error { return r.waitForApproval(ctx, d, recorder) }); err != nil {
+				return r.failApproval(ctx, d, recorder, err)
+			}
+		}
+
+		if err := r.tracker.track(d, s.name, func() error { return s.run(ctx, config) }); err != nil {
+			if err := r.failStep(ctx, d, recorder, s, err); err != nil {
+				return err
+			}
+		}
+		recorder.Record(d.GetDeployer().ExportState())
+	}
+
+	return nil
+}
+
+// failHealthCheck applies the failure policy of health check to the pipeline
+// It returns nil if the pipeline goes on with the unhealthy version.
+func (r Runner) failHealthCheck(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder, gated bool, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.Logger.Errorf("[StepHealthCheck] check new deployment error occurred: %s", err.Error())
+	failure := &StepError{Step: "StepHealthCheck", ExitCode: constants.ExitCodeHealthCheckFailure, Err: err}
+	if r.Builder.Config.AutoRollback {
+		return r.rollbackPipeline(ctx, d, recorder, failure)
+	}
+
+	if gated || r.Builder.Config.Strict {
+		return failure
+	}
+
+	return nil
+}
+
+// failApproval rolls back the new version when the approval gate is rejected or timed out
+func (r Runner) failApproval(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.Logger.Errorf("[%s] approval error occurred: %s", approvalGate, err.Error())
+	return r.rollbackPipeline(ctx, d, recorder, &StepError{Step: approvalGate, ExitCode: constants.ExitCodeApprovalRejected, Err: err})
+}
+
+// failStep applies the failure policy of a step after health checking to the pipeline
+// It returns nil if the pipeline goes on to the next step.
+func (r Runner) failStep(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder, s deployStep, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.Logger.Errorf("[%s] %s error occurred: %s", s.name, s.message, err.Error())
+	failure := &StepError{Step: s.name, ExitCode: s.exitCode, Err: err}
+	if s.step == constants.StepBake || s.step == constants.StepCanary || (s.step == constants.StepAdditionalWork && r.Builder.Config.AutoRollback) {
+		return r.rollbackPipeline(ctx, d, recorder, failure)
+	}
+
+	if r.Builder.Config.Strict {
+		return failure
+	}
+
+	return nil
+}
+
+// rollbackPipeline rolls back the new autoscaling group of deployer which is not healthy
+func (r Runner) rollbackPipeline(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder, failure *StepError) error {
+	defer recorder.Forget(d.GetDeployer().GetPipelineName())
+
+	if err := r.tracker.track(d, "StepRollback", func() error { return d.Rollback(ctx, r.Builder.Config) }); err != nil {
+		r.Logger.Errorf("[StepRollback] rollback error occurred: %s", err.Error())
+		return &StepError{Step: "StepRollback", ExitCode: constants.ExitCodeRollbackFailure, Err: fmt.Errorf("rollback failed: %s", err.Error())}
+	}
+
+	return &StepError{Step: failure.Step, ExitCode: failure.ExitCode, Err: fmt.Errorf("deployment is rolled back: %w", failure.Err)}
+}
+
+// stepError returns the error of context if the step failed because of cancellation
+func stepError(ctx context.Context, step string, exitCode int, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return &StepError{Step: step, ExitCode: exitCode, Err: err}
+}
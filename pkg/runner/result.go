@@ -0,0 +1,176 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+)
+
+// StepError is the error of a deployment step with the exit code of its failure class
+type StepError struct {
+	Step     string
+	ExitCode int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Step, e.Err.Error())
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of error
+// If errors of several pipelines are joined, the exit code of the first step error is used.
+func ExitCode(err error) int {
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		return stepErr.ExitCode
+	}
+
+	return constants.ExitCodeFailure
+}
+
+// DeploymentResult is the content of result file
+type DeploymentResult struct {
+	ID        string `json:",omitempty"`
+	Status    string
+	ExitCode  int
+	Error     string `json:",omitempty"`
+	Results   []deployer.Result
+	Approvals []state.ApprovalEvent `json:",omitempty"`
+}
+
+// resultTracker keeps timings and errors of steps of every pipeline
+type resultTracker struct {
+	mu    sync.Mutex
+	steps map[string][]deployer.StepResult
+}
+
+// newResultTracker creates a result tracker
+func newResultTracker() *resultTracker {
+	return &resultTracker{
+		steps: map[string][]deployer.StepResult{},
+	}
+}
+
+// track runs a step of pipeline and records its timing and error
+func (t *resultTracker) track(d deployer.DeployManager, name string, run func() error) error {
+	if t == nil {
+		return run()
+	}
+
+	startedAt := time.Now()
+	err := run()
+
+	step := deployer.StepResult{
+		Name:            name,
+		StartedAt:       startedAt,
+		DurationSeconds: time.Since(startedAt).Seconds(),
+	}
+
+	if err != nil {
+		step.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := d.GetDeployer().GetPipelineName()
+	t.steps[key] = append(t.steps[key], step)
+
+	return err
+}
+
+// results returns results of deployers with tracked steps
+func (t *resultTracker) results(deployers []deployer.DeployManager, r Runner) []deployer.Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var results []deployer.Result
+	for _, d := range deployers {
+		steps := t.steps[d.GetDeployer().GetPipelineName()]
+		for _, result := range d.GetDeployer().ExportResults(r.Builder.Config) {
+			result.Steps = steps
+			result.Status = constants.ResultSucceeded
+			for _, step := range steps {
+				if len(step.Error) > 0 {
+					result.Status = constants.ResultFailed
+					result.Errors = append(result.Errors, fmt.Sprintf("[%s] %s", step.Name, step.Error))
+				}
+			}
+
+			if len(steps) == 0 {
+				result.Status = constants.ResultSkipped
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// writeResultFile writes the result of deployment to the result file
+func (r Runner) writeResultFile(deployers []deployer.DeployManager, recorder *state.Recorder, err error) error {
+	if len(r.Builder.Config.ResultFile) == 0 {
+		return nil
+	}
+
+	result := DeploymentResult{
+		Status:  constants.ResultSucceeded,
+		Results: []deployer.Result{},
+	}
+
+	if recorder != nil {
+		result.ID = recorder.State.ID
+		result.Approvals = recorder.State.Approvals
+	}
+
+	if err != nil {
+		result.Status = constants.ResultFailed
+		result.ExitCode = ExitCode(err)
+		result.Error = err.Error()
+	}
+
+	if r.tracker != nil {
+		if results := r.tracker.results(deployers, r); results != nil {
+			result.Results = results
+		}
+	}
+
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(r.Builder.Config.ResultFile, b, 0644); err != nil {
+		return err
+	}
+	r.Logger.Infof("result is saved: %s", r.Builder.Config.ResultFile)
+
+	return nil
+}
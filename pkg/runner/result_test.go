@@ -0,0 +1,119 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	Logger "github.com/sirupsen/logrus"
+
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestExitCode(t *testing.T) {
+	healthCheck := &StepError{Step: "StepHealthCheck", ExitCode: constants.ExitCodeHealthCheckFailure, Err: errors.New("timeout")}
+	apiTest := &StepError{Step: "StepRunAPITest", ExitCode: constants.ExitCodeAPITestFailure, Err: errors.New("status code 500")}
+
+	testData := []struct {
+		err      error
+		expected int
+	}{
+		{err: errors.New("unknown error"), expected: constants.ExitCodeFailure},
+		{err: healthCheck, expected: constants.ExitCodeHealthCheckFailure},
+		{err: fmt.Errorf("[artd/ap-northeast-2] %w", apiTest), expected: constants.ExitCodeAPITestFailure},
+		{err: errors.Join(errors.New("unknown error"), fmt.Errorf("[artd/ap-northeast-2] %w", apiTest), healthCheck), expected: constants.ExitCodeAPITestFailure},
+		{err: context.Canceled, expected: constants.ExitCodeFailure},
+	}
+
+	for _, td := range testData {
+		if code := ExitCode(td.err); code != td.expected {
+			t.Errorf("expected %d, got %d: %s", td.expected, code, td.err.Error())
+		}
+	}
+
+	if healthCheck.Error() != "[StepHealthCheck] timeout" {
+		t.Errorf("unexpected error message: %s", healthCheck.Error())
+	}
+}
+
+func TestWriteResultFile(t *testing.T) {
+	resultFile := filepath.Join(t.TempDir(), "result.json")
+	r := Runner{
+		Logger:  Logger.New(),
+		Builder: builder.Builder{Config: schemas.Config{ResultFile: resultFile}},
+		tracker: newResultTracker(),
+	}
+
+	deployed := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "backend", Regions: []schemas.RegionConfig{{Region: "us-east-1"}}}}}
+	failed := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "frontend", Regions: []schemas.RegionConfig{{Region: "us-east-1"}}}}}
+	skipped := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "batch", Regions: []schemas.RegionConfig{{Region: "us-east-1"}}}}}
+
+	r.tracker.track(deployed, "StepDeploy", func() error { return nil })
+	r.tracker.track(failed, "StepDeploy", func() error { return nil })
+	err := r.tracker.track(failed, "StepHealthCheck", func() error { return errors.New("timeout") })
+	if err == nil {
+		t.Fatal("error of step is not returned")
+	}
+
+	err = &StepError{Step: "StepHealthCheck", ExitCode: constants.ExitCodeHealthCheckFailure, Err: err}
+	if err := r.writeResultFile([]deployer.DeployManager{deployed, failed, skipped}, nil, err); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(resultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result DeploymentResult
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != constants.ResultFailed || result.ExitCode != constants.ExitCodeHealthCheckFailure {
+		t.Errorf("unexpected status of deployment: %s(%d)", result.Status, result.ExitCode)
+	}
+
+	expected := map[string]string{
+		"backend":  constants.ResultSucceeded,
+		"frontend": constants.ResultFailed,
+		"batch":    constants.ResultSkipped,
+	}
+
+	if len(result.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(result.Results))
+	}
+
+	for _, res := range result.Results {
+		if res.Status != expected[res.Stack] {
+			t.Errorf("expected %s for %s, got %s", expected[res.Stack], res.Stack, res.Status)
+		}
+	}
+
+	if len(result.Results[1].Steps) != 2 || len(result.Results[1].Errors) != 1 || result.Results[1].Errors[0] != "[StepHealthCheck] timeout" {
+		t.Errorf("unexpected steps of failed pipeline: %v", result.Results[1])
+	}
+}
@@ -0,0 +1,226 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/collector"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+// Resume continues the deployment with saved state
+func Resume(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: goployer resume <deployment id>")
+	}
+
+	builderSt, err := builder.NewBuilder(nil)
+	if err != nil {
+		return err
+	}
+
+	m, err := builder.ParseMetricConfig(builderSt.Config.DisableMetrics, constants.MetricYamlPath)
+	if err != nil {
+		return err
+	}
+
+	store, err := state.New(builderSt.Config, m)
+	if err != nil {
+		return err
+	}
+
+	if store == nil {
+		return errors.New("state backend is disabled")
+	}
+
+	st, err := store.Load(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %s", err.Error(), args[0])
+	}
+
+	// Configuration of the original deployment is used except options for resume command
+	config := st.Config
+	config.StartTimestamp = time.Now().Unix()
+	config.AutoApply = builderSt.Config.AutoApply
+	config.LogLevel = builderSt.Config.LogLevel
+	config.StateBackend = builderSt.Config.StateBackend
+	config.StateLocation = builderSt.Config.StateLocation
+	config.OnInterrupt = builderSt.Config.OnInterrupt
+	config.Strict = config.Strict || builderSt.Config.Strict
+	config.ResultFile = builderSt.Config.ResultFile
+	if builderSt.Config.MaxParallel > 0 {
+		config.MaxParallel = builderSt.Config.MaxParallel
+	}
+
+	if config.DisableMetrics {
+		m = schemas.MetricConfig{Enabled: false}
+	}
+
+	builderSt.Config = config
+	builderSt.AwsConfig = st.AwsConfig
+	builderSt.APITestTemplates = st.APITestTemplates
+	builderSt.MetricConfig = m
+	builderSt.Stacks = nil
+	for _, ss := range st.Stacks {
+		builderSt.Stacks = append(builderSt.Stacks, ss.Stack)
+	}
+
+	r, err := NewRunner(builderSt, "resume")
+	if err != nil {
+		return err
+	}
+	r.Collector = collector.NewCollector(m, config.AssumeRole)
+	r.LogFormatting(config.LogLevel)
+
+	return r.resume(ctx, store, *st)
+}
+
+// resume runs the steps of deployers which are not finished
+func (r Runner) resume(ctx context.Context, store state.Store, st state.State) (err error) {
+	if err := tool.LocalCheck(fmt.Sprintf("Do you really want to resume the deployment %s? ", st.ID), r.Builder.Config.AutoApply); err != nil {
+		return err
+	}
+
+	lease, err := r.acquireLock("resume", r.lockKeys())
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	ctx, cancel := lease.WithContext(ctx)
+	defer cancel()
+
+	recorder := state.NewRecorder(store, st)
+	recorder.State.CreatedAt = st.CreatedAt
+	defer func() {
+		recorder.Finish(err)
+	}()
+
+	r.Logger.Infof("Resuming deployment: %s", st.ID)
+	if !r.Slacker.ValidClient() {
+		r.Slacker.SlackOff = true
+	}
+
+	if r.Builder.MetricConfig.Enabled {
+		if err := r.CheckEnabledMetrics(); err != nil {
+			return err
+		}
+	}
+
+	var deployers []deployer.DeployManager
+	for _, ss := range st.Stacks {
+		d := getDeployer(r.Logger, ss.Stack, r.Builder.AwsConfig, r.Builder.APITestTemplates, r.Builder.Config.Region, r.Slacker, r.Collector)
+		d.GetDeployer().ImportState(ss)
+		deployers = append(deployers, d)
+	}
+
+	if hasApproval(deployers) {
+		r.terminal = newTerminalApprover()
+	}
+
+	r.tracker = newResultTracker()
+	defer func() {
+		if err := r.writeResultFile(deployers, recorder, err); err != nil {
+			r.Logger.Errorf("failed to write result file: %s", err.Error())
+		}
+	}()
+
+	interrupted, err := runDeployRollouts(ctx, r.Logger, deployers, r.Builder.Config.MaxParallel, func(d deployer.DeployManager, gated bool) error {
+		err := r.resumeDeployer(ctx, d, recorder, gated)
+		if err != nil {
+			r.Logger.Errorf("[%s] resume error occurred: %s", d.GetDeployer().GetPipelineName(), err.Error())
+		}
+		return err
+	})
+
+	if lease.Err() != nil {
+		return r.handleLockLost(interrupted, recorder, lease.Err())
+	}
+
+	if ctx.Err() != nil {
+		return r.handleInterrupt(interrupted, recorder)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.Logger.Infof("resume operation is finished")
+	return nil
+}
+
+// resumeDeployer runs steps of a deployer from the first incomplete step
+// If the deploy step was not finished, the new version is removed because it cannot be resumed.
+// Deployers which were not started, like the following waves of a failed rollout, run the whole pipeline.
+// Failures of steps are handled with the same policy as deployPipeline.
+func (r Runner) resumeDeployer(ctx context.Context, d deployer.DeployManager, recorder *state.Recorder, gated bool) error {
+	config := r.Builder.Config
+	dp := d.GetDeployer()
+
+	if !dp.StepStatus[constants.StepCheckPrevious] {
+		return r.deployPipeline(ctx, d, recorder, gated)
+	}
+
+	if !dp.StepStatus[constants.StepDeploy] {
+		r.Logger.Warnf("deploy step of %s was not finished, the new version is removed", dp.GetPipelineName())
+		if err := r.tracker.track(d, "StepRollback", func() error { return dp.CleanIncompleteDeployment(ctx, config) }); err != nil {
+			return stepError(ctx, "StepRollback", constants.ExitCodeRollbackFailure, err)
+		}
+		recorder.Forget(dp.GetPipelineName())
+
+		return &StepError{Step: "StepDeploy", ExitCode: constants.ExitCodeDeployFailure, Err: fmt.Errorf("deployment of %s cannot be resumed, please deploy again", dp.GetPipelineName())}
+	}
+
+	if !dp.StepStatus[constants.StepAdditionalWork] {
+		if err := r.tracker.track(d, "StepHealthCheck", func() error { return d.HealthChecking(ctx, config) }); err != nil {
+			if err := r.failHealthCheck(ctx, d, recorder, gated, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s := range deploySteps(d) {
+		if dp.StepStatus[s.step] {
+			r.Logger.Debugf("[%s] step is already finished: %s", s.name, dp.GetPipelineName())
+			continue
+		}
+
+		if approvalBefore(d, s.step) {
+			if err := r.tracker.track(d, approvalGate, func() error { return r.waitForApproval(ctx, d, recorder) }); err != nil {
+				return r.failApproval(ctx, d, recorder, err)
+			}
+		}
+
+		if err := r.tracker.track(d, s.name, func() error { return s.run(ctx, config) }); err != nil {
+			if err := r.failStep(ctx, d, recorder, s, err); err != nil {
+				return err
+			}
+		}
+		recorder.Record(dp.ExportState())
+	}
+
+	return nil
+}
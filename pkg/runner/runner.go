@@ -17,6 +17,8 @@ limitations under the license.
 package runner
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -37,18 +39,27 @@ import (
 	"github.com/DevopsArtFactory/goployer/pkg/helper"
 	"github.com/DevopsArtFactory/goployer/pkg/initializer"
 	"github.com/DevopsArtFactory/goployer/pkg/inspector"
+	"github.com/DevopsArtFactory/goployer/pkg/lock"
 	"github.com/DevopsArtFactory/goployer/pkg/refresh"
 	"github.com/DevopsArtFactory/goployer/pkg/schemas"
 	"github.com/DevopsArtFactory/goployer/pkg/slack"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 )
 
+const (
+	interruptRollbackOption = "abort and roll back the new autoscaling groups"
+	interruptDetachOption   = "detach and leave as is"
+)
+
 type Runner struct {
 	Logger     *Logger.Logger
 	Builder    builder.Builder
 	Collector  collector.Collector
 	Slacker    slack.Slack
-	FuncMapper map[string]func() error
+	FuncMapper map[string]func(ctx context.Context) error
+	tracker    *resultTracker
+	terminal   *terminalApprover
 }
 
 // NewRunner creates a new runner
@@ -63,12 +74,13 @@ func NewRunner(newBuilder builder.Builder, mode string) (Runner, error) {
 		newRunner.Collector = collector.NewCollector(newBuilder.MetricConfig, newBuilder.Config.AssumeRole)
 	}
 
-	newRunner.FuncMapper = map[string]func() error{
-		"deploy":  newRunner.Deploy,
-		"delete":  newRunner.Delete,
-		"status":  newRunner.Status,
-		"update":  newRunner.Update,
-		"refresh": newRunner.Refresh,
+	newRunner.FuncMapper = map[string]func(ctx context.Context) error{
+		"deploy":   newRunner.Deploy,
+		"delete":   newRunner.Delete,
+		"status":   newRunner.Status,
+		"update":   newRunner.Update,
+		"refresh":  newRunner.Refresh,
+		"rollback": newRunner.Rollback,
 	}
 
 	return newRunner, nil
@@ -202,8 +214,61 @@ func AddManifest(args []string) error {
 	return nil
 }
 
+// Unlock shows the holder of deployment lock or removes it with --force
+func Unlock(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: goployer unlock <lock key> [--force]")
+	}
+	key := args[0]
+
+	builderSt, err := builder.NewBuilder(nil)
+	if err != nil {
+		return err
+	}
+
+	m, err := builder.ParseMetricConfig(builderSt.Config.DisableMetrics, constants.MetricYamlPath)
+	if err != nil {
+		return err
+	}
+
+	locker, err := lock.New(builderSt.Config, m)
+	if err != nil {
+		return err
+	}
+
+	if locker == nil {
+		return errors.New("lock backend is disabled")
+	}
+
+	holder, err := locker.Get(key)
+	if err != nil {
+		return err
+	}
+
+	if holder == nil {
+		fmt.Printf("no lock exists: %s\n", key)
+		return nil
+	}
+
+	fmt.Printf("%s is locked by %s for %s since %s (expires at %s)\n", key, holder.Owner, holder.Operation, holder.AcquiredAt.Format(time.RFC3339), holder.ExpiresAt.Format(time.RFC3339))
+	if !builderSt.Config.ForceUnlock {
+		return errors.New("use --force to remove the lock")
+	}
+
+	if err := tool.LocalCheck("Do you really want to remove the lock? ", builderSt.Config.AutoApply); err != nil {
+		return err
+	}
+
+	if err := locker.ForceRelease(key); err != nil {
+		return err
+	}
+
+	fmt.Printf("lock is removed: %s\n", key)
+	return nil
+}
+
 // Start function is the starting point of all processes.
-func Start(builderSt builder.Builder, mode string) error {
+func Start(ctx context.Context, builderSt builder.Builder, mode string) error {
 	if checkBuilderConfigurationNeeded(mode) {
 		// Check validation of configurations
 		if err := builderSt.CheckValidation(); err != nil {
@@ -212,9 +277,9 @@ func Start(builderSt builder.Builder, mode string) error {
 	}
 
 	// run with runner
-	return withRunner(builderSt, mode, func(slacker slack.Slack) error {
+	return withRunner(ctx, builderSt, mode, func(slacker slack.Slack) error {
 		// These are post actions after deployment
-		if !builderSt.Config.SlackOff {
+		if !builderSt.Config.SlackOff && !builderSt.Config.DryRun {
 			if mode == "deploy" {
 				slacker.SendSimpleMessage(fmt.Sprintf(":100: Deployment is done: %s", builderSt.AwsConfig.Name))
 			}
@@ -222,6 +287,10 @@ func Start(builderSt builder.Builder, mode string) error {
 			if mode == "delete" {
 				slacker.SendSimpleMessage(fmt.Sprintf(":100: Delete process is done: %s", builderSt.AwsConfig.Name))
 			}
+
+			if mode == "rollback" {
+				slacker.SendSimpleMessage(fmt.Sprintf(":100: Rollback is done: %s", builderSt.AwsConfig.Name))
+			}
 		}
 
 		return nil
@@ -229,14 +298,14 @@ func Start(builderSt builder.Builder, mode string) error {
 }
 
 // withRunner creates runner and runs the deployment process
-func withRunner(builderSt builder.Builder, mode string, postAction func(slacker slack.Slack) error) error {
+func withRunner(ctx context.Context, builderSt builder.Builder, mode string, postAction func(slacker slack.Slack) error) error {
 	runner, err := NewRunner(builderSt, mode)
 	if err != nil {
 		return err
 	}
 	runner.LogFormatting(builderSt.Config.LogLevel)
 
-	if err := runner.Run(mode); err != nil {
+	if err := runner.Run(ctx, mode); err != nil {
 		return err
 	}
 
@@ -250,16 +319,16 @@ func (r Runner) LogFormatting(logLevel string) {
 }
 
 // Run executes all required steps for deployments
-func (r Runner) Run(mode string) error {
+func (r Runner) Run(ctx context.Context, mode string) error {
 	f, ok := r.FuncMapper[mode]
 	if !ok {
 		return fmt.Errorf("no function exists to run for %s", mode)
 	}
-	return f()
+	return f(ctx)
 }
 
 // Deploy is the main function of `goployer deploy`
-func (r Runner) Deploy() error {
+func (r Runner) Deploy(ctx context.Context) (err error) {
 	out := os.Stdout
 	defer func() {
 		if err := recover(); err != nil {
@@ -268,10 +337,32 @@ func (r Runner) Deploy() error {
 		}
 	}()
 
+	if r.Builder.Config.DryRun {
+		return r.DryRun("deploy")
+	}
+
 	if err := tool.LocalCheck("Do you really want to deploy this application? ", r.Builder.Config.AutoApply); err != nil {
 		return err
 	}
 
+	lease, err := r.acquireLock("deploy", r.lockKeys())
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	// the deployment stops if the lock is taken by others in the middle of deployment
+	ctx, cancel := lease.WithContext(ctx)
+	defer cancel()
+
+	recorder, err := r.newStateRecorder("deploy")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		recorder.Finish(err)
+	}()
+
 	// Send Beginning Message
 	r.Logger.Infof("Beginning deployment: %s", r.Builder.AwsConfig.Name)
 
@@ -302,122 +393,261 @@ func (r Runner) Deploy() error {
 		}
 	}
 
-	r.Logger.Debugf("create wait group for deployer setup")
-	wg := sync.WaitGroup{}
-
 	// Prepare deployers
-	r.Logger.Debug("create deployers for stacks")
-	var deployers []deployer.DeployManager
+	r.Logger.Debug("create deployers for stacks and regions")
+	deployers := r.newDeployers()
+	r.Logger.Debugf("successfully assign deployer to stacks")
+
+	if hasApproval(deployers) {
+		r.terminal = newTerminalApprover()
+	}
+
+	r.tracker = newResultTracker()
+	defer func() {
+		if err := r.writeResultFile(deployers, recorder, err); err != nil {
+			r.Logger.Errorf("failed to write result file: %s", err.Error())
+		}
+	}()
+
+	// every pipeline is recorded before it starts so that waves which are not deployed yet can be resumed
+	for _, d := range deployers {
+		recorder.Record(d.GetDeployer().ExportState())
+	}
+
+	interrupted, err := runDeployRollouts(ctx, r.Logger, deployers, r.Builder.Config.MaxParallel, func(d deployer.DeployManager, gated bool) error {
+		return r.deployPipeline(ctx, d, recorder, gated)
+	})
+
+	if lease.Err() != nil {
+		return r.handleLockLost(interrupted, recorder, lease.Err())
+	}
+
+	if ctx.Err() != nil {
+		return r.handleInterrupt(interrupted, recorder)
+	}
+
+	return err
+}
+
+// DryRun prints what deploy or delete would do without changing any resources
+func (r Runner) DryRun(mode string) error {
+	r.Logger.Infof("Beginning dry-run of %s: %s", mode, r.Builder.AwsConfig.Name)
+
+	var plans []deployer.Plan
 	for _, stack := range r.Builder.Stacks {
 		if r.Builder.Config.Stack != "" && stack.Stack != r.Builder.Config.Stack {
 			r.Logger.Debugf("Skipping this stack, stack=%s", stack.Stack)
 			continue
 		}
 
-		r.Logger.Debugf("add deployer setup function : %s", stack.Stack)
-		deployers = append(deployers, getDeployer(r.Logger, stack, r.Builder.AwsConfig, r.Builder.APITestTemplates, r.Builder.Config.Region, r.Slacker, r.Collector))
+		d := getDeployer(r.Logger, stack, r.Builder.AwsConfig, r.Builder.APITestTemplates, r.Builder.Config.Region, r.Slacker, r.Collector)
+		if err := d.GetDeployer().CheckPrevious(r.Builder.Config); err != nil {
+			return err
+		}
+
+		var p []deployer.Plan
+		var err error
+		if mode == "delete" {
+			p, err = d.GetDeployer().PlanDeletion(r.Builder.Config)
+		} else {
+			p, err = d.GetDeployer().PlanDeployment(r.Builder.Config)
+		}
+		if err != nil {
+			return err
+		}
+		plans = append(plans, p...)
 	}
-	r.Logger.Debugf("successfully assign deployer to stacks")
 
-	errs := make(chan error)
-	// Check Previous Version
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.CheckPreviousResources(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepCheckPrevious] check previous deployer error occurred: %s", err.Error())
-				errs <- err
-			}
+	if err := deployer.PrintPlans(os.Stdout, plans); err != nil {
+		return err
+	}
 
-			if err := deployer.Deploy(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepDeploy] deploy step error occurred: %s", err.Error())
-				errs <- err
-			}
-		}(d)
+	planJSON, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return err
 	}
-	go func() {
-		wg.Wait()
-		close(errs)
-	}()
-	errFlag := checkError(errs)
-	if errFlag != nil {
-		return errFlag
+
+	if len(r.Builder.Config.PlanFile) == 0 {
+		fmt.Println(string(planJSON))
+		return nil
 	}
 
-	// Health checking step
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.HealthChecking(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepHealthCheck] check new deployment error occurred: %s", err.Error())
-			}
-		}(d)
+	if err := os.WriteFile(r.Builder.Config.PlanFile, planJSON, 0644); err != nil {
+		return err
 	}
-	wg.Wait()
+	r.Logger.Infof("plan is saved: %s", r.Builder.Config.PlanFile)
 
+	return nil
+}
+
+// handleInterrupt asks whether to roll back or detach the new autoscaling groups when the deployment is interrupted
+// The deployment record is updated with the choice and the state is kept for `goployer resume` if detached.
+func (r Runner) handleInterrupt(deployers []deployer.DeployManager, recorder *state.Recorder) error {
+	// context of the deployment is already cancelled
+	ctx := context.Background()
+	config := r.Builder.Config
+
+	action := selectInterruptAction(config.OnInterrupt)
+	r.Logger.Warnf("deployment is interrupted: %s", action)
+
+	var targets, detached []deployer.DeployManager
 	for _, d := range deployers {
+		// the new version cannot be rolled back after previous version is removed
+		if action == constants.InterruptRollback && !d.GetDeployer().StepStatus[constants.StepCleanPreviousVersion] {
+			targets = append(targets, d)
+		} else {
+			detached = append(detached, d)
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	var mu sync.Mutex
+	var rollbackFailed []string
+	for _, d := range targets {
 		wg.Add(1)
 		go func(deployer deployer.DeployManager) {
 			defer wg.Done()
-			// Attach scaling policy
-			if err := deployer.FinishAdditionalWork(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepFinishAdditionalWork] finish additional work error occurred: %s", err.Error())
+			dp := deployer.GetDeployer()
+			asgs := copyAsgNames(dp)
+
+			var err error
+			if dp.StepStatus[constants.StepDeploy] {
+				err = deployer.Rollback(ctx, config)
+			} else {
+				err = dp.CleanIncompleteDeployment(ctx, config)
 			}
 
-			if err := deployer.TriggerLifecycleCallbacks(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepTriggerLifecycleCallbacks] trigger lifecycle callbacks error occurred: %s", err.Error())
+			if err != nil {
+				r.Logger.Errorf("[StepRollback] rollback error occurred: %s", err.Error())
+				mu.Lock()
+				rollbackFailed = append(rollbackFailed, fmt.Sprintf("%s(%s)", dp.GetPipelineName(), err.Error()))
+				mu.Unlock()
+				return
 			}
 
-			if err := deployer.CleanPreviousVersion(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepCleanPreviousVersion] clean previous verson error occurred: %s", err.Error())
+			if dp.StepStatus[constants.StepDeploy] {
+				r.updateDeploymentStatus(asgs, constants.StatusAborted)
 			}
+			recorder.Forget(dp.GetPipelineName())
 		}(d)
 	}
 	wg.Wait()
 
-	// CleanChecking
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.CleanChecking(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepCleanChecking] clean checking error occurred: %s", err.Error())
-			}
-		}(d)
+	for _, d := range detached {
+		if d.GetDeployer().StepStatus[constants.StepDeploy] {
+			r.updateDeploymentStatus(copyAsgNames(d.GetDeployer()), constants.StatusDetached)
+		}
 	}
-	wg.Wait()
 
-	// gather metrics of previous version
+	if len(detached) > 0 {
+		recorder.Detach()
+	}
+
+	var err error
+	switch {
+	case len(rollbackFailed) > 0:
+		err = fmt.Errorf("deployment is interrupted and rollback failed: %s", strings.Join(rollbackFailed, ", "))
+	case len(detached) > 0:
+		err = fmt.Errorf("deployment is interrupted and detached: %s", strings.Join(pipelineNames(detached), ", "))
+	default:
+		err = fmt.Errorf("deployment is interrupted and rolled back: %s", strings.Join(pipelineNames(targets), ", "))
+	}
+
+	// error is not printed by command when the context is cancelled
+	r.Logger.Warn(err.Error())
+	return err
+}
+
+// handleLockLost detaches the deployment when the deployment lock is lost
+// Nothing is rolled back because another goployer may be changing the same autoscaling groups.
+func (r Runner) handleLockLost(deployers []deployer.DeployManager, recorder *state.Recorder, err error) error {
 	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.GatherMetrics(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepGatherMetrics] gather metrics error occurred: %s", err.Error())
-			}
-		}(d)
+		if d.GetDeployer().StepStatus[constants.StepDeploy] {
+			r.updateDeploymentStatus(copyAsgNames(d.GetDeployer()), constants.StatusDetached)
+		}
 	}
-	wg.Wait()
+	recorder.Detach()
+
+	return lockLostError(err)
+}
+
+// lockLostError returns the step error of the lost deployment lock
+func lockLostError(err error) error {
+	return &StepError{Step: "StepLock", ExitCode: constants.ExitCodeLockLost, Err: fmt.Errorf("operation is stopped because the lock is lost: %w", err)}
+}
 
-	// API Test
+// checkLease returns the error of the lost lock if the lease is lost, otherwise err is returned as it is
+func checkLease(lease *lock.Lease, err error) error {
+	if lease.Err() != nil {
+		return lockLostError(lease.Err())
+	}
+
+	return err
+}
+
+// updateDeploymentStatus updates status of the new autoscaling groups in the metric table
+func (r Runner) updateDeploymentStatus(asgs []string, status string) {
+	if !r.Builder.MetricConfig.Enabled {
+		return
+	}
+
+	for _, asg := range asgs {
+		if err := r.Collector.UpdateStatus(asg, status, nil); err != nil {
+			r.Logger.Errorf("Update status Error, %s : %s", err.Error(), asg)
+		}
+	}
+}
+
+// selectInterruptAction selects the action for the interrupted deployment with --on-interrupt option or interactive terminal
+// The new autoscaling groups are detached if the terminal is not available.
+func selectInterruptAction(onInterrupt string) string {
+	if onInterrupt == constants.InterruptRollback || onInterrupt == constants.InterruptDetach {
+		return onInterrupt
+	}
+
+	options := []string{interruptRollbackOption, interruptDetachOption}
+
+	var answer string
+	prompt := &survey.Select{
+		Message: "Deployment is interrupted. What do you want to do with the new autoscaling groups?",
+		Options: options,
+	}
+	if err := survey.AskOne(prompt, &answer); err != nil {
+		Logger.Warnf("cannot ask the action on interrupt, the deployment is detached: %s", err.Error())
+		return constants.InterruptDetach
+	}
+
+	if answer == interruptRollbackOption {
+		return constants.InterruptRollback
+	}
+
+	return constants.InterruptDetach
+}
+
+// copyAsgNames returns names of the new autoscaling groups of deployer
+func copyAsgNames(dp *deployer.Deployer) []string {
+	var asgs []string
+	for _, asg := range dp.AsgNames {
+		if len(asg) > 0 {
+			asgs = append(asgs, asg)
+		}
+	}
+
+	return asgs
+}
+
+// pipelineNames returns pipeline names of deployers
+func pipelineNames(deployers []deployer.DeployManager) []string {
+	var names []string
 	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.RunAPITest(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepRunAPITest] API test error occurred: %s", err.Error())
-			}
-		}(d)
+		names = append(names, d.GetDeployer().GetPipelineName())
 	}
-	wg.Wait()
 
-	return nil
+	return names
 }
 
 // Delete is the main function for `goployer delete`
-func (r Runner) Delete() error {
+func (r Runner) Delete(ctx context.Context) error {
 	defer func() {
 		if err := recover(); err != nil {
 			Logger.Error(err)
@@ -425,10 +655,23 @@ func (r Runner) Delete() error {
 		}
 	}()
 
+	if r.Builder.Config.DryRun {
+		return r.DryRun("delete")
+	}
+
 	if err := tool.LocalCheck("Do you really want to delete applications? ", r.Builder.Config.AutoApply); err != nil {
 		return err
 	}
 
+	lease, err := r.acquireLock("delete", r.lockKeys())
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	ctx, cancel := lease.WithContext(ctx)
+	defer cancel()
+
 	// Send Beginning Message
 	r.Logger.Info("Beginning delete process: ", r.Builder.AwsConfig.Name)
 	r.Builder.Config.SlackOff = true
@@ -439,106 +682,181 @@ func (r Runner) Delete() error {
 		}
 	}
 
-	wg := sync.WaitGroup{}
-
 	// Prepare deployers
-	r.Logger.Debug("create deployers for stacks to delete")
-	var deployers []deployer.DeployManager
+	r.Logger.Debug("create deployers for stacks and regions to delete")
+	deployers := r.newDeployers()
+	r.Logger.Debugf("successfully assign deployer to stacks")
+
+	err = runPipelines(ctx, deployers, r.Builder.Config.MaxParallel, func(d deployer.DeployManager) error {
+		return r.deletePipeline(ctx, d)
+	})
+
+	return checkLease(lease, err)
+}
+
+// deletePipeline removes the current version of a deployer
+func (r Runner) deletePipeline(ctx context.Context, d deployer.DeployManager) error {
+	if err := d.GetDeployer().CheckPrevious(r.Builder.Config); err != nil {
+		r.Logger.Errorf("[StepCheckPrevious] check previous deployer error occurred: %s", err.Error())
+		return err
+	}
+
+	d.GetDeployer().SkipDeployStep()
+
+	steps := []struct {
+		name     string
+		message  string
+		exitCode int
+		run      func(context.Context, schemas.Config) error
+	}{
+		{name: "StepTriggerLifecycleCallbacks", message: "trigger lifecycle callbacks", exitCode: constants.ExitCodeLifecycleCallbackFailure, run: d.TriggerLifecycleCallbacks},
+		{name: "StepCleanPreviousVersion", message: "clean previous version", exitCode: constants.ExitCodeCleanFailure, run: d.CleanPreviousVersion},
+		{name: "StepCleanChecking", message: "clean checking", exitCode: constants.ExitCodeCleanFailure, run: d.CleanChecking},
+		{name: "StepGatherMetrics", message: "gather metrics", exitCode: constants.ExitCodeMetricsFailure, run: d.GatherMetrics},
+	}
+
+	for _, s := range steps {
+		if err := s.run(ctx, r.Builder.Config); err != nil {
+			r.Logger.Errorf("[%s] %s error occurred: %s", s.name, s.message, err.Error())
+			return stepError(ctx, s.name, s.exitCode, err)
+		}
+	}
+
+	return nil
+}
+
+// Rollback is the main function for `goployer rollback`
+func (r Runner) Rollback(ctx context.Context) error {
+	if err := tool.LocalCheck("Do you really want to roll back this application? ", r.Builder.Config.AutoApply); err != nil {
+		return err
+	}
+
+	lease, err := r.acquireLock("rollback", r.lockKeys())
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	ctx, cancel := lease.WithContext(ctx)
+	defer cancel()
+
+	r.Logger.Infof("Beginning rollback: %s", r.Builder.AwsConfig.Name)
+
+	if !r.Slacker.ValidClient() && !r.Builder.Config.SlackOff {
+		r.Logger.Warn("no slack variables exists. [ SLACK_TOKEN, SLACK_CHANNEL or SLACK_WEBHOOK_URL ]")
+		r.Slacker.SlackOff = true
+	}
+
 	for _, stack := range r.Builder.Stacks {
-		// If target stack is passed from command, then
-		// Skip other stacks
 		if r.Builder.Config.Stack != "" && stack.Stack != r.Builder.Config.Stack {
 			r.Logger.Debugf("Skipping this stack, stack=%s", stack.Stack)
 			continue
 		}
 
-		r.Logger.Debugf("add deployer setup function : %s", stack.Stack)
+		// previous versions are always restored with blue/green deployment
+		stack.ReplacementType = constants.BlueGreenDeployment
 		d := getDeployer(r.Logger, stack, r.Builder.AwsConfig, r.Builder.APITestTemplates, r.Builder.Config.Region, r.Slacker, r.Collector)
-		deployers = append(deployers, d)
+		if err := r.rollbackStack(ctx, d); err != nil {
+			return checkLease(lease, err)
+		}
 	}
 
-	r.Logger.Debugf("successfully assign deployer to stacks")
+	if err := checkLease(lease, nil); err != nil {
+		return err
+	}
 
-	// Check Previous Version
-	errs := make(chan error)
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.GetDeployer().CheckPrevious(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepCheckPrevious] check previous deployer error occurred: %s", err.Error())
-				errs <- err
-			}
+	r.Logger.Infof("rollback operation is finished")
+	return nil
+}
 
-			deployer.GetDeployer().SkipDeployStep()
+// rollbackStack restores the selected previous version of a stack and drains the current one
+func (r Runner) rollbackStack(ctx context.Context, d deployer.DeployManager) error {
+	config := r.Builder.Config
+	if err := d.CheckPreviousResources(ctx, config); err != nil {
+		return err
+	}
 
-			// Trigger Lifecycle Callbacks
-			if err := deployer.TriggerLifecycleCallbacks(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepTriggerLifecycleCallbacks] trigger lifecycle callbacks error occurred: %s", err.Error())
-				errs <- err
-			}
+	dp := d.GetDeployer()
+	for _, region := range dp.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			r.Logger.Debugf("This region is skipped by user : %s", region.Region)
+			continue
+		}
 
-			// Clear previous Version
-			if err := deployer.CleanPreviousVersion(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepCleanPreviousVersion] clean previous version error occurred: %s", err.Error())
-				errs <- err
-			}
-		}(d)
+		candidates, err := dp.GetRollbackCandidates(region.Region)
+		if err != nil {
+			return err
+		}
+
+		if len(candidates) == 0 {
+			return fmt.Errorf("no previous version exists to roll back: %s(%s)", dp.GetStackName(), region.Region)
+		}
+
+		target, err := selectRollbackTarget(candidates, config.RollbackTarget)
+		if err != nil {
+			return err
+		}
+
+		if err := dp.RestoreVersion(config, region.Region, *target); err != nil {
+			return err
+		}
 	}
-	go func() {
-		wg.Wait()
-		close(errs)
-	}()
-	errFlag := checkError(errs)
-	if errFlag != nil {
-		return errFlag
+	dp.StepStatus[constants.StepDeploy] = true
+
+	if err := d.HealthChecking(ctx, config); err != nil {
+		return fmt.Errorf("[StepHealthCheck] restored version is not healthy: %s", err.Error())
 	}
 
-	errs = make(chan error)
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.CleanChecking(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepCleanChecking] clean checking error occurred: %s", err.Error())
-				errs <- err
-			}
-		}(d)
+	if err := d.FinishAdditionalWork(ctx, config); err != nil {
+		return err
 	}
-	go func() {
-		wg.Wait()
-		close(errs)
-	}()
-	errFlag = checkError(errs)
-	if errFlag != nil {
-		return errFlag
+
+	if err := d.TriggerLifecycleCallbacks(ctx, config); err != nil {
+		r.Logger.Errorf("[StepTriggerLifecycleCallbacks] trigger lifecycle callbacks error occurred: %s", err.Error())
 	}
-	// gather metrics of previous version
-	errs = make(chan error)
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.GatherMetrics(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepGatherMetrics] gather metrics error occurred: %s", err.Error())
-				errs <- err
-			}
-		}(d)
+
+	if err := d.CleanPreviousVersion(ctx, config); err != nil {
+		return err
 	}
-	go func() {
-		wg.Wait()
-		close(errs)
-	}()
-	errFlag = checkError(errs)
-	if errFlag != nil {
-		return errFlag
+
+	if err := d.CleanChecking(ctx, config); err != nil {
+		return err
 	}
 
+	dp.FinishRollback(config)
+
 	return nil
 }
 
+// selectRollbackTarget selects the version to roll back to with --to option or interactive terminal
+func selectRollbackTarget(candidates []deployer.RollbackCandidate, to string) (*deployer.RollbackCandidate, error) {
+	if len(to) > 0 {
+		return deployer.FindRollbackCandidate(candidates, to)
+	}
+
+	var options []string
+	for _, c := range candidates {
+		options = append(options, c.String())
+	}
+
+	var answer string
+	prompt := &survey.Select{
+		Message: "Choose the version to roll back to:",
+		Options: options,
+	}
+	survey.AskOne(prompt, &answer)
+
+	for i, option := range options {
+		if option == answer {
+			return &candidates[i], nil
+		}
+	}
+
+	return nil, errors.New("you have to choose the version to roll back to")
+}
+
 // Status shows the detailed information about autoscaling deployment
-func (r Runner) Status() error {
+func (r Runner) Status(_ context.Context) error {
 	inspector := inspector.New(r.Builder.Config.Region)
 
 	asg, err := inspector.SelectStack(r.Builder.Config.Application)
@@ -571,8 +889,7 @@ func (r Runner) Status() error {
 }
 
 // Update will changes configuration of current deployment on live
-func (r Runner) Update() error {
-	var wg sync.WaitGroup
+func (r Runner) Update(ctx context.Context) error {
 	i := inspector.New(r.Builder.Config.Region)
 
 	asg, err := i.SelectStack(r.Builder.Config.Application)
@@ -599,6 +916,20 @@ func (r Runner) Update() error {
 		return err
 	}
 
+	r.Builder.MetricConfig, err = builder.ParseMetricConfig(r.Builder.Config.DisableMetrics, constants.MetricYamlPath)
+	if err != nil {
+		return err
+	}
+
+	lease, err := r.acquireLock("update", []string{tool.ParseAutoScalingPrefix(*group.AutoScalingGroupName)})
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	ctx, cancel := lease.WithContext(ctx)
+	defer cancel()
+
 	if oldCapacity.Desired > newCapacity.Desired {
 		r.Logger.Debugf("downsizing operation is triggered")
 	}
@@ -625,25 +956,19 @@ func (r Runner) Update() error {
 	}
 
 	// Health checking step
-	errs := make(chan error)
 	r.Logger.Debugf("Start health checking")
-	for _, d := range deployers {
-		wg.Add(1)
-		go func(deployer deployer.DeployManager) {
-			defer wg.Done()
-			if err := deployer.HealthChecking(r.Builder.Config); err != nil {
-				r.Logger.Errorf("[StepHealthCheck] check previous deployer error occurred: %s", err.Error())
-				errs <- err
-			}
-		}(d)
+	if err := runPipelines(ctx, deployers, r.Builder.Config.MaxParallel, func(d deployer.DeployManager) error {
+		if err := d.HealthChecking(ctx, r.Builder.Config); err != nil {
+			r.Logger.Errorf("[StepHealthCheck] check previous deployer error occurred: %s", err.Error())
+			return err
+		}
+		return nil
+	}); err != nil {
+		return checkLease(lease, err)
 	}
-	go func() {
-		wg.Wait()
-		close(errs)
-	}()
-	errFlag := checkError(errs)
-	if errFlag != nil {
-		return errFlag
+
+	if err := checkLease(lease, nil); err != nil {
+		return err
 	}
 
 	r.Logger.Debugf("Health check process is done")
@@ -652,7 +977,7 @@ func (r Runner) Update() error {
 }
 
 // Refresh will refresh autoscaling group instances
-func (r Runner) Refresh() error {
+func (r Runner) Refresh(_ context.Context) error {
 	i := inspector.New(r.Builder.Config.Region)
 
 	asg, err := i.SelectStack(r.Builder.Config.Application)
@@ -736,6 +1061,88 @@ func getDeployer(logger *Logger.Logger, stack schemas.Stack, awsConfig schemas.A
 	return d
 }
 
+// acquireLock takes deployment locks with keys until the lease is released
+func (r Runner) acquireLock(operation string, keys []string) (*lock.Lease, error) {
+	locker, err := lock.New(r.Builder.Config, r.Builder.MetricConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if locker == nil {
+		r.Logger.Debug("deployment lock is disabled")
+		return nil, nil
+	}
+
+	return lock.AcquireLease(locker, keys, operation, constants.DefaultLockLeaseDuration)
+}
+
+// lockKeys returns lock keys of stacks and regions which are targets of the operation
+func (r Runner) lockKeys() []string {
+	var keys []string
+	for _, stack := range r.Builder.Stacks {
+		if r.Builder.Config.Stack != "" && stack.Stack != r.Builder.Config.Stack {
+			continue
+		}
+
+		for _, region := range stack.Regions {
+			if r.Builder.Config.Region != "" && region.Region != r.Builder.Config.Region {
+				continue
+			}
+
+			key := tool.BuildPrefixName(r.Builder.AwsConfig.Name, stack.Env, region.Region)
+			if !tool.IsStringInArray(key, keys) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+// newStateRecorder creates recorder which saves step state of the deployment
+func (r Runner) newStateRecorder(mode string) (*state.Recorder, error) {
+	store, err := state.New(r.Builder.Config, r.Builder.MetricConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if store == nil {
+		r.Logger.Debug("deployment state is not saved")
+		return nil, nil
+	}
+
+	recorder := state.NewRecorder(store, state.State{
+		ID:               state.NewID(r.Builder.AwsConfig.Name, time.Now()),
+		Mode:             mode,
+		Config:           r.Builder.Config,
+		AwsConfig:        r.Builder.AwsConfig,
+		APITestTemplates: r.Builder.APITestTemplates,
+	})
+	r.Logger.Infof("Deployment ID: %s", recorder.State.ID)
+
+	return recorder, nil
+}
+
+// excludeDeployers returns deployers which are not in the excluded list
+func excludeDeployers(deployers, excluded []deployer.DeployManager) []deployer.DeployManager {
+	var ret []deployer.DeployManager
+	for _, d := range deployers {
+		skip := false
+		for _, e := range excluded {
+			if d == e {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			ret = append(ret, d)
+		}
+	}
+
+	return ret
+}
+
 // CheckEnabledMetrics checks if metrics configuration is enabled or not
 func (r Runner) CheckEnabledMetrics() error {
 	r.Logger.Debugf("Check if storage exists or not")
@@ -770,7 +1177,7 @@ func askApplicationName() (string, error) {
 
 // checkBuilderConfigurationNeeded checks if mode needs configuration settings like builder, metrics etc
 func checkBuilderConfigurationNeeded(mode string) bool {
-	return tool.IsStringInArray(mode, []string{"deploy", "delete"})
+	return tool.IsStringInArray(mode, []string{"deploy", "delete", "rollback", "canary"})
 }
 
 // CheckUpdateInformation checks if updated information is valid or not
@@ -810,14 +1217,3 @@ func nullCheck(input, origin int64) int64 {
 
 	return input
 }
-
-func checkError(errs chan error) error {
-	if errs != nil {
-		for err := range errs {
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
@@ -17,6 +17,8 @@ limitations under the license.
 package runner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -25,7 +27,12 @@ import (
 	"time"
 
 	"github.com/go-test/deep"
+	Logger "github.com/sirupsen/logrus"
 
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/lock"
 	"github.com/DevopsArtFactory/goployer/pkg/schemas"
 )
 
@@ -92,29 +99,347 @@ func TestCheckUpdateInformation(t *testing.T) {
 	}
 }
 
-func TestGoroutineError(t *testing.T) {
-	wg := sync.WaitGroup{}
-	errs := make(chan error)
-	leaks := make(map[int]struct{})
+func TestRunPipelines(t *testing.T) {
+	var deployers []deployer.DeployManager
+	for i := 0; i < 4; i++ {
+		deployers = append(deployers, &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: fmt.Sprintf("stack%d", i)}}})
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	err := runPipelines(context.Background(), deployers, 2, func(d deployer.DeployManager) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Duration(rand.Intn(100)) * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return fmt.Errorf("%s's error returned", d.GetDeployer().GetStackName())
+	})
+
+	if err == nil {
+		t.Fatal("expected aggregated error")
+	}
 
 	for i := 0; i < 4; i++ {
-		leaks[i] = struct{}{}
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			defer delete(leaks, i)
-			time.Sleep(time.Duration(rand.Intn(100)) * time.Millisecond)
-			errs <- fmt.Errorf("goroutine %d's error returned", i)
-		}(i)
-	}
-
-	go func() {
-		wg.Wait()
-		close(errs)
-	}()
-
-	errFlag := checkError(errs)
-	if !strings.Contains(errFlag.Error(), "error returned") {
-		t.Errorf("validation error")
+		if !strings.Contains(err.Error(), fmt.Sprintf("[stack%d] stack%d's error returned", i, i)) {
+			t.Errorf("error of stack%d is not aggregated: %s", i, err.Error())
+		}
+	}
+
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 pipelines at the same time, got %d", maxRunning)
+	}
+}
+
+func TestRunDeployRolloutsInterrupted(t *testing.T) {
+	first := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "first"}}}
+	second := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "second"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted, err := runDeployRollouts(ctx, Logger.New(), []deployer.DeployManager{first, second}, 1, func(d deployer.DeployManager, _ bool) error {
+		if d == first {
+			return nil
+		}
+
+		cancel()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+
+	found := false
+	for _, d := range interrupted {
+		if d == second {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("cancelled pipeline is not interrupted")
+	}
+}
+
+func waveDeployer(region string) deployer.DeployManager {
+	return &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{
+		Stack:   "app",
+		Regions: []schemas.RegionConfig{{Region: region}},
+		Rollout: &schemas.RolloutConfig{
+			Strategy: "waves",
+			Waves:    [][]string{{"us-east-1"}, {"eu-west-1", "ap-northeast-2"}},
+		},
+	}}}
+}
+
+func TestNewRollouts(t *testing.T) {
+	usEast := waveDeployer("us-east-1")
+	euWest := waveDeployer("eu-west-1")
+	apNortheast := waveDeployer("ap-northeast-2")
+	other := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "other", Regions: []schemas.RegionConfig{{Region: "us-east-1"}}}}}
+
+	rollouts := newRollouts([]deployer.DeployManager{apNortheast, other, usEast, euWest})
+	if len(rollouts) != 2 {
+		t.Fatalf("expected 2 rollouts, got %d", len(rollouts))
+	}
+
+	if rollouts[0].stack != "app" || len(rollouts[0].waves) != 2 {
+		t.Fatalf("expected 2 waves of app, got %v", rollouts[0])
+	}
+
+	if diff := deep.Equal(pipelineNames(rollouts[0].waves[0]), []string{"app/us-east-1"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if diff := deep.Equal(pipelineNames(rollouts[0].waves[1]), []string{"app/ap-northeast-2", "app/eu-west-1"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if rollouts[1].stack != "other" || len(rollouts[1].waves) != 1 {
+		t.Errorf("expected a single wave of other, got %v", rollouts[1])
+	}
+
+	// waves without deployers are skipped
+	rollouts = newRollouts([]deployer.DeployManager{euWest})
+	if len(rollouts) != 1 || len(rollouts[0].waves) != 1 {
+		t.Errorf("expected a single wave, got %v", rollouts)
+	}
+}
+
+func TestRunDeployRollouts(t *testing.T) {
+	usEast := waveDeployer("us-east-1")
+	euWest := waveDeployer("eu-west-1")
+	apNortheast := waveDeployer("ap-northeast-2")
+	deployers := []deployer.DeployManager{usEast, euWest, apNortheast}
+
+	var mu sync.Mutex
+	gates := map[string]bool{}
+	_, err := runDeployRollouts(context.Background(), Logger.New(), deployers, 0, func(d deployer.DeployManager, gated bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		gates[d.GetDeployer().GetPipelineName()] = gated
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := deep.Equal(gates, map[string]bool{"app/us-east-1": true, "app/eu-west-1": false, "app/ap-northeast-2": false}); diff != nil {
+		t.Error(diff)
+	}
+
+	// the following waves are not deployed if a wave fails
+	var deployed []string
+	_, err = runDeployRollouts(context.Background(), Logger.New(), deployers, 0, func(d deployer.DeployManager, _ bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deployed = append(deployed, d.GetDeployer().GetPipelineName())
+		return errors.New("new version is not healthy")
+	})
+
+	if diff := deep.Equal(deployed, []string{"app/us-east-1"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "[app] rollout is stopped at wave 1, following pipelines are not deployed: app/eu-west-1, app/ap-northeast-2") {
+		t.Errorf("expected rollout error, got %v", err)
+	}
+}
+
+func TestRunDeployRolloutsWithDependencies(t *testing.T) {
+	newDeployer := func(stack string, dependsOn ...string) deployer.DeployManager {
+		return &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: stack, DependsOn: dependsOn}}}
+	}
+
+	db := newDeployer("db")
+	backend := newDeployer("backend", "db")
+	batch := newDeployer("batch", "db")
+	frontend := newDeployer("frontend", "backend", "batch")
+	deployers := []deployer.DeployManager{frontend, batch, backend, db}
+
+	var mu sync.Mutex
+	finished := map[string]bool{}
+	gates := map[string]bool{}
+	_, err := runDeployRollouts(context.Background(), Logger.New(), deployers, 0, func(d deployer.DeployManager, gated bool) error {
+		time.Sleep(time.Duration(rand.Intn(20)) * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range d.GetDeployer().Stack.DependsOn {
+			if !finished[dep] {
+				t.Errorf("%s is deployed before %s", d.GetDeployer().GetStackName(), dep)
+			}
+		}
+		finished[d.GetDeployer().GetStackName()] = true
+		gates[d.GetDeployer().GetStackName()] = gated
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := deep.Equal(gates, map[string]bool{"db": true, "backend": true, "batch": true, "frontend": false}); diff != nil {
+		t.Error(diff)
+	}
+
+	// stacks depending on the failed stack are not deployed
+	var deployed []string
+	_, err = runDeployRollouts(context.Background(), Logger.New(), deployers, 0, func(d deployer.DeployManager, _ bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deployed = append(deployed, d.GetDeployer().GetStackName())
+		if d == backend {
+			return errors.New("new version is not healthy")
+		}
+		return nil
+	})
+
+	for _, stack := range deployed {
+		if stack == "frontend" {
+			t.Errorf("frontend is deployed though backend failed")
+		}
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "[frontend] stack is not deployed because the dependency is not deployed: backend") {
+		t.Errorf("expected dependency error, got %v", err)
+	}
+
+	// dependencies which are not deployed together are not waited for
+	_, err = runDeployRollouts(context.Background(), Logger.New(), []deployer.DeployManager{frontend}, 0, func(d deployer.DeployManager, _ bool) error {
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExcludeDeployers(t *testing.T) {
+	first := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "first"}}}
+	second := &deployer.BlueGreen{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "second"}}}
+	third := &deployer.RollingUpdate{Deployer: &deployer.Deployer{Stack: schemas.Stack{Stack: "third"}}}
+
+	deployers := []deployer.DeployManager{first, second, third}
+
+	ret := excludeDeployers(deployers, []deployer.DeployManager{second})
+	if len(ret) != 2 || ret[0] != first || ret[1] != third {
+		t.Errorf("exclude deployers error")
+	}
+
+	ret = excludeDeployers(deployers, nil)
+	if len(ret) != len(deployers) {
+		t.Errorf("exclude deployers error with empty list")
+	}
+}
+
+func TestFailStep(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clean := deployStep{step: constants.StepCleanPreviousVersion, name: "StepCleanPreviousVersion", message: "clean previous version", exitCode: constants.ExitCodeCleanFailure}
+	additional := deployStep{step: constants.StepAdditionalWork, name: "StepFinishAdditionalWork", message: "finish additional work", exitCode: constants.ExitCodeAdditionalWorkFailure}
+
+	testData := []struct {
+		ctx      context.Context
+		config   schemas.Config
+		step     deployStep
+		expected int
+	}{
+		{ctx: context.Background(), step: clean, expected: 0},
+		{ctx: context.Background(), config: schemas.Config{Strict: true}, step: clean, expected: constants.ExitCodeCleanFailure},
+		{ctx: context.Background(), step: additional, expected: 0},
+		{ctx: context.Background(), config: schemas.Config{Strict: true}, step: additional, expected: constants.ExitCodeAdditionalWorkFailure},
+		{ctx: cancelled, config: schemas.Config{Strict: true}, step: clean, expected: constants.ExitCodeFailure},
+	}
+
+	for _, td := range testData {
+		r := Runner{Logger: Logger.New(), Builder: builder.Builder{Config: td.config}}
+		err := r.failStep(td.ctx, nil, nil, td.step, errors.New("failed"))
+		if td.expected == 0 {
+			if err != nil {
+				t.Errorf("%s: expected the pipeline to go on, got %s", td.step.name, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("%s: expected the pipeline to stop", td.step.name)
+			continue
+		}
+
+		if code := ExitCode(err); code != td.expected {
+			t.Errorf("%s: expected exit code %d, got %d", td.step.name, td.expected, code)
+		}
+	}
+}
+
+func TestFailHealthCheck(t *testing.T) {
+	testData := []struct {
+		config   schemas.Config
+		gated    bool
+		expected bool
+	}{
+		{expected: false},
+		{gated: true, expected: true},
+		{config: schemas.Config{Strict: true}, expected: true},
+	}
+
+	for _, td := range testData {
+		r := Runner{Logger: Logger.New(), Builder: builder.Builder{Config: td.config}}
+		err := r.failHealthCheck(context.Background(), nil, nil, td.gated, errors.New("unhealthy"))
+		if (err != nil) != td.expected {
+			t.Errorf("expected pipeline to stop: %t, got %v", td.expected, err)
+			continue
+		}
+
+		if err != nil && ExitCode(err) != constants.ExitCodeHealthCheckFailure {
+			t.Errorf("expected exit code %d, got %d", constants.ExitCodeHealthCheckFailure, ExitCode(err))
+		}
+	}
+}
+
+func TestCheckLease(t *testing.T) {
+	locker, err := lock.NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lease, err := lock.AcquireLease(locker, []string{"hello-dev_apnortheast2"}, "delete", 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	failure := errors.New("failed")
+	if err := checkLease(lease, failure); err != failure {
+		t.Errorf("expected the error as it is while the lock is held, got %v", err)
+	}
+
+	if err := locker.ForceRelease("hello-dev_apnortheast2"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if code := ExitCode(checkLease(lease, nil)); code != constants.ExitCodeLockLost {
+		t.Errorf("expected exit code %d, got %d", constants.ExitCodeLockLost, code)
+	}
+
+	var nilLease *lock.Lease
+	if err := checkLease(nilLease, nil); err != nil {
+		t.Errorf("expected no error without lock, got %s", err)
 	}
 }
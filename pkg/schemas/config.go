@@ -48,6 +48,26 @@ type Config struct { // Do not add comments for this struct
 	SlackOff               bool          `json:"slack_off"`
 	ForceManifestCapacity  bool          `json:"force_manifest_capacity"`
 	CompleteCanary         bool          `json:"complete_canary"`
+	AutoRollback           bool          `json:"auto_rollback"`
+	RollbackTarget         string        `json:"to"`
+	DryRun                 bool          `json:"dry_run"`
+	PlanFile               string        `json:"plan_file"`
+	LockBackend            string        `json:"lock_backend"`
+	LockTable              string        `json:"lock_table"`
+	ForceUnlock            bool          `json:"force"`
+	StateBackend           string        `json:"state_backend"`
+	StateLocation          string        `json:"state_location"`
+	OnInterrupt            string        `json:"on_interrupt"`
+	MaxParallel            int64         `json:"max_parallel"`
+	LaunchFailureThreshold int64         `json:"launch_failure_threshold"`
+	Strict                 bool          `json:"strict"`
+	ResultFile             string        `json:"result_file"`
+	DiagnosticsDir         string        `json:"diagnostics_dir"`
+	DiagnosticsCloudInit   bool          `json:"diagnostics_cloud_init"`
+	DisableDiagnostics     bool          `json:"disable_diagnostics"`
+	Approver               string        `json:"approver"`
+	Comment                string        `json:"comment"`
+	Reject                 bool          `json:"reject"`
 	DownSizingUpdate       bool
 }
 
@@ -146,6 +166,15 @@ type Stack struct {
 	// Polling interval when health checking
 	PollingInterval time.Duration `yaml:"polling_interval,omitempty"`
 
+	// Percentage of desired capacity which should be healthy to finish health checking (default 100)
+	HealthyThresholdPercent int64 `yaml:"healthy_threshold_percent,omitempty"`
+
+	// Minimum number of healthy instances in each availability zone of autoscaling group to finish health checking
+	MinHealthyPerAZ int64 `yaml:"min_healthy_per_az,omitempty"`
+
+	// Whether to terminate instances which are not healthy when health checking is finished by the thresholds, so autoscaling group replaces them
+	TerminateUnhealthy bool `yaml:"terminate_unhealthy,omitempty"`
+
 	// Whether using EBS Optimized option or not
 	EbsOptimized bool `yaml:"ebs_optimized,omitempty"`
 
@@ -161,6 +190,9 @@ type Stack struct {
 	// MixedInstancePolicy of autoscaling group
 	MixedInstancesPolicy MixedInstancesPolicy `yaml:"mixed_instances_policy,omitempty"`
 
+	// Handling of spot interruptions during health checking
+	SpotInterruption *SpotInterruptionConfig `yaml:"spot_interruption,omitempty"`
+
 	// EBS Block Devices for EC2 Instance
 	BlockDevices []BlockDevice `yaml:"block_devices,omitempty"`
 
@@ -181,6 +213,176 @@ type Stack struct {
 
 	// List of region configurations
 	Regions []RegionConfig `yaml:"regions"`
+
+	// Configuration of rollout across regions
+	Rollout *RolloutConfig `yaml:"rollout,omitempty"`
+
+	// List of stacks which should be deployed and healthy before this stack
+	DependsOn []string `yaml:"depends_on,omitempty"`
+
+	// Configuration of bake period before the previous version is cleaned
+	Bake *BakeConfig `yaml:"bake,omitempty"`
+
+	// Configuration of manual approval gate which pauses the pipeline before a step
+	Approval *ApprovalConfig `yaml:"approval,omitempty"`
+
+	// Configuration of canary deployment
+	Canary *CanaryConfig `yaml:"canary,omitempty"`
+}
+
+// Canary configuration of how traffic is routed to the canary version
+type CanaryConfig struct {
+	// Way of routing traffic to the canary target group. Valid modes are:
+	// `load_balancer`: canary target group is attached to a separate canary load balancer
+	// `weighted`: canary target group is added to forward actions of production listeners with weights
+	// Default is load_balancer
+	Mode string `yaml:"mode,omitempty"`
+
+	// Percentage of traffic which is forwarded to the canary target group in weighted mode. Default is 5
+	Weight int64 `yaml:"weight,omitempty"`
+
+	// List of traffic steps which goployer walks through before promoting the canary automatically
+	Steps []CanaryStep `yaml:"steps,omitempty"`
+
+	// List of CloudWatch alarm names. Canary is aborted if any of them is in ALARM state during steps
+	Alarms []string `yaml:"alarms,omitempty"`
+
+	// Configuration of automated analysis comparing metrics of canary and baseline at the end of each step
+	Analysis *CanaryAnalysis `yaml:"analysis,omitempty"`
+
+	// Port of the listener of canary load balancer. Default is 80 for HTTP and 443 for HTTPS
+	ListenerPort int64 `yaml:"listener_port,omitempty"`
+
+	// Protocol of the listener of canary load balancer: `HTTP` or `HTTPS`. Default is HTTP
+	ListenerProtocol string `yaml:"listener_protocol,omitempty"`
+
+	// ARN of ACM certificate for HTTPS listener of canary load balancer
+	CertificateArn string `yaml:"certificate_arn,omitempty"`
+
+	// Security policy for HTTPS listener of canary load balancer. Default is the policy of ELB
+	SslPolicy string `yaml:"ssl_policy,omitempty"`
+
+	// Whether canary load balancer is `internet-facing` or `internal`. Default is internet-facing.
+	// Internal load balancer is placed in private subnets if subnets are not specified
+	Scheme string `yaml:"scheme,omitempty"`
+
+	// List of CIDRs which are allowed to access the listener of canary load balancer. Default is 0.0.0.0/0
+	IngressCidrs []string `yaml:"ingress_cidrs,omitempty"`
+}
+
+// Canary analysis configuration. Built-in metrics are always compared:
+// `target_5xx_rate`, `target_response_time_p50`, `target_response_time_p99` and `request_count`
+type CanaryAnalysis struct {
+	// Interval of datapoints which are compared. Default is 1m
+	Period time.Duration `yaml:"period,omitempty"`
+
+	// Significance level of Mann-Whitney U test. Default is 0.05
+	Alpha float64 `yaml:"alpha,omitempty"`
+
+	// Minimum score to pass the analysis. Default is 90
+	PassScore float64 `yaml:"pass_score,omitempty"`
+
+	// Minimum score to be marginal. Marginal canary goes to the next step, but is not promoted at the last step.
+	// Default is 70
+	MarginalScore float64 `yaml:"marginal_score,omitempty"`
+
+	// List of custom metrics and tolerances of built-in metrics
+	Metrics []CanaryMetric `yaml:"metrics,omitempty"`
+}
+
+// Metric compared in canary analysis
+type CanaryMetric struct {
+	// Built-in metric name or a name of custom metric
+	Name string `yaml:"name"`
+
+	// Percentage of difference of medians which is allowed even if it is significant. Default is 10
+	Tolerance float64 `yaml:"tolerance,omitempty"`
+
+	// Direction of difference which fails the metric: `increase`, `decrease` or `both`.
+	// Default is both for request_count, and increase for the others
+	FailOn string `yaml:"fail_on,omitempty"`
+
+	// CloudWatch namespace of custom metric
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Name of custom CloudWatch metric
+	MetricName string `yaml:"metric_name,omitempty"`
+
+	// CloudWatch statistic of custom metric. Default is Average
+	Statistic string `yaml:"statistic,omitempty"`
+
+	// Dimension of custom metric whose value is the autoscaling group name of canary and baseline.
+	// Default is AutoScalingGroupName
+	AsgDimension string `yaml:"asg_dimension,omitempty"`
+
+	// Other dimensions of custom metric
+	Dimensions map[string]string `yaml:"dimensions,omitempty"`
+}
+
+// Canary step of progressive traffic shifting
+type CanaryStep struct {
+	// Percentage of traffic forwarded to the canary. Canary autoscaling group is scaled to
+	// the same percentage of the previous capacity
+	Weight int64 `yaml:"weight"`
+
+	// Time to watch the canary before the next step
+	Pause time.Duration `yaml:"pause,omitempty"`
+}
+
+// Approval configuration to pause the pipeline until someone approves it
+type ApprovalConfig struct {
+	// Step which waits for approval. Valid steps are:
+	// `finish_additional_work`, `bake`, `trigger_lifecycle_callbacks` and `clean_previous_version`
+	Before string `yaml:"before"`
+
+	// Time to wait for the decision. The gate is rejected after the timeout. Default is 1h
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Bake configuration to watch the new version while the previous version is kept
+type BakeConfig struct {
+	// Time to watch alarms and metrics after the new version is healthy
+	Duration time.Duration `yaml:"duration"`
+
+	// List of CloudWatch alarm names. Bake fails if any of them is in ALARM state
+	Alarms []string `yaml:"alarms,omitempty"`
+
+	// List of metric thresholds. Bake fails if any datapoint exceeds the threshold
+	Metrics []BakeMetric `yaml:"metrics,omitempty"`
+}
+
+// Metric threshold of target group during bake
+type BakeMetric struct {
+	// Valid metric types are:
+	// `target_5xx_rate`: percentage of 5xx responses of targets over requests
+	// `target_response_time`: response time of targets in seconds with the statistic
+	Type string `yaml:"type"`
+
+	// Target group name or ARN. Default is the healthcheck target group of region
+	TargetGroup string `yaml:"target_group,omitempty"`
+
+	// Percentile or statistic of response time like `p99` or `Average`. Default is `p99`
+	Statistic string `yaml:"statistic,omitempty"`
+
+	// Maximum value of metric
+	Threshold float64 `yaml:"threshold"`
+
+	// Length of each datapoint in minutes unit. Default is 1m
+	Period time.Duration `yaml:"period,omitempty"`
+}
+
+// Rollout configuration across regions of a stack
+type RolloutConfig struct {
+	// Valid strategies of rollout are:
+	// `all` (default): all regions are deployed at the same time
+	// `waves`: regions are deployed wave by wave
+	Strategy string `yaml:"strategy"`
+
+	// List of region groups which are deployed in order with waves strategy
+	Waves [][]string `yaml:"waves,omitempty"`
+
+	// Time to wait after a wave is finished before the next wave starts
+	BakeTime time.Duration `yaml:"bake_time,omitempty"`
 }
 
 // Instance Market Options Configuration
@@ -216,6 +418,19 @@ type MixedInstancesPolicy struct {
 	SpotMaxPrice string `yaml:"spot_max_price,omitempty"`
 }
 
+// Spot interruption configuration while the new version becomes healthy
+type SpotInterruptionConfig struct {
+	// Time added to the health check timeout for each interrupted spot instance. Default is 5m
+	GracePeriod time.Duration `yaml:"grace_period,omitempty"`
+
+	// Upper limit of time added to the health check timeout. Default is the timeout of deployment
+	MaxExtension time.Duration `yaml:"max_extension,omitempty"`
+
+	// Whether to launch only on-demand instances in the new autoscaling group after a spot interruption.
+	// This works with mixed_instances_policy
+	FallbackToOnDemand bool `yaml:"fallback_to_on_demand,omitempty"`
+}
+
 // Spot configurations
 type SpotOptions struct {
 	// BlockDurationMinutes menas How long you want to use spot instance for sure
@@ -341,6 +556,18 @@ type RegionConfig struct {
 	// Target group name for healthcheck
 	HealthcheckTargetGroup string `yaml:"healthcheck_target_group"`
 
+	// List of target group names for healthcheck when instances serve several target groups
+	HealthcheckTargetGroups []string `yaml:"healthcheck_target_groups,omitempty"`
+
+	// List of classic load balancer names for healthcheck
+	HealthcheckLBs []string `yaml:"healthcheck_load_balancers,omitempty"`
+
+	// Number of target groups and load balancers for healthcheck which an instance should be healthy in. Default is all of them
+	HealthcheckQuorum int `yaml:"healthcheck_quorum,omitempty"`
+
+	// Health check which is directly requested to instances without load balancer
+	HealthCheck *HealthCheckConfig `yaml:"health_check,omitempty"`
+
 	// List of security group name
 	SecurityGroups []string `yaml:"security_groups"`
 
@@ -369,6 +596,32 @@ type RegionConfig struct {
     IMDSHopLimit int `yaml:"imds_hop_limit,omitempty"`
 }
 
+// Health check configuration of instances
+type HealthCheckConfig struct {
+	// Valid health check types are:
+	// `http`: request to the port of instance
+	// `ssm`: run commands on instance with `AWS-RunShellScript` document
+	Type string `yaml:"type"`
+
+	// Instance port to request with `http` type
+	Port int64 `yaml:"port,omitempty"`
+
+	// List of shell commands to run with `ssm` type. Instance is healthy if the commands exit with 0
+	Commands []string `yaml:"commands,omitempty"`
+
+	// Request path of health check. Default is `/`
+	Path string `yaml:"path,omitempty"`
+
+	// Status code of healthy response. Redirects are not followed. Default is 200
+	ExpectedStatus int `yaml:"expected_status,omitempty"`
+
+	// Time to wait for each response or command. Default is 5s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Number of consecutive successes for an instance to be healthy. Default is 1
+	ConsecutiveSuccesses int `yaml:"consecutive_successes,omitempty"`
+}
+
 // ENI Configuration
 type ENIConfig struct {
 	// Device index for ENI
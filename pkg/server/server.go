@@ -17,10 +17,14 @@ limitations under the license.
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 
 	Logger "github.com/sirupsen/logrus"
 
@@ -28,6 +32,7 @@ import (
 	"github.com/DevopsArtFactory/goployer/pkg/constants"
 	"github.com/DevopsArtFactory/goployer/pkg/runner"
 	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
 )
 
 var (
@@ -45,12 +50,28 @@ type Server struct {
 type Config struct {
 	Addr string
 	Port int64
+
+	// ApprovalTokens maps bearer tokens to approvers who are allowed to decide approval gates
+	ApprovalTokens map[string]string
+
+	// StateConfig has the state backend where decisions on approval gates are saved
+	StateConfig schemas.Config
 }
 
 type RequestBody struct {
 	Config schemas.Config `json:"config"`
 }
 
+// ApprovalRequestBody is the decision on approval gates of a deployment
+// The approver is not a part of the body because it is identified by the token.
+type ApprovalRequestBody struct {
+	ID       string `json:"id"`
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment"`
+	Stack    string `json:"stack"`
+	Region   string `json:"region"`
+}
+
 func New() Server {
 	return Server{
 		Router: http.NewServeMux(),
@@ -65,6 +86,7 @@ func New() Server {
 func (s Server) SetRouter() Server {
 	s.Router.HandleFunc("/health", s.Healthcheck)
 	s.Router.HandleFunc("/deploy", s.TriggerDeploy)
+	s.Router.HandleFunc("/approve", s.Approve)
 	return s
 }
 
@@ -77,6 +99,28 @@ func (s Server) SetDefaultSetting() Server {
 	return s
 }
 
+// SetApprovalSetting reads approval tokens and state backend of approval gates from environment variables
+// If there is no valid token, every request to /approve is rejected.
+func (s Server) SetApprovalSetting() Server {
+	tokens, err := parseApprovalTokens(os.Getenv(constants.ApprovalTokensVariable))
+	if err != nil {
+		s.Logger.Errorf("approval is disabled: %s", err.Error())
+	}
+
+	if len(tokens) == 0 {
+		s.Logger.Warnf("approval is disabled because %s is not set", constants.ApprovalTokensVariable)
+	}
+
+	s.ServerConfig.ApprovalTokens = tokens
+	s.ServerConfig.StateConfig = schemas.Config{
+		StateBackend:  os.Getenv(constants.StateBackendVariable),
+		StateLocation: os.Getenv(constants.StateLocationVariable),
+		Region:        os.Getenv(constants.DefaultRegionVariable),
+	}
+
+	return s
+}
+
 func (s Server) Healthcheck(w http.ResponseWriter, req *http.Request) {
 	s.Logger.Infof("%s %s healthy", req.RemoteAddr, req.Method)
 }
@@ -94,16 +138,108 @@ func (s Server) TriggerDeploy(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := runner.Start(builder, "server"); err != nil {
+	if err := runner.Start(context.Background(), builder, "server"); err != nil {
 		s.Logger.Errorf(err.Error())
 		return
 	}
 }
 
+// Approve saves the decision on pending approval gates of the deployment
+// The request should have a bearer token of an approver, and the decision is recorded with the approver and the client address.
+// The decision is saved to the state backend of the server.
+func (s Server) Approve(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method is not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(s.ServerConfig.ApprovalTokens) == 0 {
+		http.Error(w, "approval is disabled", http.StatusForbidden)
+		return
+	}
+
+	approver, ok := s.authenticate(req)
+	if !ok {
+		s.Logger.Warnf("%s %s approval is not authorized", req.RemoteAddr, req.Method)
+		http.Error(w, "approval is not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body ApprovalRequestBody
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(body.ID) == 0 {
+		http.Error(w, "deployment id is required", http.StatusBadRequest)
+		return
+	}
+
+	decision := state.Decision{
+		Stack:    body.Stack,
+		Region:   body.Region,
+		Approved: body.Approved,
+		Approver: fmt.Sprintf("%s (%s)", approver, req.RemoteAddr),
+		Source:   constants.ApprovalSourceHTTP,
+		Comment:  body.Comment,
+	}
+
+	if err := runner.ApproveDeployment(s.ServerConfig.StateConfig, body.ID, decision); err != nil {
+		s.Logger.Errorf(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.Logger.Infof("%s %s decision of %s on %s is saved: approved=%t", req.RemoteAddr, req.Method, approver, body.ID, body.Approved)
+}
+
+// authenticate returns the approver of bearer token in the request
+func (s Server) authenticate(req *http.Request) (string, bool) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if len(token) == 0 || token == req.Header.Get("Authorization") {
+		return "", false
+	}
+
+	approver, found := "", false
+	for t, a := range s.ServerConfig.ApprovalTokens {
+		// every token is compared so that the response time does not tell which token is close
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			approver, found = a, true
+		}
+	}
+
+	return approver, found
+}
+
 func (s Server) GetAddr() string {
 	return fmt.Sprintf("%s:%d", s.ServerConfig.Addr, s.ServerConfig.Port)
 }
 
+// parseApprovalTokens parses tokens of approvers like approver=token,approver=token
+func parseApprovalTokens(value string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		split := strings.SplitN(entry, "=", 2)
+		if len(split) != 2 || len(split[0]) == 0 || len(split[1]) == 0 {
+			// the entry is not printed because it may be a token
+			return nil, fmt.Errorf("approval token should be approver=token: entry %d", i+1)
+		}
+
+		if _, ok := tokens[split[1]]; ok {
+			return nil, fmt.Errorf("approval token is duplicated: %s", split[0])
+		}
+		tokens[split[1]] = split[0]
+	}
+
+	return tokens, nil
+}
+
 // parameterParsing returns RequestBody
 func parameterParsing(body io.Reader) (RequestBody, error) {
 	decoder := json.NewDecoder(body)
@@ -0,0 +1,143 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	Logger "github.com/sirupsen/logrus"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+	"github.com/DevopsArtFactory/goployer/pkg/state"
+)
+
+func TestParseApprovalTokens(t *testing.T) {
+	testData := []struct {
+		value    string
+		expected map[string]string
+		err      error
+	}{
+		{value: "", expected: map[string]string{}},
+		{value: "alice=secret, bob=hidden", expected: map[string]string{"secret": "alice", "hidden": "bob"}},
+		{value: "alice=secret,secret-only", err: fmt.Errorf("approval token should be approver=token: entry 2")},
+		{value: "alice=secret,bob=secret", err: fmt.Errorf("approval token is duplicated: bob")},
+	}
+
+	for _, td := range testData {
+		tokens, err := parseApprovalTokens(td.value)
+		if td.err != nil {
+			if err == nil || err.Error() != td.err.Error() {
+				t.Errorf("expected %s, got %v", td.err.Error(), err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+			continue
+		}
+
+		if diff := deep.Equal(tokens, td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestApprove(t *testing.T) {
+	dir := t.TempDir()
+	store, err := state.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := state.State{
+		ID:        "hello-20201001120000",
+		Stacks:    []state.StackState{{Stack: schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}}}},
+		Approvals: []state.ApprovalEvent{{Pipeline: "artd/ap-northeast-2", Gate: "bake", Status: constants.ApprovalPending}},
+	}
+	if err := store.Save(st); err != nil {
+		t.Fatal(err)
+	}
+
+	s := Server{
+		Logger: Logger.New(),
+		ServerConfig: Config{
+			ApprovalTokens: map[string]string{"secret": "alice"},
+			StateConfig:    schemas.Config{StateBackend: constants.FileStateBackend, StateLocation: dir, DisableMetrics: true},
+		},
+	}
+
+	// state backend and approver in the body should be ignored
+	body := fmt.Sprintf(`{"id": "%s", "approved": true, "approver": "mallory", "config": {"state_backend": "file", "state_location": "%s"}}`, st.ID, t.TempDir())
+
+	testData := []struct {
+		server        Server
+		method        string
+		authorization string
+		body          string
+		expected      int
+	}{
+		{server: s, method: http.MethodGet, authorization: "Bearer secret", body: body, expected: http.StatusMethodNotAllowed},
+		{server: Server{Logger: Logger.New(), ServerConfig: Config{StateConfig: s.ServerConfig.StateConfig}}, method: http.MethodPost, authorization: "Bearer secret", body: body, expected: http.StatusForbidden},
+		{server: s, method: http.MethodPost, body: body, expected: http.StatusUnauthorized},
+		{server: s, method: http.MethodPost, authorization: "secret", body: body, expected: http.StatusUnauthorized},
+		{server: s, method: http.MethodPost, authorization: "Bearer wrong", body: body, expected: http.StatusUnauthorized},
+		{server: s, method: http.MethodPost, authorization: "Bearer secret", body: `{"approved": true}`, expected: http.StatusBadRequest},
+		{server: s, method: http.MethodPost, authorization: "Bearer secret", body: body, expected: http.StatusOK},
+	}
+
+	for _, td := range testData {
+		req := httptest.NewRequest(td.method, "/approve", strings.NewReader(td.body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		if len(td.authorization) > 0 {
+			req.Header.Set("Authorization", td.authorization)
+		}
+
+		w := httptest.NewRecorder()
+		td.server.Approve(w, req)
+		if w.Code != td.expected {
+			t.Errorf("%s %q: expected %d, got %d", td.method, td.authorization, td.expected, w.Code)
+		}
+
+		if w.Code == http.StatusOK {
+			continue
+		}
+
+		if decisions, _ := store.LoadDecisions(st.ID); len(decisions) > 0 {
+			t.Fatalf("decision is saved by rejected request: %v", decisions)
+		}
+	}
+
+	decisions, err := store.LoadDecisions(st.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decisions) != 1 {
+		t.Fatalf("expected 1 decision, got %d", len(decisions))
+	}
+
+	if !decisions[0].Approved || decisions[0].Approver != "alice (192.0.2.1:1234)" || decisions[0].Source != constants.ApprovalSourceHTTP {
+		t.Errorf("expected approval of alice, got %v", decisions[0])
+	}
+}
@@ -0,0 +1,142 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package state
+
+import (
+	"encoding/json"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+)
+
+// DynamoDBStore stores states as items of a dynamodb table
+type DynamoDBStore struct {
+	Client    aws.DynamoDBClient
+	TableName string
+}
+
+// NewDynamoDBStore creates dynamodb state backend
+func NewDynamoDBStore(region, tableName string) DynamoDBStore {
+	return DynamoDBStore{
+		Client:    aws.BootstrapMetricService(region, constants.EmptyString).DynamoDBService,
+		TableName: tableName,
+	}
+}
+
+// EnsureTable creates state table if it does not exist
+func (d DynamoDBStore) EnsureTable() error {
+	isExist, err := d.Client.CheckTableExists(d.TableName)
+	if err != nil {
+		return err
+	}
+
+	if !isExist {
+		if err := d.Client.CreateTable(d.TableName); err != nil {
+			return err
+		}
+	}
+
+	return d.Client.WaitTableExists(d.TableName)
+}
+
+// Save puts state item
+func (d DynamoDBStore) Save(s State) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]string{
+		"status": s.Status,
+		"state":  string(b),
+	}
+
+	return d.Client.PutStringItem(itemKey(s.ID), d.TableName, fields)
+}
+
+// Load retrieves state item
+func (d DynamoDBStore) Load(id string) (*State, error) {
+	item, err := d.Client.GetSingleItem(itemKey(id), d.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := item["state"]
+	if !ok || v.S == nil {
+		return nil, ErrStateNotFound
+	}
+
+	var s State
+	if err := json.Unmarshal([]byte(*v.S), &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Delete removes state item and approval decisions item
+func (d DynamoDBStore) Delete(id string) error {
+	if err := d.Client.DeleteSingleItem(itemKey(id), d.TableName); err != nil {
+		return err
+	}
+
+	return d.Client.DeleteSingleItem(itemKey(decisionsID(id)), d.TableName)
+}
+
+// AddDecision appends the decision to approval decisions item in a single update
+func (d DynamoDBStore) AddDecision(id string, decision Decision) error {
+	b, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	return d.Client.AppendStringItem(itemKey(decisionsID(id)), d.TableName, "decisions", string(b))
+}
+
+// LoadDecisions retrieves approval decisions item
+func (d DynamoDBStore) LoadDecisions(id string) ([]Decision, error) {
+	item, err := d.Client.GetSingleItem(itemKey(decisionsID(id)), d.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := item["decisions"]
+	if !ok {
+		return nil, nil
+	}
+
+	var decisions []Decision
+	for _, av := range v.L {
+		if av.S == nil {
+			continue
+		}
+
+		var decision Decision
+		if err := json.Unmarshal([]byte(*av.S), &decision); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	sortDecisions(decisions)
+
+	return decisions, nil
+}
+
+// itemKey returns identifier of state item
+func itemKey(id string) string {
+	return constants.StateKeyPrefix + id
+}
@@ -0,0 +1,140 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore stores states as JSON files in a local directory
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates file state backend
+func NewFileStore(dir string) (FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return FileStore{}, err
+	}
+
+	return FileStore{Dir: dir}, nil
+}
+
+// Save writes state file through a temporary file so that the state is not broken in the middle of writing
+func (f FileStore) Save(s State) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.path(s.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.path(s.ID))
+}
+
+// Load reads state file
+func (f FileStore) Load(id string) (*State, error) {
+	b, err := os.ReadFile(f.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrStateNotFound
+		}
+		return nil, err
+	}
+
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Delete removes state file and approval decisions directory
+func (f FileStore) Delete(id string) error {
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.RemoveAll(f.decisionsDir(id))
+}
+
+// AddDecision writes a new file for the decision in approval decisions directory
+func (f FileStore) AddDecision(id string, decision Decision) error {
+	b, err := json.MarshalIndent(decision, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name, err := decisionName(decision)
+	if err != nil {
+		return err
+	}
+
+	dir := f.decisionsDir(id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, name+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, name+".json"))
+}
+
+// LoadDecisions reads every decision file in approval decisions directory
+func (f FileStore) LoadDecisions(id string) ([]Decision, error) {
+	files, err := filepath.Glob(filepath.Join(f.decisionsDir(id), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var decisions []Decision
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var decision Decision
+		if err := json.Unmarshal(b, &decision); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	sortDecisions(decisions)
+
+	return decisions, nil
+}
+
+// decisionsDir returns directory path of approval decisions
+func (f FileStore) decisionsDir(id string) string {
+	return filepath.Join(f.Dir, strings.ReplaceAll(decisionsID(id), "/", "_"))
+}
+
+// path returns file path of state
+func (f FileStore) path(id string) string {
+	return filepath.Join(f.Dir, strings.ReplaceAll(id, "/", "_")+".json")
+}
@@ -0,0 +1,145 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+)
+
+// S3Store stores states as JSON objects in a s3 bucket
+type S3Store struct {
+	Client aws.S3Client
+	Bucket string
+	Prefix string
+}
+
+// NewS3Store creates s3 state backend
+func NewS3Store(region, bucket, prefix string) S3Store {
+	return S3Store{
+		Client: aws.BootstrapManifestService(region, constants.EmptyString).S3Service,
+		Bucket: bucket,
+		Prefix: prefix,
+	}
+}
+
+// Save uploads state object
+func (s S3Store) Save(st State) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	return s.Client.PutObject(s.Bucket, s.key(st.ID), b)
+}
+
+// Load downloads state object
+func (s S3Store) Load(id string) (*State, error) {
+	b, err := s.Client.GetManifest(s.Bucket, s.key(id))
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrStateNotFound
+		}
+		return nil, err
+	}
+
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+
+	return &st, nil
+}
+
+// Delete removes state object and approval decisions objects
+func (s S3Store) Delete(id string) error {
+	if err := s.Client.DeleteObject(s.Bucket, s.key(id)); err != nil {
+		return err
+	}
+
+	keys, err := s.Client.ListObjectKeys(s.Bucket, s.decisionsPrefix(id)+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := s.Client.DeleteObject(s.Bucket, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddDecision uploads a new object for the decision under approval decisions prefix
+func (s S3Store) AddDecision(id string, decision Decision) error {
+	b, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	name, err := decisionName(decision)
+	if err != nil {
+		return err
+	}
+
+	return s.Client.PutObject(s.Bucket, path.Join(s.decisionsPrefix(id), name+".json"), b)
+}
+
+// LoadDecisions downloads every decision object under approval decisions prefix
+func (s S3Store) LoadDecisions(id string) ([]Decision, error) {
+	keys, err := s.Client.ListObjectKeys(s.Bucket, s.decisionsPrefix(id)+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var decisions []Decision
+	for _, key := range keys {
+		b, err := s.Client.GetManifest(s.Bucket, key)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+				continue
+			}
+			return nil, err
+		}
+
+		var decision Decision
+		if err := json.Unmarshal(b, &decision); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	sortDecisions(decisions)
+
+	return decisions, nil
+}
+
+// decisionsPrefix returns key prefix of approval decisions objects
+func (s S3Store) decisionsPrefix(id string) string {
+	return path.Join(s.Prefix, decisionsID(id))
+}
+
+// key returns object key of state
+func (s S3Store) key(id string) string {
+	return path.Join(s.Prefix, id+".json")
+}
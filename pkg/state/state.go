@@ -0,0 +1,389 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	Logger "github.com/sirupsen/logrus"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+var (
+	// ErrStateNotFound is returned when no state exists with the deployment id
+	ErrStateNotFound = errors.New("deployment state does not exist")
+)
+
+// Store is a backend storage of deployment state
+type Store interface {
+	// Save creates or overwrites the state
+	Save(s State) error
+
+	// Load retrieves the state with deployment id
+	Load(id string) (*State, error)
+
+	// Delete removes the state and approval decisions with deployment id
+	Delete(id string) error
+
+	// AddDecision stores a single approval decision of the deployment
+	// Every decision is written separately so concurrent decisions never overwrite each other.
+	AddDecision(id string, decision Decision) error
+
+	// LoadDecisions retrieves approval decisions of the deployment in the order of decision time
+	// It returns nothing if no decision is made yet.
+	LoadDecisions(id string) ([]Decision, error)
+}
+
+// State is the progress of a deployment
+type State struct {
+	ID               string                     `json:"id"`
+	Mode             string                     `json:"mode"`
+	Status           string                     `json:"status"`
+	Config           schemas.Config             `json:"config"`
+	AwsConfig        schemas.AWSConfig          `json:"aws_config"`
+	APITestTemplates []*schemas.APITestTemplate `json:"api_test_templates"`
+	Stacks           []StackState               `json:"stacks"`
+	Approvals        []ApprovalEvent            `json:"approvals,omitempty"`
+	CreatedAt        time.Time                  `json:"created_at"`
+	UpdatedAt        time.Time                  `json:"updated_at"`
+}
+
+// StackState is the step status and resources of a deployer
+type StackState struct {
+	Stack             schemas.Stack               `json:"stack"`
+	StepStatus        map[int64]bool              `json:"step_status"`
+	AsgNames          map[string]string           `json:"asg_names"`
+	PrevAsgs          map[string][]string         `json:"prev_asgs"`
+	PrevInstances     map[string][]string         `json:"prev_instances"`
+	PrevVersions      map[string][]int            `json:"prev_versions"`
+	PrevInstanceCount map[string]schemas.Capacity `json:"prev_instance_count"`
+	SecurityGroup     map[string]*string          `json:"security_group"`
+	LatestAsg         map[string]string           `json:"latest_asg"`
+	DeploymentFlag    map[string]string           `json:"deployment_flag"`
+	AppliedCapacity   *schemas.Capacity           `json:"applied_capacity,omitempty"`
+}
+
+// ApprovalEvent is the request and the decision of an approval gate of a pipeline
+type ApprovalEvent struct {
+	Pipeline    string    `json:"pipeline"`
+	Gate        string    `json:"gate"`
+	Status      string    `json:"status"`
+	Approver    string    `json:"approver,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Comment     string    `json:"comment,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	DecidedAt   time.Time `json:"decided_at,omitempty"`
+}
+
+// Decision is the approval or rejection of pending gates which is made outside of the deployment process
+// Empty stack or region matches every pipeline.
+type Decision struct {
+	Stack     string    `json:"stack,omitempty"`
+	Region    string    `json:"region,omitempty"`
+	Approved  bool      `json:"approved"`
+	Approver  string    `json:"approver"`
+	Source    string    `json:"source"`
+	Comment   string    `json:"comment,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Matches returns true if the decision is made for the stack
+func (d Decision) Matches(stack schemas.Stack) bool {
+	if len(d.Stack) > 0 && d.Stack != stack.Stack {
+		return false
+	}
+
+	if len(d.Region) == 0 {
+		return true
+	}
+
+	for _, region := range stack.Regions {
+		if region.Region == d.Region {
+			return true
+		}
+	}
+
+	return false
+}
+
+// New creates state backend with configuration
+// If backend is not specified, local files are used.
+func New(config schemas.Config, mc schemas.MetricConfig) (Store, error) {
+	backend := config.StateBackend
+	if len(backend) == 0 {
+		backend = constants.FileStateBackend
+	}
+
+	region := config.Region
+	if len(region) == 0 {
+		region = constants.DefaultRegion
+	}
+
+	switch backend {
+	case constants.NoStateBackend:
+		return nil, nil
+	case constants.FileStateBackend:
+		dir := config.StateLocation
+		if len(dir) == 0 {
+			dir = constants.DefaultStateDirectory
+		}
+		return NewFileStore(dir)
+	case constants.S3StateBackend:
+		location := strings.TrimPrefix(config.StateLocation, constants.S3Prefix)
+		if len(location) == 0 {
+			return nil, errors.New("you have to specify --state-location with bucket name to use s3 state backend")
+		}
+
+		split := strings.SplitN(location, "/", 2)
+		prefix := constants.EmptyString
+		if len(split) > 1 {
+			prefix = split[1]
+		}
+		return NewS3Store(region, split[0], prefix), nil
+	case constants.DynamoDBStateBackend:
+		table := config.StateLocation
+		if len(table) == 0 {
+			table = mc.Storage.Name
+			if len(mc.Region) > 0 {
+				region = mc.Region
+			}
+		}
+
+		if len(table) == 0 {
+			return nil, errors.New("you have to specify --state-location or enable metrics to use dynamodb state backend")
+		}
+
+		store := NewDynamoDBStore(region, table)
+		if err := store.EnsureTable(); err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	}
+
+	return nil, fmt.Errorf("state backend is not supported: %s", backend)
+}
+
+// decisionsID returns identifier of approval decisions of the deployment
+func decisionsID(id string) string {
+	return id + ".approvals"
+}
+
+// decisionName returns unique name of a single decision
+func decisionName(d Decision) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return constants.EmptyString, err
+	}
+
+	return fmt.Sprintf("%d-%s", d.DecidedAt.UnixNano(), hex.EncodeToString(b)), nil
+}
+
+// sortDecisions sorts decisions in the order of decision time
+func sortDecisions(decisions []Decision) {
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].DecidedAt.Before(decisions[j].DecidedAt)
+	})
+}
+
+// NewID creates deployment id with application name and time
+func NewID(application string, t time.Time) string {
+	return fmt.Sprintf("%s-%s", application, t.UTC().Format("20060102150405"))
+}
+
+// StackKey returns identifier of a stack state with stack name and regions of the deployer
+func StackKey(stack schemas.Stack) string {
+	if len(stack.Regions) == 0 {
+		return stack.Stack
+	}
+
+	var regions []string
+	for _, region := range stack.Regions {
+		regions = append(regions, region.Region)
+	}
+
+	return fmt.Sprintf("%s/%s", stack.Stack, strings.Join(regions, ","))
+}
+
+// GetStack returns state of the stack with key
+func (s State) GetStack(key string) (*StackState, bool) {
+	for i := range s.Stacks {
+		if StackKey(s.Stacks[i].Stack) == key {
+			return &s.Stacks[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// GetApproval returns the event of approval gate of the pipeline
+func (s State) GetApproval(pipeline, gate string) (*ApprovalEvent, bool) {
+	for i := range s.Approvals {
+		if s.Approvals[i].Pipeline == pipeline && s.Approvals[i].Gate == gate {
+			return &s.Approvals[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Recorder saves the state whenever a step of a stack is finished
+type Recorder struct {
+	Store Store
+	State State
+	mu    sync.Mutex
+}
+
+// NewRecorder creates recorder with a new state
+func NewRecorder(store Store, s State) *Recorder {
+	now := time.Now()
+	s.Status = constants.StateRunning
+	s.CreatedAt = now
+	s.UpdatedAt = now
+
+	return &Recorder{
+		Store: store,
+		State: s,
+	}
+}
+
+// Record updates the state of the stack and saves it
+func (r *Recorder) Record(ss StackState) {
+	if r == nil || r.Store == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if prev, ok := r.State.GetStack(StackKey(ss.Stack)); ok {
+		*prev = ss
+	} else {
+		r.State.Stacks = append(r.State.Stacks, ss)
+	}
+
+	r.save()
+}
+
+// Forget removes the stack state with key which has nothing to resume and saves the state
+func (r *Recorder) Forget(key string) {
+	if r == nil || r.Store == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stacks []StackState
+	for _, ss := range r.State.Stacks {
+		if StackKey(ss.Stack) != key {
+			stacks = append(stacks, ss)
+		}
+	}
+	r.State.Stacks = stacks
+
+	r.save()
+}
+
+// RecordApproval updates the event of approval gate and saves the state
+func (r *Recorder) RecordApproval(ev ApprovalEvent) {
+	if r == nil || r.Store == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if prev, ok := r.State.GetApproval(ev.Pipeline, ev.Gate); ok {
+		*prev = ev
+	} else {
+		r.State.Approvals = append(r.State.Approvals, ev)
+	}
+
+	r.save()
+}
+
+// GetApproval returns a copy of the event of approval gate
+func (r *Recorder) GetApproval(pipeline, gate string) (ApprovalEvent, bool) {
+	if r == nil || r.Store == nil {
+		return ApprovalEvent{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ev, ok := r.State.GetApproval(pipeline, gate)
+	if !ok {
+		return ApprovalEvent{}, false
+	}
+
+	return *ev, true
+}
+
+// Detach marks the state as detached so that the deployment can be resumed later
+func (r *Recorder) Detach() {
+	if r == nil || r.Store == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.State.Status = constants.StateDetached
+	r.save()
+}
+
+// Finish removes the state if the deployment succeeded, otherwise marks it as failed
+// Detached state keeps its status.
+func (r *Recorder) Finish(err error) {
+	if r == nil || r.Store == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil || len(r.State.Stacks) == 0 {
+		if err := r.Store.Delete(r.State.ID); err != nil {
+			Logger.Warnf("failed to delete deployment state %s: %s", r.State.ID, err.Error())
+		}
+		return
+	}
+
+	if r.State.Status != constants.StateDetached {
+		r.State.Status = constants.StateFailed
+	}
+	r.save()
+	Logger.Warnf("deployment is not finished, run `goployer resume %s` to continue", r.State.ID)
+}
+
+// save writes the state to the store
+func (r *Recorder) save() {
+	r.State.UpdatedAt = time.Now()
+	if err := r.Store.Save(r.State); err != nil {
+		Logger.Warnf("failed to save deployment state %s: %s", r.State.ID, err.Error())
+	}
+}
@@ -0,0 +1,279 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package state
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"github.com/DevopsArtFactory/goployer/pkg/constants"
+	"github.com/DevopsArtFactory/goployer/pkg/schemas"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capacity := schemas.Capacity{Min: 1, Max: 2, Desired: 1}
+	s := State{
+		ID:     NewID("hello", time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)),
+		Mode:   "deploy",
+		Status: constants.StateRunning,
+		Stacks: []StackState{
+			{
+				Stack:             schemas.Stack{Stack: "artd", Env: "dev"},
+				StepStatus:        map[int64]bool{constants.StepCheckPrevious: true, constants.StepDeploy: true},
+				AsgNames:          map[string]string{"ap-northeast-2": "hello-dev_apnortheast2-v002"},
+				PrevAsgs:          map[string][]string{"ap-northeast-2": {"hello-dev_apnortheast2-v001"}},
+				PrevVersions:      map[string][]int{"ap-northeast-2": {1}},
+				PrevInstanceCount: map[string]schemas.Capacity{"ap-northeast-2": capacity},
+				AppliedCapacity:   &capacity,
+			},
+		},
+	}
+
+	if s.ID != "hello-20201001120000" {
+		t.Errorf("unexpected deployment id: %s", s.ID)
+	}
+
+	if err := store.Save(s); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(*loaded, s); diff != nil {
+		t.Error(diff)
+	}
+
+	if err := store.Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(s.ID); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound, got %v", err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := NewRecorder(store, State{ID: "hello-20201001120000", Mode: "deploy"})
+	recorder.Record(StackState{Stack: schemas.Stack{Stack: "artd"}, StepStatus: map[int64]bool{constants.StepCheckPrevious: true}})
+	recorder.Record(StackState{Stack: schemas.Stack{Stack: "artd"}, StepStatus: map[int64]bool{constants.StepCheckPrevious: true, constants.StepDeploy: true}})
+	recorder.Record(StackState{Stack: schemas.Stack{Stack: "batch"}, StepStatus: map[int64]bool{constants.StepCheckPrevious: true}})
+
+	loaded, err := store.Load("hello-20201001120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Stacks) != 2 {
+		t.Fatalf("expected 2 stacks, got %d", len(loaded.Stacks))
+	}
+
+	artd, _ := loaded.GetStack("artd")
+	if !artd.StepStatus[constants.StepDeploy] || artd.StepStatus[constants.StepAdditionalWork] {
+		t.Errorf("expected artd to be recorded up to the deploy step, got %v", artd.StepStatus)
+	}
+
+	recorder.Forget("batch")
+	recorder.Finish(errors.New("health check failed"))
+
+	loaded, err = store.Load("hello-20201001120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Status != constants.StateFailed || len(loaded.Stacks) != 1 {
+		t.Errorf("expected failed state with artd only, got %s with %d stacks", loaded.Status, len(loaded.Stacks))
+	}
+
+	recorder.Finish(nil)
+	if _, err := store.Load("hello-20201001120000"); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected state to be removed after success, got %v", err)
+	}
+}
+
+func TestRecorderDetach(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := NewRecorder(store, State{ID: "hello-20201001120000", Mode: "deploy"})
+	recorder.Record(StackState{Stack: schemas.Stack{Stack: "artd"}, StepStatus: map[int64]bool{constants.StepCheckPrevious: true, constants.StepDeploy: true}})
+	recorder.Detach()
+	recorder.Finish(errors.New("deployment is interrupted"))
+
+	loaded, err := store.Load("hello-20201001120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Status != constants.StateDetached {
+		t.Errorf("expected detached state, got %s", loaded.Status)
+	}
+}
+
+func TestRecordApproval(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requestedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	recorder := NewRecorder(store, State{ID: "hello-20201001120000", Mode: "deploy"})
+	recorder.RecordApproval(ApprovalEvent{Pipeline: "artd/ap-northeast-2", Gate: "bake", Status: constants.ApprovalPending, RequestedAt: requestedAt})
+	recorder.RecordApproval(ApprovalEvent{Pipeline: "artd/ap-northeast-2", Gate: "bake", Status: constants.ApprovalApproved, Approver: "jack", Source: constants.ApprovalSourceCommand, RequestedAt: requestedAt})
+
+	loaded, err := store.Load("hello-20201001120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ApprovalEvent{{Pipeline: "artd/ap-northeast-2", Gate: "bake", Status: constants.ApprovalApproved, Approver: "jack", Source: constants.ApprovalSourceCommand, RequestedAt: requestedAt}}
+	if diff := deep.Equal(loaded.Approvals, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if ev, ok := recorder.GetApproval("artd/ap-northeast-2", "bake"); !ok || ev.Approver != "jack" {
+		t.Errorf("expected approval of jack, got %v", ev)
+	}
+}
+
+func TestDecisions(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions, err := store.LoadDecisions("hello-20201001120000")
+	if err != nil || decisions != nil {
+		t.Fatalf("expected no decision, got %v, %v", decisions, err)
+	}
+
+	decidedAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	expected := []Decision{
+		{Stack: "artd", Approved: true, Approver: "jack", Source: constants.ApprovalSourceCommand, DecidedAt: decidedAt},
+		{Stack: "artd", Approved: false, Approver: "jill", Source: constants.ApprovalSourceHTTP, DecidedAt: decidedAt.Add(time.Second)},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(expected))
+	for i := len(expected) - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(decision Decision) {
+			defer wg.Done()
+			errs <- store.AddDecision("hello-20201001120000", decision)
+		}(expected[i])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	decisions, err = store.LoadDecisions("hello-20201001120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(decisions, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	if err := store.Delete("hello-20201001120000"); err != nil {
+		t.Fatal(err)
+	}
+
+	if decisions, _ := store.LoadDecisions("hello-20201001120000"); decisions != nil {
+		t.Errorf("expected decisions to be removed with the state, got %v", decisions)
+	}
+}
+
+func TestDecisionMatches(t *testing.T) {
+	stack := schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}}
+	testData := []struct {
+		decision Decision
+		expected bool
+	}{
+		{
+			decision: Decision{},
+			expected: true,
+		},
+		{
+			decision: Decision{Stack: "artd", Region: "ap-northeast-2"},
+			expected: true,
+		},
+		{
+			decision: Decision{Stack: "batch"},
+			expected: false,
+		},
+		{
+			decision: Decision{Region: "us-east-1"},
+			expected: false,
+		},
+	}
+
+	for _, td := range testData {
+		if got := td.decision.Matches(stack); got != td.expected {
+			t.Errorf("%v: expected %t, got %t", td.decision, td.expected, got)
+		}
+	}
+}
+
+func TestStackKey(t *testing.T) {
+	testData := []struct {
+		stack    schemas.Stack
+		expected string
+	}{
+		{
+			stack:    schemas.Stack{Stack: "artd"},
+			expected: "artd",
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}}},
+			expected: "artd/ap-northeast-2",
+		},
+		{
+			stack:    schemas.Stack{Stack: "artd", Regions: []schemas.RegionConfig{{Region: "ap-northeast-2"}, {Region: "us-east-1"}}},
+			expected: "artd/ap-northeast-2,us-east-1",
+		},
+	}
+
+	for _, td := range testData {
+		if diff := deep.Equal(StackKey(td.stack), td.expected); diff != nil {
+			t.Error(diff)
+		}
+	}
+}
@@ -92,6 +92,18 @@ NAME	TYPE	SIZE	IOPS
 {{- if (gt (len $region.HealthcheckTargetGroup) 0) }}
 {{ decorate "bullet" (decorate "bold" "Healthcheck TG") }}: {{ $region.HealthcheckTargetGroup }}
 {{- end }}
+{{- if (gt (len $region.HealthcheckLBs) 0) }}
+{{ decorate "bullet" (decorate "bold" "Healthcheck LBs") }}: {{ joinString $region.HealthcheckLBs "," }}
+{{- end }}
+{{- if (gt (len $region.HealthcheckTargetGroups) 0) }}
+{{ decorate "bullet" (decorate "bold" "Healthcheck TGs") }}: {{ joinString $region.HealthcheckTargetGroups "," }}
+{{- end }}
+{{- if (gt $region.HealthcheckQuorum 0) }}
+{{ decorate "bullet" (decorate "bold" "Healthcheck Quorum") }}: {{ $region.HealthcheckQuorum }}
+{{- end }}
+{{- if $region.HealthCheck }}
+{{ decorate "bullet" (decorate "bold" "Health Check") }}: {{ $region.HealthCheck.Type }} port {{ $region.HealthCheck.Port }}
+{{- end }}
 {{- if (gt (len $region.AvailabilityZones) 0) }}
 {{ decorate "bullet" (decorate "bold" "Availability Zones") }}: {{ joinString $region.AvailabilityZones "," }}
 {{- end }}
@@ -191,3 +203,86 @@ const InstanceRefreshStatusTemplate = `{{decorate "bold" "Name"}}:	{{ .Target.Au
 {{decorate "bold" "End Time"}}:	{{ .Summary.EndTime }}
 {{decorate "bold" "Status"}}:	{{ .Summary.Status }}
 `
+
+const DeploymentPlanTemplate = `============================================================
+Deployment Plan (dry-run)
+============================================================
+{{- range $plan := .Plans }}
+{{ decorate "underline bold" "Stack" }}:	{{ $plan.Stack }}
+{{ decorate "underline bold" "Region" }}:	{{ $plan.Region }}
+{{ decorate "underline bold" "Replacement Type" }}:	{{ $plan.Mode }}
+{{- with $plan.NewAutoScalingGroup }}
+
+{{ decorate "underline bold" "Autoscaling group to create" }}:	{{ .Name }}
+{{ decorate "bullet" (decorate "bold" "Launch Template") }}:	{{ .LaunchTemplate.Name }}
+{{ decorate "bullet" (decorate "bold" "AMI ID") }}:	{{ .LaunchTemplate.Ami }}
+{{ decorate "bullet" (decorate "bold" "Instance Type") }}:	{{ .LaunchTemplate.InstanceType }}
+{{ decorate "bullet" (decorate "bold" "SSH Key") }}:	{{ .LaunchTemplate.SSHKey }}
+{{ decorate "bullet" (decorate "bold" "IAM Instance Profile") }}:	{{ .LaunchTemplate.IamInstanceProfile }}
+{{ decorate "bullet" (decorate "bold" "EBS Optimized") }}:	{{ .LaunchTemplate.EbsOptimized }}
+{{ decorate "bullet" (decorate "bold" "Security Groups") }}:	{{ joinString .LaunchTemplate.SecurityGroupIDs "," }}
+{{ decorate "bullet" (decorate "bold" "Block Devices") }}:	{{ joinString .LaunchTemplate.BlockDevices "," }}
+{{ decorate "bullet" (decorate "bold" "Userdata SHA256") }}:	{{ .LaunchTemplate.UserdataSHA256 }}
+{{ decorate "bullet" (decorate "bold" "Availability Zones") }}:	{{ joinString .AvailabilityZones "," }}
+{{ decorate "bullet" (decorate "bold" "Subnets") }}:	{{ joinString .Subnets "," }}
+{{ decorate "bullet" (decorate "bold" "Load Balancers") }}:	{{ joinString .LoadBalancers "," }}
+{{ decorate "bullet" (decorate "bold" "Target Groups") }}:	{{ joinString .TargetGroupARNs "," }}
+{{ decorate "bullet" (decorate "bold" "Lifecycle Hooks") }}:	{{ joinString .LifecycleHooks "," }}
+{{ decorate "bullet" (decorate "bold" "Scaling Policies") }}:	{{ joinString .ScalingPolicies "," }}
+{{ decorate "bullet" (decorate "bold" "Alarms") }}:	{{ joinString .Alarms "," }}
+{{ decorate "bullet" (decorate "bold" "Scheduled Actions") }}:	{{ joinString .ScheduledActions "," }}
+{{ decorate "bold" "Capacity" }}
+MINIMUM 	DESIRED 	MAXIMUM
+{{ .Capacity.Min }}	{{ .Capacity.Desired }}	{{ .Capacity.Max }}
+{{ decorate "bold" "Tags" }}
+{{- range $k, $v := .Tags }}
+ {{ decorate "bullet" $k }}:	{{ $v }}
+{{- end }}
+{{- end }}
+
+{{ decorate "underline bold" "Autoscaling groups to resize and delete" }}
+{{- if eq (len $plan.PreviousAutoScalingGroups) 0 }}
+ No autoscaling group will be deleted
+{{- else }}
+NAME	ACTION	MINIMUM	DESIRED	MAXIMUM	INSTANCES	POLICIES	HOOKS
+{{- range $prev := $plan.PreviousAutoScalingGroups }}
+{{ $prev.Name }}	{{ $prev.Action }}	{{ $prev.Capacity.Min }}	{{ $prev.Capacity.Desired }}	{{ $prev.Capacity.Max }}	{{ $prev.InstanceCount }}	{{ joinString $prev.ScalingPolicies "," }}	{{ joinString $prev.LifecycleHooks "," }}
+{{- end }}
+{{- end }}
+{{- if gt (len $plan.LifecycleCallbacks) 0 }}
+
+{{ decorate "underline bold" "Lifecycle callbacks before termination" }}
+{{- range $command := $plan.LifecycleCallbacks }}
+ {{ decorate "bullet" $command }}
+{{- end }}
+{{- end }}
+{{- if gt (len $plan.Notes) 0 }}
+
+{{ decorate "underline bold" "Notes" }}
+{{- range $note := $plan.Notes }}
+ {{ decorate "bullet" $note }}
+{{- end }}
+{{- end }}
+============================================================
+{{- end }}
+
+`
+
+const CanaryStatusTemplate = `{{- if eq (len .Statuses) 0 }}
+ No canary is in progress
+{{- else }}
+{{- range $status := .Statuses }}
+{{ decorate "underline bold" "Stack" }}:	{{ $status.Stack }}
+{{ decorate "underline bold" "Region" }}:	{{ $status.Region }}
+{{ decorate "bullet" (decorate "bold" "Mode") }}:	{{ $status.Mode }}
+{{ decorate "bullet" (decorate "bold" "Canary") }}:	{{ $status.AutoScalingGroup }}
+{{ decorate "bullet" (decorate "bold" "Baseline") }}:	{{ $status.Baseline }}
+{{- if gt (len $status.TargetGroup) 0 }}
+{{ decorate "bullet" (decorate "bold" "Target Group") }}:	{{ parseTGName $status.TargetGroup }}
+{{- end }}
+{{ decorate "bullet" (decorate "bold" "Weight") }}:	{{ $status.Weight }}%
+{{ decorate "bullet" (decorate "bold" "Healthy") }}:	{{ $status.Healthy }}/{{ $status.Desired }}
+{{ decorate "bullet" (decorate "bold" "Age") }}:	{{ $status.Age }} (since {{ $status.CreatedTime.Format "2006-01-02 15:04:05 MST" }})
+{{- end }}
+{{- end }}
+`
@@ -17,6 +17,7 @@ limitations under the license.
 package tool
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -73,6 +74,19 @@ func CheckTimeout(start int64, timeout time.Duration) (bool, error) {
 	return false, nil
 }
 
+// SleepWithContext waits for the duration and returns error if the context is cancelled before
+func SleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // GetBaseTimeWithTimezone returns time with timezone
 func GetBaseTimeWithTimezone(timezone string) time.Time {
 	now := time.Now()
@@ -17,9 +17,12 @@ limitations under the license.
 package tool
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DevopsArtFactory/goployer/pkg/constants"
 )
@@ -55,3 +58,16 @@ func TestIsTargetGroupArn(t *testing.T) {
 		}
 	}
 }
+
+func TestSleepWithContext(t *testing.T) {
+	if err := SleepWithContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := SleepWithContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+}
@@ -47,6 +47,20 @@ func ParseAutoScalingVersion(name string) int {
 	return 0
 }
 
+// ParseAutoScalingPrefix parses frigga prefix from autoscaling group name
+func ParseAutoScalingPrefix(name string) string {
+	idx := strings.LastIndex(name, "-v")
+	if idx < 0 {
+		return name
+	}
+
+	if _, err := strconv.Atoi(name[idx+2:]); err != nil {
+		return name
+	}
+
+	return name[:idx]
+}
+
 // GenerateAsgName generates the autoscaling name
 func GenerateAsgName(prefix string, version int) string {
 	return fmt.Sprintf("%s-v%03d", prefix, version)
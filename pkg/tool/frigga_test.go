@@ -47,3 +47,29 @@ func TestParseTargetGroupVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAutoScalingPrefix(t *testing.T) {
+	testData := []struct {
+		Input    string
+		Expected string
+	}{
+		{
+			Input:    "hello-dev_apnortheast2-v001",
+			Expected: "hello-dev_apnortheast2",
+		},
+		{
+			Input:    "hello-vpc-dev_apnortheast2-v120",
+			Expected: "hello-vpc-dev_apnortheast2",
+		},
+		{
+			Input:    "hello-vpc",
+			Expected: "hello-vpc",
+		},
+	}
+
+	for _, td := range testData {
+		if output := ParseAutoScalingPrefix(td.Input); output != td.Expected {
+			t.Errorf("expected: %s, output: %s", td.Expected, output)
+		}
+	}
+}
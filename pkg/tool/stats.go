@@ -0,0 +1,96 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package tool
+
+import (
+	"math"
+	"sort"
+)
+
+// Median returns the median of values
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
+
+// MannWhitneyU returns U statistic of x and two-sided p-value of Mann-Whitney U test
+// The p-value is calculated with normal approximation with tie and continuity correction.
+func MannWhitneyU(x, y []float64) (float64, float64) {
+	n1, n2 := float64(len(x)), float64(len(y))
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type sample struct {
+		value float64
+		first bool
+	}
+
+	samples := make([]sample, 0, len(x)+len(y))
+	for _, v := range x {
+		samples = append(samples, sample{value: v, first: true})
+	}
+	for _, v := range y {
+		samples = append(samples, sample{value: v})
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].value < samples[j].value })
+
+	// tied values have the average of their ranks
+	rankSum, ties := 0.0, 0.0
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if samples[k].first {
+				rankSum += rank
+			}
+		}
+
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u := rankSum - n1*(n1+1)/2
+	n := n1 + n2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+
+	z := (math.Abs(u-n1*n2/2) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+
+	return u, math.Erfc(z / math.Sqrt2)
+}
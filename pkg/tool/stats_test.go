@@ -0,0 +1,73 @@
+/*
+copyright 2020 the Goployer authors
+
+licensed under the apache license, version 2.0 (the "license");
+you may not use this file except in compliance with the license.
+you may obtain a copy of the license at
+
+    http://www.apache.org/licenses/license-2.0
+
+unless required by applicable law or agreed to in writing, software
+distributed under the license is distributed on an "as is" basis,
+without warranties or conditions of any kind, either express or implied.
+see the license for the specific language governing permissions and
+limitations under the license.
+*/
+
+package tool
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMedian(t *testing.T) {
+	testData := []struct {
+		values   []float64
+		expected float64
+	}{
+		{values: nil, expected: 0},
+		{values: []float64{3, 1, 2}, expected: 2},
+		{values: []float64{4, 1, 3, 2}, expected: 2.5},
+	}
+
+	for _, td := range testData {
+		if output := Median(td.values); output != td.expected {
+			t.Errorf("expected: %f, output: %f", td.expected, output)
+		}
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	testData := []struct {
+		x, y      []float64
+		expectedU float64
+		expectedP float64
+	}{
+		{
+			x:         []float64{1, 2, 3, 4, 5},
+			y:         []float64{6, 7, 8, 9, 10},
+			expectedU: 0,
+			expectedP: 0.012186,
+		},
+		{
+			x:         []float64{1, 2, 2, 3},
+			y:         []float64{1, 2, 2, 3},
+			expectedU: 8,
+			expectedP: 1,
+		},
+		{
+			x:         []float64{0, 0, 0},
+			y:         []float64{0, 0, 0},
+			expectedU: 4.5,
+			expectedP: 1,
+		},
+	}
+
+	for _, td := range testData {
+		u, p := MannWhitneyU(td.x, td.y)
+		if u != td.expectedU || math.Abs(p-td.expectedP) > 1e-6 {
+			t.Errorf("expected: U=%f p=%f, output: U=%f p=%f", td.expectedU, td.expectedP, u, p)
+		}
+	}
+}
@@ -28,6 +28,7 @@ func main() {
 	Logger.Infof("Booting up goployer server")
 	s := server.New().
 		SetDefaultSetting().
+		SetApprovalSetting().
 		SetRouter()
 
 	Logger.Infof("Server setting is done")